@@ -0,0 +1,249 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+)
+
+// AnimationFormat selects the file format of QRSequence.WriteAnimation.
+type AnimationFormat uint8
+
+const (
+	// AnimationGIF writes an animated GIF, which every browser and image
+	// viewer plays. Colors, e.g. of a logo, are reduced to gray levels.
+	AnimationGIF AnimationFormat = iota
+	// AnimationAPNG writes an animated PNG, which keeps all colors and is
+	// usually smaller than the GIF. Viewers without APNG support show the
+	// first frame only.
+	AnimationAPNG
+)
+
+// AnimationOptions configures the animation written by
+// QRSequence.WriteAnimation.
+type AnimationOptions struct {
+	// Format is the file format of the animation.
+	Format AnimationFormat
+	// FPS is the number of frames shown per second.
+	FPS float64
+	// Loops is the number of times the sequence is played, or 0 to play it
+	// forever.
+	Loops int
+}
+
+// WriteAnimation writes the frames of the QRSequence as an animated image
+// file, for senders that can only show an image, like a web page or a slide.
+//
+// Frames of different sizes, e.g. of chunks rendered at a higher error
+// correction level, are centered on a white canvas of the size of the largest
+// frame. Players round the frame delay, GIF players to 1/100 s.
+//
+// Parameters:
+// - w: the io.Writer the animation is written to.
+// - opt: the RenderOptions to render the frames with.
+// - anim: the format and timing of the animation.
+//
+// Returns:
+//   - error: an error if the QRSequence is not complete, the options are
+//     invalid or the frames cannot be rendered or written.
+func (s QRSequence) WriteAnimation(w io.Writer, opt RenderOptions, anim AnimationOptions) error {
+	if anim.FPS <= 0 {
+		return ErrInvalidFrameRate
+	}
+	if anim.Loops < 0 {
+		return ErrInvalidLoops
+	}
+	images, err := s.playFrames(opt)
+	if err != nil {
+		return err
+	}
+
+	switch anim.Format {
+	case AnimationGIF:
+		return writeGIF(w, animationFrames(images, true), anim)
+	case AnimationAPNG:
+		return writeAPNG(w, animationFrames(images, isGray(images)), anim)
+	}
+	return ErrUnknownAnimationFormat
+}
+
+// animationFrames centers the frames on white canvases of a common size,
+// gray ones if gray is set.
+func animationFrames(images []image.Image, gray bool) []draw.Image {
+	var size image.Point
+	for _, img := range images {
+		size.X = max(size.X, img.Bounds().Dx())
+		size.Y = max(size.Y, img.Bounds().Dy())
+	}
+
+	frames := make([]draw.Image, len(images))
+	for i, img := range images {
+		var canvas draw.Image = image.NewRGBA(image.Rectangle{Max: size})
+		if gray {
+			canvas = image.NewGray(image.Rectangle{Max: size})
+		}
+		draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+		b := img.Bounds()
+		offset := image.Pt((size.X-b.Dx())/2, (size.Y-b.Dy())/2)
+		draw.Draw(canvas, image.Rectangle{Min: offset, Max: offset.Add(b.Size())}, img, b.Min, draw.Over)
+		frames[i] = canvas
+	}
+	return frames
+}
+
+// isGray reports whether all images only hold gray levels.
+func isGray(images []image.Image) bool {
+	for _, img := range images {
+		switch img := img.(type) {
+		case *image.Gray:
+		case *image.Paletted:
+			for _, c := range img.Palette {
+				r, g, b, a := c.RGBA()
+				if r != g || g != b || a != 0xffff {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func writeGIF(w io.Writer, frames []draw.Image, anim AnimationOptions) error {
+	grays := make(color.Palette, 256)
+	for i := range grays {
+		grays[i] = color.Gray{Y: uint8(i)}
+	}
+	delay := max(1, int(math.Round(100/anim.FPS)))
+
+	// LoopCount counts the repetitions after the first play, with -1 for none
+	// and 0 for forever
+	g := &gif.GIF{LoopCount: anim.Loops - 1}
+	switch anim.Loops {
+	case 0:
+		g.LoopCount = 0
+	case 1:
+		g.LoopCount = -1
+	}
+	for _, frame := range frames {
+		p := image.NewPaletted(frame.Bounds(), grays)
+		draw.Draw(p, p.Rect, frame, frame.Bounds().Min, draw.Src)
+		g.Image = append(g.Image, p)
+		g.Delay = append(g.Delay, delay)
+	}
+	return gif.EncodeAll(w, g)
+}
+
+// writeAPNG writes the frames as an animated PNG. Every frame is encoded with
+// image/png, and its image data is moved into the frame chunks of the APNG
+// format.
+func writeAPNG(w io.Writer, frames []draw.Image, anim AnimationOptions) error {
+	aw := &apngWriter{w: w}
+	aw.write(pngSignature)
+
+	// the delay is 100 / delayDen seconds, precise to 1/100 frames per second
+	delayDen := uint16(min(math.Round(anim.FPS*100), math.MaxUint16))
+	seq := uint32(0)
+	for i, frame := range frames {
+		buf := new(bytes.Buffer)
+		if err := png.Encode(buf, frame); err != nil {
+			return err
+		}
+		chunks, err := pngChunks(buf.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			aw.chunk("IHDR", chunks[0].data)
+			actl := binary.BigEndian.AppendUint32(nil, uint32(len(frames)))
+			aw.chunk("acTL", binary.BigEndian.AppendUint32(actl, uint32(anim.Loops)))
+		}
+
+		size := frame.Bounds().Size()
+		fctl := binary.BigEndian.AppendUint32(nil, seq)
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(size.X))
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(size.Y))
+		fctl = binary.BigEndian.AppendUint64(fctl, 0) // x and y offset
+		fctl = binary.BigEndian.AppendUint16(fctl, 100)
+		fctl = binary.BigEndian.AppendUint16(fctl, delayDen)
+		fctl = append(fctl, 0, 0) // no disposal, no blending
+		aw.chunk("fcTL", fctl)
+		seq++
+
+		for _, c := range chunks {
+			if c.typ != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				aw.chunk("IDAT", c.data)
+				continue
+			}
+			aw.chunk("fdAT", append(binary.BigEndian.AppendUint32(nil, seq), c.data...))
+			seq++
+		}
+	}
+	aw.chunk("IEND", nil)
+	return aw.err
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// pngChunks splits a PNG file as written by image/png into its chunks.
+func pngChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrInvalidPNG
+	}
+	var chunks []pngChunk
+	for data = data[len(pngSignature):]; len(data) >= 12; {
+		n := int(binary.BigEndian.Uint32(data))
+		if n > len(data)-12 {
+			return nil, ErrInvalidPNG
+		}
+		chunks = append(chunks, pngChunk{typ: string(data[4:8]), data: data[8 : 8+n]})
+		data = data[12+n:]
+	}
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" {
+		return nil, ErrInvalidPNG
+	}
+	return chunks, nil
+}
+
+// apngWriter writes PNG chunks, keeping the first error.
+type apngWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (aw *apngWriter) write(b []byte) {
+	if aw.err == nil {
+		_, aw.err = aw.w.Write(b)
+	}
+}
+
+// chunk writes a chunk with its length and CRC.
+func (aw *apngWriter) chunk(typ string, data []byte) {
+	header := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	header = append(header, typ...)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	aw.write(header)
+	aw.write(data)
+	aw.write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+}
@@ -0,0 +1,117 @@
+package qrseq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// Backup lays out a payload on printed pages for long-term paper backups, with
+// parity frames distributed across the pages, so the payload can be recovered
+// even if an entire page is lost.
+//
+// The payload is fountain coded. Every data frame carries one block of the
+// payload, and every parity frame the XOR of the blocks of one group. The
+// frames at the same position of all pages form a group: the data frames of
+// its blocks and its parity frame, which rotates through the pages. Losing a
+// page therefore costs every group at most one frame, which the other frames
+// of the group restore.
+//
+// Pages are decoded by a receiving QRSequence with DecodePage, in any order.
+type Backup struct {
+	// Pages holds the texts of the QR codes of every page, by position. Empty
+	// texts mark unused positions on the pages of a payload that does not
+	// fill the last group.
+	Pages    [][]string
+	Manifest BackupManifest
+}
+
+// BackupManifest describes a Backup and its recovery threshold. It is meant to
+// be stored or printed with the pages.
+type BackupManifest struct {
+	ChunkSize     ChunkSize `json:"chunk_size"`
+	Length        int       `json:"length"`          // payload length in bytes
+	SHA256        string    `json:"sha256"`          // hex digest of the payload
+	Pages         int       `json:"pages"`           // number of pages
+	FramesPerPage int       `json:"frames_per_page"` // QR codes on every page
+	DataFrames    int       `json:"data_frames"`     // frames carrying a payload block
+	ParityFrames  int       `json:"parity_frames"`   // frames carrying the parity of a group
+	LostPages     int       `json:"lost_pages"`      // whole pages that may be lost
+	Recovery      string    `json:"recovery"`        // the recovery threshold in words
+}
+
+// NewBackup lays out data on pages holding at most framesPerPage QR codes
+// each. The number of pages follows from the number of blocks of the payload:
+// every page but one holds data, so the parity costs about one page. The
+// sequence ID of the frames is drawn from the source set with WithRand and the
+// frames carry the tag set with WithAppTag, other options are ignored.
+//
+// Parameters:
+// - data: the payload.
+// - chunkSize: a ChunkSize enum value specifying the size of each frame.
+// - framesPerPage: the maximum number of QR codes on a page.
+// - opts: the options to apply.
+//
+// Returns:
+//   - *Backup: the new Backup.
+//   - error: an error if an option is invalid, the chunk size is invalid,
+//     framesPerPage is not positive or the payload is too large for the chunk
+//     size.
+func NewBackup(data []byte, chunkSize ChunkSize, framesPerPage int, opts ...Option) (*Backup, error) {
+	if framesPerPage < 1 {
+		return nil, ErrInvalidFramesPerPage
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := internal.NewFountainEncoder(data, uint16(chunkSize), readSeqID(orRand(o.rand)), o.appTag)
+	if err != nil {
+		return nil, err
+	}
+
+	// each group has one frame on every page, its blocks on all pages but
+	// the one holding its parity
+	blocks := enc.Blocks()
+	pages := (blocks+framesPerPage-1)/framesPerPage + 1
+	perGroup := pages - 1
+	groups := (blocks + perGroup - 1) / perGroup
+
+	b := &Backup{Pages: make([][]string, pages)}
+	for p := range b.Pages {
+		b.Pages[p] = make([]string, groups)
+	}
+	for g := 0; g < groups; g++ {
+		first := g * perGroup
+		count := min(perGroup, blocks-first)
+		parityPage := g % pages
+		b.Pages[parityPage][g] = internal.EncodeText(enc.ParityFrame(first, count), internal.TextBase64)
+
+		nr := first
+		for p := 0; p < pages && nr < first+count; p++ {
+			if p == parityPage {
+				continue
+			}
+			b.Pages[p][g] = internal.EncodeText(enc.Frame(uint32(nr)), internal.TextBase64)
+			nr++
+		}
+	}
+
+	digest := sha256.Sum256(data)
+	b.Manifest = BackupManifest{
+		ChunkSize:     chunkSize,
+		Length:        len(data),
+		SHA256:        hex.EncodeToString(digest[:]),
+		Pages:         pages,
+		FramesPerPage: groups,
+		DataFrames:    blocks,
+		ParityFrames:  groups,
+		LostPages:     1,
+		Recovery: fmt.Sprintf("any %d of the %d pages recover the payload; more generally it is "+
+			"recoverable as long as at most one QR code is lost at each of the %d positions "+
+			"across all pages", pages-1, pages, groups),
+	}
+	return b, nil
+}
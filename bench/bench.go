@@ -0,0 +1,277 @@
+//go:build !core
+
+// Package bench measures the encode and decode performance of qrseq on
+// standardized workloads, so regressions can be tracked across releases.
+//
+// A workload sends a pseudo-random payload of a fixed size with a fixed chunk
+// size and passes every rendered frame, degraded like a camera capture, to a
+// receiver once. The payloads and sequence IDs are generated from a fixed
+// seed, so every run measures the same frames. Reports can be stored as JSON and compared with
+// the report of a later run.
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math/rand"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/airsigner/qrseq"
+	"github.com/airsigner/qrseq/internal"
+)
+
+// blockSize is the size of a QR code module of the rendered frames in pixels.
+const blockSize = 4
+
+// seed is the seed of the generated payloads.
+const seed = 1
+
+// Degradation is a simulated capture quality the frames are decoded at.
+type Degradation struct {
+	// ModuleSize is the number of camera pixels a QR code module covers, or
+	// 0 to decode the rendered frames as they are.
+	ModuleSize float64 `json:"module_size"`
+	// Radius is the radius of the box blur applied after scaling.
+	Radius int `json:"radius"`
+}
+
+// The standard degradation levels, from the rendered frames to a small and
+// blurred capture.
+var (
+	DegradationNone     = Degradation{}
+	DegradationModerate = Degradation{ModuleSize: 3, Radius: 1}
+	DegradationSevere   = Degradation{ModuleSize: 2.25, Radius: 1}
+)
+
+// String returns a short name of the degradation.
+func (d Degradation) String() string {
+	switch d {
+	case DegradationNone:
+		return "none"
+	case DegradationModerate:
+		return "moderate"
+	case DegradationSevere:
+		return "severe"
+	}
+	return fmt.Sprintf("%gpx/r%d", d.ModuleSize, d.Radius)
+}
+
+// Workload is one benchmark case.
+type Workload struct {
+	Name        string          `json:"name"`
+	PayloadSize int             `json:"payload_size"` // bytes
+	ChunkSize   qrseq.ChunkSize `json:"chunk_size"`
+	Degradation Degradation     `json:"degradation"`
+}
+
+// Standard returns the standard workloads, every combination of payloads of
+// 1 KiB and 16 KiB, the chunk sizes from 128 to 2048 bytes and the standard
+// degradation levels. Their names are stable across releases.
+//
+// Returns:
+// - []Workload: the standard workloads.
+func Standard() []Workload {
+	var workloads []Workload
+	for _, size := range []int{1 << 10, 16 << 10} {
+		for _, chunkSize := range []qrseq.ChunkSize{qrseq.ChunkSize128, qrseq.ChunkSize256, qrseq.ChunkSize512, qrseq.ChunkSize1024, qrseq.ChunkSize2048} {
+			for _, d := range []Degradation{DegradationNone, DegradationModerate, DegradationSevere} {
+				workloads = append(workloads, Workload{
+					Name:        fmt.Sprintf("%dKiB/chunk%d/%s", size>>10, chunkSize, d),
+					PayloadSize: size,
+					ChunkSize:   chunkSize,
+					Degradation: d,
+				})
+			}
+		}
+	}
+	return workloads
+}
+
+// Result is the outcome of a workload. The durations are the fastest of all
+// rounds.
+type Result struct {
+	Workload
+	Frames   int           `json:"frames"`   // frames of the sequence
+	Decoded  int           `json:"decoded"`  // frames that decoded into a chunk
+	Complete bool          `json:"complete"` // whether the receiver completed
+	Encode   time.Duration `json:"encode"`   // creating the sender
+	Render   time.Duration `json:"render"`   // rendering all frames
+	Decode   time.Duration `json:"decode"`   // decoding all frames
+}
+
+// DecodePerFrame returns the mean decode time of a frame.
+func (r Result) DecodePerFrame() time.Duration {
+	if r.Frames == 0 {
+		return 0
+	}
+	return r.Decode / time.Duration(r.Frames)
+}
+
+// Report holds the results of a benchmark run and the environment it ran in.
+type Report struct {
+	GoVersion string    `json:"go_version"`
+	GOOS      string    `json:"goos"`
+	GOARCH    string    `json:"goarch"`
+	CPUs      int       `json:"cpus"`
+	Time      time.Time `json:"time"`
+	Results   []Result  `json:"results"`
+}
+
+// Regression describes a workload that got slower or stopped completing
+// compared to a baseline report.
+type Regression struct {
+	Name   string
+	Before Result
+	After  Result
+}
+
+// Run runs the workloads, each the given number of rounds, keeping the
+// fastest time of every phase.
+//
+// Parameters:
+// - workloads: the workloads to run, e.g. Standard().
+// - rounds: the number of times every workload is run, at least 1.
+//
+// Returns:
+// - *Report: the results of all workloads in the given order.
+// - error: an error if a workload is invalid.
+func Run(workloads []Workload, rounds int) (*Report, error) {
+	report := &Report{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		Time:      time.Now().UTC(),
+	}
+	for _, w := range workloads {
+		var best Result
+		for round := 0; round < max(rounds, 1); round++ {
+			result, err := run(w)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", w.Name, err)
+			}
+			if round == 0 {
+				best = result
+				continue
+			}
+			best.Encode = min(best.Encode, result.Encode)
+			best.Render = min(best.Render, result.Render)
+			best.Decode = min(best.Decode, result.Decode)
+		}
+		report.Results = append(report.Results, best)
+	}
+	return report, nil
+}
+
+// run runs a workload once.
+func run(w Workload) (Result, error) {
+	if w.PayloadSize < 1 {
+		return Result{}, errors.New("invalid payload size")
+	}
+	payload := make([]byte, w.PayloadSize)
+	rand.New(rand.NewSource(seed)).Read(payload)
+	result := Result{Workload: w}
+
+	start := time.Now()
+	sender, err := qrseq.New(payload, qrseq.WithChunkSize(w.ChunkSize), qrseq.WithRand(rand.New(rand.NewSource(seed))))
+	if err != nil {
+		return Result{}, err
+	}
+	result.Encode = time.Since(start)
+
+	start = time.Now()
+	frames, err := sender.QRCodes(blockSize)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Render = time.Since(start)
+	result.Frames = len(frames)
+
+	// degrading the frames simulates the camera and is not measured
+	if w.Degradation != DegradationNone {
+		for i, frame := range frames {
+			frames[i] = degrade(frame, w.Degradation)
+		}
+	}
+
+	receiver := qrseq.NewEmpty()
+	start = time.Now()
+	for _, frame := range frames {
+		if receiver.DecodeImage(frame) == nil {
+			result.Decoded++
+		}
+	}
+	result.Decode = time.Since(start)
+	result.Complete = receiver.IsComplete()
+	return result, nil
+}
+
+func degrade(img image.Image, d Degradation) image.Image {
+	return internal.Degrade(img, blockSize/d.ModuleSize, d.Radius)
+}
+
+// Compare returns the workloads that stopped completing or whose decode time
+// per frame grew by more than the given fraction compared to the baseline
+// report. Workloads missing from either report are ignored.
+//
+// Parameters:
+// - baseline: the report of a previous run to compare against.
+// - tolerance: the accepted slowdown, e.g. 0.1 for 10%.
+//
+// Returns:
+// - []Regression: the regressed workloads.
+func (r Report) Compare(baseline Report, tolerance float64) []Regression {
+	before := make(map[string]Result, len(baseline.Results))
+	for _, result := range baseline.Results {
+		before[result.Name] = result
+	}
+
+	var regressions []Regression
+	for _, after := range r.Results {
+		b, ok := before[after.Name]
+		if !ok {
+			continue
+		}
+		slower := float64(after.DecodePerFrame()) > float64(b.DecodePerFrame())*(1+tolerance)
+		if (b.Complete && !after.Complete) || slower {
+			regressions = append(regressions, Regression{Name: after.Name, Before: b, After: after})
+		}
+	}
+	return regressions
+}
+
+// WriteText writes the report as a table for humans.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s %s/%s, %d CPUs\n", r.GoVersion, r.GOOS, r.GOARCH, r.CPUs)
+	fmt.Fprintln(tw, "workload\tframes\tdecoded\tcomplete\tencode\trender\tdecode\tdecode/frame\t")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%t\t%v\t%v\t%v\t%v\t\n",
+			res.Name, res.Frames, res.Decoded, res.Complete,
+			res.Encode.Round(time.Microsecond), res.Render.Round(time.Microsecond),
+			res.Decode.Round(time.Microsecond), res.DecodePerFrame().Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON, so it can be stored as the baseline of
+// later runs.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReport reads a report written by Report.WriteJSON.
+func ReadReport(r io.Reader) (*Report, error) {
+	report := new(Report)
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
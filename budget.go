@@ -0,0 +1,63 @@
+package qrseq
+
+import "time"
+
+// SetFrameBudget sets the maximum time DecodeImage may spend on a single frame.
+//
+// If decoding a frame takes longer, DecodeImage gives up on it and returns a
+// DecodeError with FailureAborted, so the caller can move on to the next
+// camera frame instead of building a growing backlog on slow hardware. The
+// aborted decode finishes in the background. Frames arriving while it still
+// runs are aborted immediately, so at most one decode is ever pending.
+//
+// Parameters:
+// - budget: the time budget per frame, or zero to disable the budget.
+func (s *QRSequence) SetFrameBudget(budget time.Duration) {
+	s.frameBudget = budget
+}
+
+// decodeFrame runs decode, which decodes a frame and the rotation of its QR
+// code from an image, within the frame budget of the QRSequence. An aborted
+// decode keeps running, so its results are only handed over the channel.
+func (s *QRSequence) decodeFrame(decode func() ([]byte, int, error)) ([]byte, int, error) {
+	if s.frameBudget <= 0 {
+		return decode()
+	}
+
+	if s.decoding == nil {
+		s.decoding = make(chan struct{}, 1)
+	}
+	decoding := s.decoding
+	select {
+	case decoding <- struct{}{}:
+	default:
+		return nil, 0, &DecodeError{
+			Failure: FailureAborted,
+			Err:     ErrFrameBusy,
+		}
+	}
+
+	type outcome struct {
+		frame    []byte
+		rotation int
+		err      error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		frame, rotation, err := decode()
+		<-decoding
+		done <- outcome{frame, rotation, err}
+	}()
+
+	timer := time.NewTimer(s.frameBudget)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.frame, o.rotation, o.err
+	case <-timer.C:
+		return nil, 0, &DecodeError{
+			Failure: FailureAborted,
+			Err:     ErrFrameBudget,
+		}
+	}
+}
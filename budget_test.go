@@ -0,0 +1,63 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+)
+
+// slowDecoder returns text with a rotation of 90 degrees after delay. It
+// does not synchronize with the test, so the race detector catches state
+// shared by an aborted decode.
+type slowDecoder struct {
+	text  string
+	delay time.Duration
+}
+
+func (d *slowDecoder) Decode(img image.Image) (string, int, error) {
+	time.Sleep(d.delay)
+	return d.text, 90, nil
+}
+
+func TestFrameBudgetAbortsSlowDecode(t *testing.T) {
+	sender, err := New([]byte("budget"), WithChunkSize(ChunkSize32))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	payloads, err := sender.Payloads()
+	if err != nil {
+		t.Fatalf("Payloads: %v", err)
+	}
+
+	dec := &slowDecoder{text: payloads[0], delay: 50 * time.Millisecond}
+	receiver := NewEmpty(WithDecoder(dec))
+	receiver.SetFrameBudget(10 * time.Millisecond)
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+
+	var decodeErr *DecodeError
+	err = receiver.DecodeImage(img)
+	if !errors.As(err, &decodeErr) || decodeErr.Failure != FailureAborted {
+		t.Fatalf("got %v, want an aborted decode", err)
+	}
+
+	// the aborted decode finishes in the background, later frames are
+	// aborted until it has
+	receiver.SetFrameBudget(time.Second)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err = receiver.DecodeImage(img)
+		if !errors.As(err, &decodeErr) || decodeErr.Failure != FailureAborted || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("DecodeImage: %v", err)
+	}
+	if !receiver.IsComplete() {
+		t.Errorf("sequence not complete")
+	}
+}
@@ -0,0 +1,122 @@
+//go:build !core
+
+// Package capture reads frames from a camera or a network stream and feeds
+// them into a receiving QRSequence until it is complete, so applications do
+// not have to write their own capture loop.
+//
+// V4L2 cameras are supported on Linux, MJPEG streams over HTTP and recorded
+// videos everywhere. Webcams on other platforms are supported through OpenCV
+// with the gocv build tag, see OpenWebcam.
+package capture
+
+import (
+	"context"
+	"errors"
+	"image"
+	"os"
+	"strings"
+
+	"github.com/airsigner/qrseq"
+)
+
+// ErrNoFrame means a Webcam stopped delivering frames.
+var ErrNoFrame = errors.New("webcam delivered no frame")
+
+// Source delivers the frames of a camera or stream. It is implemented by
+// qrseq.Camera, qrseq.MJPEGStream, qrseq.Libcamera and qrseq.Video.
+type Source interface {
+	// ReadFrame waits for the next frame. A *qrseq.FrameError reports a
+	// frame that cannot be decoded, which is skipped.
+	ReadFrame() (image.Image, error)
+	// Close releases the source.
+	Close() error
+}
+
+// Config describes how a V4L2 camera is opened by Open. It is ignored for
+// streams.
+type Config struct {
+	// Width and Height are the requested size of the frames in pixels. The
+	// driver may pick the closest size it supports.
+	Width  int
+	Height int
+	// MJPEG captures JPEG compressed frames instead of uncompressed YUYV
+	// ones, which most USB cameras need for high resolutions at full frame
+	// rate.
+	MJPEG bool
+}
+
+// Open opens a camera or stream by name. Names starting with http:// or
+// https:// are opened as MJPEG streams, regular files as videos decoded with
+// ffmpeg and other names as V4L2 devices such as /dev/video0.
+//
+// Parameters:
+//   - name: the URL of the stream, or the path of the video file or camera
+//     device.
+//   - config: the configuration of the camera.
+//
+// Returns:
+// - Source: the opened source.
+// - error: an error if the source cannot be opened.
+func Open(name string, config Config) (Source, error) {
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		return qrseq.OpenMJPEGStream(name)
+	}
+	if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() {
+		return qrseq.OpenVideo(name)
+	}
+	return openDevice(name, config)
+}
+
+// Receive decodes frames of src into the receiving QRSequence until it is
+// complete, with the ReceiveContext method of src if it has one and with
+// qrseq.ReceiveFrames otherwise. Frames that cannot be decoded or hold no
+// readable QR code are skipped. The source is not closed.
+//
+// Cancelling ctx interrupts the frame a qrseq.Camera, qrseq.MJPEGStream,
+// qrseq.Libcamera or qrseq.Video is waiting for, after which the source can
+// only be closed. Other sources, such as a Webcam, finish the frame being
+// waited for first.
+//
+// Parameters:
+// - ctx: the context to stop receiving with.
+// - src: the source of the frames.
+// - seq: the receiving QRSequence.
+//
+// Returns:
+//   - error: the error of ctx if it is done first, qrseq.ErrSourceEnded if the
+//     source ends first, an error if a frame cannot be read, or the terminal
+//     error of the QRSequence.
+func Receive(ctx context.Context, src Source, seq *qrseq.QRSequence) error {
+	if r, ok := src.(contextReceiver); ok {
+		return r.ReceiveContext(ctx, seq)
+	}
+	return qrseq.ReceiveFrames(ctx, src, seq, nil)
+}
+
+// contextReceiver is implemented by the sources of package qrseq, which know
+// how to interrupt a pending frame.
+type contextReceiver interface {
+	ReceiveContext(ctx context.Context, seq *qrseq.QRSequence) error
+}
+
+// ReceiveFrom opens a source by name like Open, decodes its frames into the
+// receiving QRSequence like Receive and closes it again.
+//
+// Parameters:
+//   - ctx: the context to stop receiving with.
+//   - name: the URL of the stream, or the path of the video file or camera
+//     device.
+//   - config: the configuration of the camera.
+//   - seq: the receiving QRSequence.
+//
+// Returns:
+//   - error: an error if the source cannot be opened, or the error of
+//     Receive.
+func ReceiveFrom(ctx context.Context, name string, config Config, seq *qrseq.QRSequence) error {
+	src, err := Open(name, config)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return Receive(ctx, src, seq)
+}
@@ -0,0 +1,13 @@
+//go:build !core && (!linux || mips || mipsle || mips64 || mips64le || ppc64 || ppc64le)
+
+package capture
+
+import (
+	"errors"
+	"fmt"
+)
+
+// openDevice reports that V4L2 cameras are not supported on this platform.
+func openDevice(string, Config) (Source, error) {
+	return nil, fmt.Errorf("%w: cameras are only supported on Linux, use OpenWebcam", errors.ErrUnsupported)
+}
@@ -0,0 +1,64 @@
+//go:build gocv && !core
+
+package capture
+
+import (
+	"image"
+
+	"github.com/airsigner/qrseq"
+	"gocv.io/x/gocv"
+)
+
+// Webcam is a Source capturing frames through the VideoCapture of OpenCV,
+// which supports the webcams of all platforms OpenCV runs on.
+//
+// It is only built with the gocv build tag, which needs OpenCV installed and
+// gocv.io/x/gocv added to the module of the application.
+type Webcam struct {
+	capture *gocv.VideoCapture
+	frame   gocv.Mat
+}
+
+// OpenWebcam opens the webcam with the given index, 0 for the default one.
+//
+// Parameters:
+// - id: the index of the webcam.
+// - config: the configuration of the webcam, MJPEG is ignored.
+//
+// Returns:
+// - *Webcam: the opened webcam.
+// - error: an error if the webcam cannot be opened.
+func OpenWebcam(id int, config Config) (*Webcam, error) {
+	capture, err := gocv.OpenVideoCapture(id)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > 0 && config.Height > 0 {
+		capture.Set(gocv.VideoCaptureFrameWidth, float64(config.Width))
+		capture.Set(gocv.VideoCaptureFrameHeight, float64(config.Height))
+	}
+	return &Webcam{capture: capture, frame: gocv.NewMat()}, nil
+}
+
+// ReadFrame waits for the next frame of the webcam.
+//
+// Returns:
+//   - image.Image: the frame.
+//   - error: an error if the webcam stopped delivering frames, or a
+//     *qrseq.FrameError if the frame cannot be converted.
+func (w *Webcam) ReadFrame() (image.Image, error) {
+	if !w.capture.Read(&w.frame) || w.frame.Empty() {
+		return nil, ErrNoFrame
+	}
+	img, err := w.frame.ToImage()
+	if err != nil {
+		return nil, &qrseq.FrameError{Err: err}
+	}
+	return img, nil
+}
+
+// Close closes the webcam.
+func (w *Webcam) Close() error {
+	w.frame.Close()
+	return w.capture.Close()
+}
@@ -0,0 +1,14 @@
+//go:build linux && !core && !(mips || mipsle || mips64 || mips64le || ppc64 || ppc64le)
+
+package capture
+
+import "github.com/airsigner/qrseq"
+
+// openDevice opens a V4L2 camera.
+func openDevice(path string, config Config) (Source, error) {
+	format := qrseq.PixelFormatYUYV
+	if config.MJPEG {
+		format = qrseq.PixelFormatMJPEG
+	}
+	return qrseq.OpenCamera(path, config.Width, config.Height, format)
+}
@@ -0,0 +1,119 @@
+package qrseq
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of a QRSequence and of the components driven by
+// it: chunk arrival times and the durations and heatmaps derived from them,
+// the timestamps and idle eviction of a SessionManager, watermarks, session
+// recordings and the frame schedule of the players.
+//
+// Air-gapped devices whose real-time clock drifts can correct it with
+// SkewClock, and tests can make time deterministic with a ManualClock. The
+// frame budget always uses the system clock, since it bounds real decoding
+// time, and so do the modification times of written files, which viewers
+// compare with the file system.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep pauses the calling goroutine for the duration d.
+	Sleep(d time.Duration)
+}
+
+// systemClock is the Clock of the operating system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// skewClock is a Clock running at a fixed offset to another Clock.
+type skewClock struct {
+	clock Clock
+	skew  time.Duration
+}
+
+func (c skewClock) Now() time.Time        { return c.clock.Now().Add(c.skew) }
+func (c skewClock) Sleep(d time.Duration) { c.clock.Sleep(d) }
+
+// SkewClock returns a Clock that runs at a fixed offset to another Clock, e.g.
+// to correct a real-time clock known to be off.
+//
+// Parameters:
+// - clock: the Clock to offset, or nil for the system clock.
+// - skew: the offset added to the times of clock.
+//
+// Returns:
+// - Clock: the offset Clock.
+func SkewClock(clock Clock, skew time.Duration) Clock {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return skewClock{clock: clock, skew: skew}
+}
+
+// ManualClock is a Clock that only advances when told to, for deterministic
+// tests. Sleep advances it instead of waiting. It is safe for concurrent use.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a ManualClock standing at the given time.
+//
+// Parameters:
+// - start: the initial time of the clock.
+//
+// Returns:
+// - *ManualClock: the new ManualClock.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the time the clock stands at.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep advances the clock by d, if d is positive, and returns at once.
+func (c *ManualClock) Sleep(d time.Duration) {
+	if d > 0 {
+		c.Advance(d)
+	}
+}
+
+// Advance moves the clock forward by d.
+//
+// Parameters:
+// - d: the duration to advance the clock by.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SetClock sets the Clock of the QRSequence. A receiver stamps chunk arrivals
+// with it, a sender watermarks and schedules its frames with it. The
+// sequences delivered by NewSequence keep it.
+//
+// Parameters:
+// - clock: the Clock, or nil for the system clock.
+func (s *QRSequence) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// timeSource returns the Clock of the QRSequence.
+func (s QRSequence) timeSource() Clock {
+	if s.clock == nil {
+		return systemClock{}
+	}
+	return s.clock
+}
+
+// sleepUntil pauses until clock reaches t.
+func sleepUntil(clock Clock, t time.Time) {
+	clock.Sleep(t.Sub(clock.Now()))
+}
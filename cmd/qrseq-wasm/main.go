@@ -0,0 +1,17 @@
+//go:build js && wasm
+
+// Command qrseq-wasm is the WebAssembly module of browser receivers. It
+// installs the qrseq object of package wasm and keeps running, so the
+// functions stay available. Build it with the core tag:
+//
+//	GOOS=js GOARCH=wasm go build -tags core -o qrseq.wasm ./cmd/qrseq-wasm
+//
+// and load it with wasm_exec.js of the Go distribution.
+package main
+
+import "github.com/airsigner/qrseq/wasm"
+
+func main() {
+	wasm.Register()
+	select {}
+}
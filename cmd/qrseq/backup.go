@@ -0,0 +1,93 @@
+//go:build !core
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/airsigner/qrseq"
+)
+
+func backup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: qrseq backup [flags] <file>")
+		fs.PrintDefaults()
+	}
+	var (
+		out     = fs.String("o", "backup", "output directory for the pages and the manifest")
+		chunk   = fs.Int("chunk", int(qrseq.DefaultChunkSize), "chunk size in bytes: 32, 64, 128, 256, 512, 1024 or 2048")
+		perPage = fs.Int("per-page", 12, "maximum number of QR codes on a page")
+		block   = fs.Int("block", 4, "size of a QR code module in pixels")
+	)
+	path, err := parseArg(fs, args, "file")
+	if err != nil {
+		return err
+	}
+
+	chunkSize, ok := chunkSizes[*chunk]
+	if !ok {
+		return fmt.Errorf("invalid chunk size %d", *chunk)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	b, err := qrseq.NewBackup(data, chunkSize, *perPage)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	opt := qrseq.RenderOptions{BlockSize: *block}
+	for p := range b.Pages {
+		img, err := b.PageImage(p, opt)
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(filepath.Join(*out, fmt.Sprintf("page_%03d.png", p+1)))
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(f, img); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(b.Manifest.Recovery)
+	return os.WriteFile(filepath.Join(*out, "manifest.json"), append(manifest, '\n'), 0o644)
+}
+
+// manifestDigest returns the payload digest recorded in the manifest of a
+// backup directory, or an empty string if dir holds no manifest.
+func manifestDigest(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var manifest qrseq.BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("manifest.json: %v", err)
+	}
+	return manifest.SHA256, nil
+}
@@ -0,0 +1,75 @@
+//go:build !core
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/airsigner/qrseq/bench"
+)
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: qrseq bench [flags]")
+		fs.PrintDefaults()
+	}
+	var (
+		rounds    = fs.Int("rounds", 3, "number of times every workload is run")
+		jsonOut   = fs.String("json", "", "file the report is written to as JSON")
+		baseline  = fs.String("baseline", "", "JSON report of a previous run to compare against")
+		tolerance = fs.Float64("tolerance", 0.1, "accepted decode slowdown compared to the baseline")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	report, err := bench.Run(bench.Standard(), *rounds)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		return err
+	}
+	if *jsonOut != "" {
+		f, err := os.Create(*jsonOut)
+		if err != nil {
+			return err
+		}
+		if err := report.WriteJSON(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if *baseline == "" {
+		return nil
+	}
+
+	f, err := os.Open(*baseline)
+	if err != nil {
+		return err
+	}
+	base, err := bench.ReadReport(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	regressions := report.Compare(*base, *tolerance)
+	for _, r := range regressions {
+		fmt.Printf("regression %s: complete %t -> %t, decode/frame %v -> %v\n",
+			r.Name, r.Before.Complete, r.After.Complete, r.Before.DecodePerFrame(), r.After.DecodePerFrame())
+	}
+	if len(regressions) > 0 {
+		return fmt.Errorf("%d regressed workloads", len(regressions))
+	}
+	return nil
+}
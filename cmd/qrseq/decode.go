@@ -0,0 +1,128 @@
+//go:build !core
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"os/signal"
+
+	"github.com/airsigner/qrseq"
+	"github.com/airsigner/qrseq/capture"
+)
+
+func decode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: qrseq decode [flags] <directory, video, camera device or stream URL>")
+		fs.PrintDefaults()
+	}
+	var (
+		out     = fs.String("o", "-", "output file, - for standard output")
+		keyFile = fs.String("key", "", "file holding the 32 byte key the file is encrypted with, raw or hex encoded")
+		width   = fs.Int("width", 1280, "width of the camera frames in pixels")
+		height  = fs.Int("height", 720, "height of the camera frames in pixels")
+		mjpeg   = fs.Bool("mjpeg", false, "capture JPEG compressed frames from the camera")
+		tag     = fs.Uint("tag", 0, "application tag the sequence must carry, 0 to accept any")
+	)
+	source, err := parseArg(fs, args, "directory, video, camera device or stream URL")
+	if err != nil {
+		return err
+	}
+
+	if *tag > 0xffff {
+		return fmt.Errorf("invalid application tag %d", *tag)
+	}
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	opts := []qrseq.Option{qrseq.WithAppTag(uint16(*tag))}
+	if key != nil {
+		opts = append(opts, qrseq.WithEncryption(key))
+	}
+	seq := qrseq.NewEmpty(opts...)
+
+	if err := receive(seq, source, capture.Config{Width: *width, Height: *height, MJPEG: *mjpeg}); err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		_, err = os.Stdout.Write(seq.Data())
+		return err
+	}
+	return os.WriteFile(*out, seq.Data(), 0o644)
+}
+
+// receive decodes the frames of a directory of images, or of a source opened
+// by capture.Open, until the sequence is complete or the user interrupts.
+func receive(seq *qrseq.QRSequence, source string, config capture.Config) error {
+	var err error
+	if info, statErr := os.Stat(source); statErr == nil && info.IsDir() {
+		err = receiveFS(seq, os.DirFS(source))
+	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err = capture.ReceiveFrom(ctx, source, config, seq)
+		stop()
+	}
+	if err != nil {
+		return err
+	}
+	if !seq.IsComplete() {
+		return fmt.Errorf("sequence not complete, %.0f%% received", seq.Progress()*100)
+	}
+	return nil
+}
+
+// receiveFS decodes the images in fsys, which may be frames or pages of a
+// backup, in the order of their paths until the sequence is complete. Files
+// that are no images or hold no readable QR code are skipped.
+func receiveFS(seq *qrseq.QRSequence, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if seq.IsComplete() {
+			return fs.SkipAll
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		img, err := readImage(fsys, path)
+		if errors.Is(err, image.ErrFormat) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// printed pages hold several codes, the reader for a single code is
+		// more tolerant, so it is tried on images without a readable page
+		var decodeErr *qrseq.DecodeError
+		_, err = seq.DecodePage(img)
+		if errors.As(err, &decodeErr) {
+			err = seq.DecodeImage(img)
+		}
+		if err != nil && !errors.As(err, &decodeErr) {
+			return err
+		}
+		return nil
+	})
+}
+
+func readImage(fsys fs.FS, path string) (image.Image, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
@@ -0,0 +1,136 @@
+//go:build !core
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/airsigner/qrseq"
+)
+
+// chunkSizes maps the values of the -chunk flag to chunk sizes.
+var chunkSizes = map[int]qrseq.ChunkSize{
+	32:   qrseq.ChunkSize32,
+	64:   qrseq.ChunkSize64,
+	128:  qrseq.ChunkSize128,
+	256:  qrseq.ChunkSize256,
+	512:  qrseq.ChunkSize512,
+	1024: qrseq.ChunkSize1024,
+	2048: qrseq.ChunkSize2048,
+}
+
+// ecLevels maps the values of the -ec flag to error correction levels.
+var ecLevels = map[string]qrseq.ECLevel{
+	"":         qrseq.ECLevelDefault,
+	"low":      qrseq.ECLevelLow,
+	"medium":   qrseq.ECLevelMedium,
+	"quartile": qrseq.ECLevelQuartile,
+	"high":     qrseq.ECLevelHigh,
+}
+
+// textEncodings maps the values of the -text flag to text encodings.
+var textEncodings = map[string]qrseq.TextEncoding{
+	"base64": qrseq.TextBase64,
+	"base45": qrseq.TextBase45,
+	"raw":    qrseq.TextRaw,
+}
+
+func encode(args []string) error {
+	fs := flag.NewFlagSet("encode", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: qrseq encode [flags] <file>")
+		fs.PrintDefaults()
+	}
+	var (
+		out     = fs.String("o", "frames", "output directory for png, output file for gif and apng")
+		format  = fs.String("format", "png", "output format: png, gif or apng")
+		chunk   = fs.Int("chunk", int(qrseq.DefaultChunkSize), "chunk size in bytes: 32, 64, 128, 256, 512, 1024 or 2048")
+		ec      = fs.String("ec", "", "error correction level: low, medium, quartile or high, by default quartile or the highest the chunk size allows")
+		gzip    = fs.Bool("gzip", false, "compress the file with gzip")
+		text    = fs.String("text", "base64", "text encoding of the QR codes: base64, base45 or raw, which fit more data per QR code")
+		keyFile = fs.String("key", "", "file holding a 32 byte key to encrypt the file with, raw or hex encoded")
+		block   = fs.Int("block", 4, "size of a QR code module in pixels")
+		fps     = fs.Float64("fps", 5, "frames per second of gif and apng")
+		loops   = fs.Int("loops", 0, "number of times gif and apng are played, 0 for forever")
+		edge    = fs.Int("edge", 0, "number of chunks at both ends rendered at error correction level high")
+		sync    = fs.Bool("sync", false, "draw a corner marker that toggles with every frame")
+		tag     = fs.Uint("tag", 0, "application tag to send the sequence with, 0 for none")
+	)
+	path, err := parseArg(fs, args, "file")
+	if err != nil {
+		return err
+	}
+
+	chunkSize, ok := chunkSizes[*chunk]
+	if !ok {
+		return fmt.Errorf("invalid chunk size %d", *chunk)
+	}
+	level, ok := ecLevels[*ec]
+	if !ok {
+		return fmt.Errorf("unknown error correction level %q", *ec)
+	}
+	enc, ok := textEncodings[*text]
+	if !ok {
+		return fmt.Errorf("unknown text encoding %q", *text)
+	}
+	if *tag > 0xffff {
+		return fmt.Errorf("invalid application tag %d", *tag)
+	}
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	opts := []qrseq.Option{qrseq.WithChunkSize(chunkSize), qrseq.WithECLevel(level), qrseq.WithAppTag(uint16(*tag))}
+	if *gzip {
+		opts = append(opts, qrseq.WithCompression(qrseq.CompressionGzip))
+	}
+	if enc != qrseq.TextBase64 {
+		opts = append(opts, qrseq.WithTextEncoding(enc))
+	}
+	if key != nil {
+		opts = append(opts, qrseq.WithEncryption(key))
+	}
+	opt := qrseq.RenderOptions{BlockSize: *block, EdgeChunks: *edge, EdgeECLevel: qrseq.ECLevelHigh, SyncMarker: *sync}
+	seq, plan, err := qrseq.NewPlanned(data, opt, opts...)
+	if err != nil {
+		return err
+	}
+	for _, warning := range plan.Warnings {
+		fmt.Fprintln(os.Stderr, "qrseq: warning:", warning)
+	}
+
+	anim := qrseq.AnimationOptions{FPS: *fps, Loops: *loops}
+	return writeFrames(seq, opt, *format, *out, anim)
+}
+
+// writeFrames writes the frames of seq as PNG files into the directory out,
+// or as an animation of the format into the file out. The format of anim is
+// set from format.
+func writeFrames(seq *qrseq.QRSequence, opt qrseq.RenderOptions, format, out string, anim qrseq.AnimationOptions) error {
+	switch format {
+	case "png":
+		return seq.SavePNGs(out, opt)
+	case "gif", "apng":
+		anim.Format = qrseq.AnimationGIF
+		if format == "apng" {
+			anim.Format = qrseq.AnimationAPNG
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		if err := seq.WriteAnimation(f, opt, anim); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
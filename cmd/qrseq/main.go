@@ -0,0 +1,117 @@
+//go:build !core
+
+// Command qrseq sends files as sequences of QR codes and receives them again,
+// for scripts that move data over an air gap.
+//
+// Usage:
+//
+//	qrseq encode [flags] <file>
+//	qrseq decode [flags] <directory, video, camera device or stream URL>
+//	qrseq migrate [flags] <directory, video, camera device or stream URL>
+//	qrseq backup [flags] <file>
+//	qrseq verify [flags] <directory, zip, pdf, gif, recording or video>
+//	qrseq bench [flags]
+//
+// encode writes the frames of the file as PNG files into a directory, or as an
+// animated GIF or APNG file. decode reads the frames from a directory of
+// images, in the order of their names, from a video file decoded with ffmpeg,
+// from a V4L2 camera such as /dev/video0 or from an MJPEG stream over HTTP,
+// and writes the received file. migrate receives a sequence of legacy chunks
+// from the same sources and writes its frames again in the v2 format, with the
+// output flags of encode. backup writes the file as pages of QR codes for
+// printing, with parity codes spread across the pages so any one page may be
+// lost, and a manifest.json describing the pages and the digest of the file.
+// verify decodes a stored sequence from a directory or zip archive of images,
+// a PDF file rasterized with pdftoppm, an animated GIF, a session recording or
+// a video, and checks that it still reassembles to the digest embedded by the
+// sender or recorded at backup time, by default the one of the manifest of a
+// backup directory, for periodic checks of long-term backups. bench runs the
+// standard workloads of package bench and compares them with a baseline
+// report. Run a subcommand with -h to list its flags.
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const usage = `usage:
+  qrseq encode [flags] <file>
+  qrseq decode [flags] <directory, video, camera device or stream URL>
+  qrseq migrate [flags] <directory, video, camera device or stream URL>
+  qrseq backup [flags] <file>
+  qrseq verify [flags] <directory, zip, pdf, gif, recording or video>
+  qrseq bench [flags]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "encode":
+		err = encode(os.Args[2:])
+	case "decode":
+		err = decode(os.Args[2:])
+	case "migrate":
+		err = migrate(os.Args[2:])
+	case "backup":
+		err = backup(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	case "bench":
+		err = runBench(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "qrseq: unknown command %q\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "qrseq: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseArg parses the flags of a subcommand, which takes exactly one
+// argument after them.
+func parseArg(fs *flag.FlagSet, args []string, name string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", fmt.Errorf("expected one %s", name)
+	}
+	return fs.Arg(0), nil
+}
+
+// readKey reads a 32 byte key from a file, which holds it either as raw bytes
+// or hex encoded. An empty path means no key.
+func readKey(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if len(data) != 32 {
+		return nil, errors.New("key file must hold 32 bytes, raw or hex encoded")
+	}
+	return data, nil
+}
@@ -0,0 +1,55 @@
+//go:build !core
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/airsigner/qrseq"
+	"github.com/airsigner/qrseq/capture"
+)
+
+func migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: qrseq migrate [flags] <directory, video, camera device or stream URL>")
+		fs.PrintDefaults()
+	}
+	var (
+		out    = fs.String("o", "frames", "output directory for png, output file for gif and apng")
+		format = fs.String("format", "png", "output format: png, gif or apng")
+		block  = fs.Int("block", 4, "size of a QR code module in pixels")
+		fps    = fs.Float64("fps", 5, "frames per second of gif and apng")
+		loops  = fs.Int("loops", 0, "number of times gif and apng are played, 0 for forever")
+		width  = fs.Int("width", 1280, "width of the camera frames in pixels")
+		height = fs.Int("height", 720, "height of the camera frames in pixels")
+		mjpeg  = fs.Bool("mjpeg", false, "capture JPEG compressed frames from the camera")
+	)
+	source, err := parseArg(fs, args, "directory, video, camera device or stream URL")
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "png", "gif", "apng":
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	// legacy chunks carry no compressed or encrypted payloads, so no key is
+	// needed
+	seq := qrseq.NewEmpty()
+	if err := receive(seq, source, capture.Config{Width: *width, Height: *height, MJPEG: *mjpeg}); err != nil {
+		return err
+	}
+	if !seq.IsLegacy() {
+		fmt.Fprintln(os.Stderr, "qrseq: warning: the sequence already uses v2 chunks")
+	}
+	migrated, err := seq.Migrate()
+	if err != nil {
+		return err
+	}
+	opt := qrseq.RenderOptions{BlockSize: *block}
+	return writeFrames(migrated, opt, *format, *out, qrseq.AnimationOptions{FPS: *fps, Loops: *loops})
+}
@@ -0,0 +1,180 @@
+//go:build !core
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"image/gif"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/airsigner/qrseq"
+	"github.com/airsigner/qrseq/capture"
+)
+
+// recordingMagic starts a session recording written by qrseq.Recorder.
+const recordingMagic = "QRSR"
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: qrseq verify [flags] <directory, zip, pdf, gif, recording or video>")
+		fs.PrintDefaults()
+	}
+	var (
+		sum     = fs.String("sha256", "", "hex encoded SHA-256 digest the payload must match, as recorded at backup time, by default the one of the manifest.json of a backup directory")
+		keyFile = fs.String("key", "", "file holding the 32 byte key the file is encrypted with, raw or hex encoded, to check that it still decrypts")
+		dpi     = fs.Int("dpi", 150, "resolution pdf pages are rasterized at")
+	)
+	source, err := parseArg(fs, args, "directory, zip, pdf, gif, recording or video")
+	if err != nil {
+		return err
+	}
+
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	var opts []qrseq.Option
+	if key != nil {
+		opts = append(opts, qrseq.WithEncryption(key))
+	}
+	seq := qrseq.NewEmpty(opts...)
+	if info, statErr := os.Stat(source); *sum == "" && statErr == nil && info.IsDir() {
+		if *sum, err = manifestDigest(source); err != nil {
+			return err
+		}
+	}
+	if *sum != "" {
+		want, err := hex.DecodeString(*sum)
+		if err != nil || len(want) != sha256.Size {
+			return errors.New("-sha256 must be 64 hex digits")
+		}
+		seq.ExpectDigest([sha256.Size]byte(want))
+	}
+
+	if err := receiveArchive(seq, source, *dpi); err != nil {
+		return err
+	}
+	if err := seq.Err(); err != nil {
+		return err
+	}
+	if !seq.IsComplete() {
+		return fmt.Errorf("sequence not complete, %.0f%% received, missing chunks %v", seq.Progress()*100, seq.Missing())
+	}
+
+	// a recorded digest has been checked on completion and covers the whole
+	// payload, without one the digest embedded by the sender is required
+	if *sum == "" {
+		if err := seq.Verify(); err != nil {
+			return err
+		}
+	}
+	digest, err := seq.Digest()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("ok: %d chunks, %d bytes, sha256 %x\n", len(seq.Received()), len(seq.Data()), digest)
+	return nil
+}
+
+// receiveArchive decodes the frames of a stored sequence, choosing the reader
+// by the kind of file.
+func receiveArchive(seq *qrseq.QRSequence, source string, dpi int) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return receiveFS(seq, os.DirFS(source))
+	}
+
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".zip":
+		zr, err := zip.OpenReader(source)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return receiveFS(seq, zr)
+	case ".pdf":
+		return receivePDF(seq, source, dpi)
+	case ".gif":
+		return receiveGIF(seq, source)
+	}
+
+	if isRecording(source) {
+		f, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return qrseq.Replay(f, seq, false)
+	}
+	return receive(seq, source, capture.Config{})
+}
+
+// receivePDF rasterizes the pages of a PDF file with pdftoppm of poppler and
+// decodes them.
+func receivePDF(seq *qrseq.QRSequence, path string, dpi int) error {
+	dir, err := os.MkdirTemp("", "qrseq-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.Command("pdftoppm", "-r", fmt.Sprint(dpi), "-gray", "-png", path, filepath.Join(dir, "page"))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New("pdftoppm: " + msg)
+		}
+		return err
+	}
+	return receiveFS(seq, os.DirFS(dir))
+}
+
+// receiveGIF decodes the frames of an animated GIF file.
+func receiveGIF(seq *qrseq.QRSequence, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		return err
+	}
+	for _, img := range g.Image {
+		if seq.IsComplete() {
+			break
+		}
+		var decodeErr *qrseq.DecodeError
+		if err := seq.DecodeImage(img); err != nil && !errors.As(err, &decodeErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isRecording reports whether the file at path is a session recording.
+func isRecording(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, len(recordingMagic))
+	_, err = io.ReadFull(f, magic)
+	return err == nil && string(magic) == recordingMagic
+}
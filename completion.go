@@ -0,0 +1,117 @@
+package qrseq
+
+import "github.com/airsigner/qrseq/internal"
+
+// CompletionMode selects what a receiving QRSequence does with frames that
+// arrive after it is complete.
+type CompletionMode uint8
+
+const (
+	// IgnoreAfterComplete drops frames after completion without decoding
+	// them. This is the default.
+	IgnoreAfterComplete CompletionMode = iota
+	// CountAfterComplete decodes frames after completion and counts them in
+	// Stats.AfterComplete, e.g. to tell how long the sender kept looping.
+	CountAfterComplete
+	// DetectNewSequence counts frames after completion like
+	// CountAfterComplete and starts a new receiving QRSequence as soon as a
+	// chunk with a different sequence ID arrives, so back-to-back transfers
+	// are received without restarting the scanner. The new sequence is
+	// delivered by NewSequence.
+	DetectNewSequence
+)
+
+// SetCompletionMode sets what the QRSequence does with frames that arrive
+// after it is complete.
+//
+// Parameters:
+// - mode: the CompletionMode to use.
+func (s *QRSequence) SetCompletionMode(mode CompletionMode) {
+	s.completionMode = mode
+}
+
+// NewSequence returns a channel on which the next sequence is delivered once
+// DetectNewSequence detects it.
+//
+// The delivered sequence is a receiving QRSequence that already holds the
+// chunk it was detected by and inherits the completion mode, key, error
+// correction level, frame budget and decoder. Frames passed to the completed sequence afterwards are forwarded to
+// it, so the caller can switch over at its own pace. Like Result, the channel
+// delivers exactly one value and is closed afterwards.
+//
+// Returns:
+// - <-chan *QRSequence: the channel delivering the next sequence.
+func (s *QRSequence) NewSequence() <-chan *QRSequence {
+	if s.newSequence == nil {
+		s.newSequence = make(chan *QRSequence, 1)
+		if s.next != nil {
+			s.deliverNewSequence()
+		}
+	}
+	return s.newSequence
+}
+
+// acceptsFrames reports whether frames passed to the QRSequence are decoded.
+func (s QRSequence) acceptsFrames() bool {
+	return !s.IsComplete() || s.completionMode != IgnoreAfterComplete
+}
+
+// receiveAfterComplete handles a decoded frame that arrived after the
+// QRSequence was complete, according to its completion mode.
+//
+// Parameters:
+// - frame: the bytes of the decoded frame.
+//
+// Returns:
+//   - error: the error of adding the frame to the next sequence, if it is
+//     forwarded there.
+func (s *QRSequence) receiveAfterComplete(frame []byte) error {
+	if s.next != nil {
+		return s.next.receive(frame)
+	}
+	if s.completionMode == IgnoreAfterComplete {
+		return nil
+	}
+	s.stats.AfterComplete++
+
+	if s.completionMode != DetectNewSequence || internal.FrameLayout(frame) != internal.LayoutV2 {
+		return nil
+	}
+	chunk, err := internal.NewChunk(frame)
+	if err != nil || chunk.IsDecoy() {
+		return nil
+	}
+	if seqID, ok := chunk.SeqID(); !ok || s.hasSeqID && seqID == s.seqID {
+		return nil
+	}
+	if checkAppTag(s.appTag, chunk, internal.FountainHeader{}) != nil {
+		return nil
+	}
+
+	next := NewEmpty()
+	next.completionMode = s.completionMode
+	next.key = s.key
+	next.ecLevel = s.ecLevel
+	next.textEncoding = s.textEncoding
+	next.appTag = s.appTag
+	next.decoder = s.decoder
+	next.tee = s.tee
+	next.clock = s.clock
+	next.frameBudget = s.frameBudget
+	next.tearDetection = s.tearDetection
+	s.next = next
+	s.deliverNewSequence()
+	return next.receive(frame)
+}
+
+// deliverNewSequence sends the next sequence on the channel of NewSequence and
+// closes it. It does nothing if nobody asked for it or if it has already been
+// delivered.
+func (s *QRSequence) deliverNewSequence() {
+	if s.newSequence == nil || s.newSequenceDelivered {
+		return
+	}
+	s.newSequence <- s.next
+	close(s.newSequence)
+	s.newSequenceDelivered = true
+}
@@ -0,0 +1,132 @@
+package qrseq
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"time"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// A configuration sequence carries configMagic, the configuration version and
+// the JSON encoded ReceiverConfig, encrypted with AES-256-GCM.
+const (
+	configMagic   = "QRRC"
+	configVersion = 1
+)
+
+// ReceiverConfig is the configuration of a receiver that can itself be sent
+// as a QR sequence, so a fleet of air-gapped receivers can be provisioned over
+// the camera.
+//
+// The configuration pins trust roots, so its sequence is sealed with a key
+// shared by the provisioning device and the receiver, usually the session key
+// of a Pairing. Anyone else who can show a QR code to the receiver cannot
+// produce a configuration it accepts.
+type ReceiverConfig struct {
+	// ExpectedDigests are the payload digests the receiver accepts, see
+	// QRSequence.ExpectDigest.
+	ExpectedDigests [][sha256.Size]byte `json:"expected_digests,omitempty"`
+	// FrameBudget is the per-frame decode time budget, see
+	// QRSequence.SetFrameBudget.
+	FrameBudget time.Duration `json:"frame_budget,omitempty"`
+	// TearDetection drops frames that blend two QR codes, see
+	// QRSequence.SetTearDetection.
+	TearDetection bool `json:"tear_detection,omitempty"`
+	// TrustedKeys are the sender keys to pin in the TrustStore.
+	TrustedKeys []TrustedKey `json:"trusted_keys,omitempty"`
+	// Render are the options the receiver renders its own sequences with,
+	// e.g. when relaying them.
+	Render *RenderOptions `json:"render,omitempty"`
+}
+
+// Sequence creates a sender QRSequence carrying the configuration, encrypted
+// and authenticated with the given key.
+//
+// Parameters:
+// - chunkSize: a ChunkSize enum value specifying the size of each chunk.
+// - key: the 32 byte key shared with the receivers, e.g. a session key.
+//
+// Returns:
+//   - *QRSequence: the sender for the configuration.
+//   - error: an error if the key is invalid or the configuration cannot be
+//     encoded.
+func (c ReceiverConfig) Sequence(chunkSize ChunkSize, key []byte) (*QRSequence, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := append([]byte(configMagic), configVersion)
+	return New(append(payload, data...), WithChunkSize(chunkSize), WithEncryption(key))
+}
+
+// ReadReceiverConfig reads the configuration from a completed QRSequence
+// created by ReceiverConfig.Sequence. The receiving QRSequence must have been
+// given the key of the sender with WithEncryption or SetKey, which decrypts
+// and authenticates the configuration on completion.
+//
+// Parameters:
+// - seq: the completed receiving QRSequence.
+//
+// Returns:
+//   - *ReceiverConfig: the received configuration.
+//   - error: ErrConfigNotSealed if the sequence was not encrypted, or an error
+//     if the sequence is not complete or does not carry a valid
+//     configuration.
+func ReadReceiverConfig(seq *QRSequence) (*ReceiverConfig, error) {
+	if !seq.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+	if seq.encoding()&internal.EncodingAESGCM == 0 {
+		return nil, ErrConfigNotSealed
+	}
+
+	payload := seq.Data()
+	if !bytes.HasPrefix(payload, []byte(configMagic)) {
+		return nil, ErrNotConfig
+	}
+	if len(payload) < len(configMagic)+1 || payload[len(configMagic)] != configVersion {
+		return nil, ErrConfigVersion
+	}
+
+	c := new(ReceiverConfig)
+	if err := json.Unmarshal(payload[len(configMagic)+1:], c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Apply configures a receiving QRSequence with the expected digests, the
+// frame budget and the tear detection of the configuration.
+//
+// Parameters:
+// - seq: the receiving QRSequence to configure.
+func (c ReceiverConfig) Apply(seq *QRSequence) {
+	if len(c.ExpectedDigests) > 0 {
+		seq.ExpectDigest(c.ExpectedDigests...)
+	}
+	if c.FrameBudget > 0 {
+		seq.SetFrameBudget(c.FrameBudget)
+	}
+	if c.TearDetection {
+		seq.SetTearDetection(true)
+	}
+}
+
+// ApplyTrust pins the trusted keys of the configuration in a TrustStore.
+//
+// Parameters:
+// - t: the TrustStore to add the keys to.
+//
+// Returns:
+// - error: an error if a key is invalid or cannot be saved.
+func (c ReceiverConfig) ApplyTrust(t *TrustStore) error {
+	for _, key := range c.TrustedKeys {
+		if err := t.Add(key.Name, key.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package qrseq
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+func TestReadReceiverConfigRejectsTampering(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, keySize)
+	config := ReceiverConfig{FrameBudget: time.Second, TearDetection: true}
+	sender, err := config.Sequence(ChunkSize128, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := New(append([]byte(configMagic), configVersion, '{', '}'), WithChunkSize(ChunkSize128))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tampered chunks are valid and carry the digest of the tampered
+	// payload, only the authentication of the encryption catches them
+	sealed := internal.GetData(sender.chunks)
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)/2] ^= 1
+	seqID, _ := sender.chunks[0].SeqID()
+
+	for _, tc := range []struct {
+		name   string
+		chunks []*internal.QRChunk
+		key    []byte
+		want   error
+	}{
+		{name: "sealed", chunks: sender.chunks, key: key},
+		{name: "tampered", chunks: internal.CreateChunks(tampered, uint16(ChunkSize128), seqID, sender.encoding(), 0), key: key, want: ErrDecryptionFailed},
+		{name: "other key", chunks: sender.chunks, key: bytes.Repeat([]byte{2}, keySize), want: ErrDecryptionFailed},
+		{name: "not sealed", chunks: plain.chunks, key: key, want: ErrConfigNotSealed},
+	} {
+		seq := NewEmpty(WithEncryption(tc.key))
+		for _, chunk := range tc.chunks {
+			seq.AddChunkFromBytes(chunk.Bytes())
+		}
+
+		got, err := ReadReceiverConfig(seq)
+		if seq.Err() != nil {
+			err = seq.Err()
+		}
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+		if tc.want == nil && (got == nil || got.FrameBudget != config.FrameBudget || !got.TearDetection) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, config)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// CorpusResult is the outcome of replaying one recorded receive session.
+//
+// The latency is measured on the recorded timeline, so results are
+// deterministic and comparable between runs.
+type CorpusResult struct {
+	Name     string        `json:"name"`
+	Frames   int           `json:"frames"`   // frames in the recording
+	Decoded  int           `json:"decoded"`  // frames that decoded without error
+	Complete bool          `json:"complete"` // whether the sequence completed
+	Latency  time.Duration `json:"latency"`  // first frame to completing frame
+}
+
+// CorpusReport holds the results of replaying a corpus of recorded sessions.
+type CorpusReport struct {
+	Results []CorpusResult `json:"results"`
+}
+
+// CorpusChange describes a recorded session whose outcome differs between a
+// baseline report and the current one. Before is nil for sessions that are
+// new in the current report.
+type CorpusChange struct {
+	Name   string
+	Before *CorpusResult
+	After  CorpusResult
+}
+
+// ReplayCorpus replays every recording matching pattern in fsys through a new
+// receiving QRSequence and reports how well each one decodes.
+//
+// Parameters:
+// - fsys: the file system containing the recordings.
+// - pattern: the fs.Glob pattern selecting the recordings, e.g. "*.qrsr".
+//
+// Returns:
+// - *CorpusReport: the results of all replayed recordings, sorted by name.
+// - error: an error if a recording cannot be opened or read.
+func ReplayCorpus(fsys fs.FS, pattern string) (*CorpusReport, error) {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CorpusReport{Results: make([]CorpusResult, 0, len(names))}
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		result, err := replayRecording(name, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+func replayRecording(name string, r io.Reader) (CorpusResult, error) {
+	result := CorpusResult{Name: name}
+
+	sr, err := NewSessionReader(r)
+	if err != nil {
+		return result, err
+	}
+
+	seq := NewEmpty()
+	var first time.Duration
+	for {
+		frame, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if result.Frames == 0 {
+			first = frame.Offset
+		}
+		result.Frames++
+
+		if seq.IsComplete() {
+			continue
+		}
+		if seq.DecodeImage(frame.Image) == nil {
+			result.Decoded++
+		}
+		if seq.IsComplete() {
+			result.Complete = true
+			result.Latency = frame.Offset - first
+		}
+	}
+}
+
+// CompletionRate returns the fraction of recordings that completed, between 0
+// and 1.
+func (r CorpusReport) CompletionRate() float64 {
+	if len(r.Results) == 0 {
+		return 0
+	}
+	complete := 0
+	for _, result := range r.Results {
+		if result.Complete {
+			complete++
+		}
+	}
+	return float64(complete) / float64(len(r.Results))
+}
+
+// MeanLatency returns the mean latency of all recordings that completed.
+func (r CorpusReport) MeanLatency() time.Duration {
+	var sum time.Duration
+	complete := 0
+	for _, result := range r.Results {
+		if result.Complete {
+			sum += result.Latency
+			complete++
+		}
+	}
+	if complete == 0 {
+		return 0
+	}
+	return sum / time.Duration(complete)
+}
+
+// Compare returns the recordings whose completion or latency differs from the
+// baseline report. Recordings that only exist in the baseline are ignored.
+//
+// Parameters:
+// - baseline: the report of a previous run to compare against.
+//
+// Returns:
+// - []CorpusChange: the recordings with a different outcome.
+func (r CorpusReport) Compare(baseline CorpusReport) []CorpusChange {
+	before := make(map[string]CorpusResult, len(baseline.Results))
+	for _, result := range baseline.Results {
+		before[result.Name] = result
+	}
+
+	var changes []CorpusChange
+	for _, after := range r.Results {
+		b, ok := before[after.Name]
+		if !ok {
+			changes = append(changes, CorpusChange{Name: after.Name, After: after})
+			continue
+		}
+		if b.Complete != after.Complete || b.Latency != after.Latency {
+			changes = append(changes, CorpusChange{Name: after.Name, Before: &b, After: after})
+		}
+	}
+	return changes
+}
+
+// WriteJSON writes the report as JSON, so it can be stored as the baseline of
+// later runs.
+func (r CorpusReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadCorpusReport reads a report written by CorpusReport.WriteJSON.
+func ReadCorpusReport(r io.Reader) (*CorpusReport, error) {
+	report := new(CorpusReport)
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
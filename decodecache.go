@@ -0,0 +1,179 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"image"
+	"sync"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// DecodeCache remembers the texts of recently decoded frames by a hash of
+// their pixels, so a frame that is passed through several decoders, e.g. a
+// fast one and a slower, more tolerant one as a fallback, or to several
+// receivers, is only decoded once.
+//
+// Only successful decodes are cached, since a frame one decoder fails on may
+// still be read by another. A DecodeCache is safe for concurrent use and can
+// be shared by any number of decoders.
+type DecodeCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List // most recently used first
+	hits    int
+}
+
+type decodeCacheEntry struct {
+	key      [sha256.Size]byte
+	text     string
+	rotation int
+}
+
+// NewDecodeCache creates a DecodeCache holding the texts of up to size
+// frames, dropping the least recently used ones first.
+//
+// Parameters:
+// - size: the number of frames remembered, at least 1.
+//
+// Returns:
+// - *DecodeCache: the new DecodeCache.
+func NewDecodeCache(size int) *DecodeCache {
+	return &DecodeCache{
+		size:    max(size, 1),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Decoder returns a Decoder that answers frames found in the cache right away
+// and decodes the others with d, adding them to the cache if they decode.
+// Pass it to a receiver with WithDecoder.
+//
+// Parameters:
+//   - d: the Decoder to decode frames missing from the cache with, or nil for
+//     the built-in gozxing reader.
+//
+// Returns:
+//   - Decoder: the caching Decoder.
+func (c *DecodeCache) Decoder(d Decoder) Decoder {
+	return &cachedDecoder{cache: c, d: d}
+}
+
+// Hits returns the number of frames answered from the cache.
+//
+// Returns:
+// - int: the number of cache hits.
+func (c *DecodeCache) Hits() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits
+}
+
+// lookup returns the cached text of a frame and marks it as recently used.
+func (c *DecodeCache) lookup(key [sha256.Size]byte) (decodeCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return decodeCacheEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	c.hits++
+	return elem.Value.(decodeCacheEntry), true
+}
+
+// add caches the text of a frame, dropping the least recently used frame if
+// the cache is full.
+func (c *DecodeCache) add(entry decodeCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[entry.key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Remove(c.order.Back()).(decodeCacheEntry)
+		delete(c.entries, oldest.key)
+	}
+}
+
+type cachedDecoder struct {
+	cache *DecodeCache
+	d     Decoder
+}
+
+// Decode returns the cached text of img or decodes it.
+func (d *cachedDecoder) Decode(img image.Image) (string, int, error) {
+	key := frameHash(img)
+	if entry, ok := d.cache.lookup(key); ok {
+		return entry.text, entry.rotation, nil
+	}
+
+	read := internal.ReadImageRotation
+	if d.d != nil {
+		read = d.d.Decode
+	}
+	text, rotation, err := read(img)
+	if err != nil {
+		return "", 0, err
+	}
+	d.cache.add(decodeCacheEntry{key: key, text: text, rotation: rotation})
+	return text, rotation, nil
+}
+
+// frameHash hashes the bounds and pixels of a frame. The pixel buffers of the
+// common image types are hashed directly, with the palette of paletted images
+// and of YCbCr images only the luma plane, which is all QR code readers look
+// at.
+func frameHash(img image.Image) [sha256.Size]byte {
+	h := sha256.New()
+	b := img.Bounds()
+	for _, v := range []int{b.Min.X, b.Min.Y, b.Max.X, b.Max.Y} {
+		binary.Write(h, binary.LittleEndian, int64(v))
+	}
+
+	switch img := img.(type) {
+	case *image.Gray:
+		hashRows(h, img.Pix, img.PixOffset(b.Min.X, b.Min.Y), img.Stride, b.Dx(), b.Dy())
+	case *image.RGBA:
+		hashRows(h, img.Pix, img.PixOffset(b.Min.X, b.Min.Y), img.Stride, 4*b.Dx(), b.Dy())
+	case *image.NRGBA:
+		hashRows(h, img.Pix, img.PixOffset(b.Min.X, b.Min.Y), img.Stride, 4*b.Dx(), b.Dy())
+	case *image.Paletted:
+		for _, c := range img.Palette {
+			r, g, bl, a := c.RGBA()
+			binary.Write(h, binary.LittleEndian, [4]uint32{r, g, bl, a})
+		}
+		hashRows(h, img.Pix, img.PixOffset(b.Min.X, b.Min.Y), img.Stride, b.Dx(), b.Dy())
+	case *image.YCbCr:
+		hashRows(h, img.Y, img.YOffset(b.Min.X, b.Min.Y), img.YStride, b.Dx(), b.Dy())
+	default:
+		buf := make([]byte, 0, 4*b.Dx())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			buf = buf[:0]
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, a := img.At(x, y).RGBA()
+				buf = append(buf, byte(r>>8), byte(g>>8), byte(bl>>8), byte(a>>8))
+			}
+			h.Write(buf)
+		}
+	}
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// hashRows hashes rows of n bytes of a pixel buffer.
+func hashRows(h hash.Hash, pix []byte, offset, stride, n, rows int) {
+	for y := 0; y < rows; y++ {
+		h.Write(pix[offset+y*stride : offset+y*stride+n])
+	}
+}
@@ -0,0 +1,41 @@
+package qrseq
+
+import "image"
+
+// Decoder finds and reads the QR code in a frame. It replaces the built-in
+// gozxing reader of a receiver created with WithDecoder, e.g. by the OpenCV
+// backend of the gocv build tag.
+type Decoder interface {
+	// Decode reads the text of the QR code in img.
+	//
+	// Parameters:
+	// - img: the frame to decode.
+	//
+	// Returns:
+	//   - string: the text of the QR code.
+	//   - int: the clockwise rotation of the code in degrees, rounded to 0, 90,
+	//     180 or 270, or 0 if it is not known.
+	//   - error: an error if no QR code could be read, preferably a
+	//     *DecodeError that classifies the failure for Stats and Guidance.
+	//     Other errors count as FailureInvalid.
+	Decode(img image.Image) (string, int, error)
+}
+
+// WithDecoder sets the Decoder a receiver reads frames with instead of the
+// built-in gozxing reader. It is kept by the sequences delivered by
+// NewSequence and ignored by senders.
+//
+// Parameters:
+// - d: the Decoder.
+//
+// Returns:
+// - Option: the option.
+func WithDecoder(d Decoder) Option {
+	return func(o *options) error {
+		if d == nil {
+			return ErrNilDecoder
+		}
+		o.decoder = d
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+//go:build gocv && !core
+
+package qrseq
+
+import (
+	"errors"
+	"image"
+
+	"github.com/airsigner/qrseq/internal"
+	"gocv.io/x/gocv"
+)
+
+// OpenCVDecoder is a Decoder based on the QRCodeDetector of OpenCV, which
+// finds small and low contrast codes more reliably than the built-in gozxing
+// reader.
+//
+// It is only built with the gocv build tag, which needs OpenCV installed and
+// gocv.io/x/gocv added to the module of the application, see the gocv
+// documentation. Pass it to a receiver with WithDecoder.
+type OpenCVDecoder struct {
+	detector gocv.QRCodeDetector
+}
+
+// NewOpenCVDecoder creates an OpenCVDecoder. It must be closed to release the
+// detector of OpenCV.
+//
+// Returns:
+// - *OpenCVDecoder: the new OpenCVDecoder.
+func NewOpenCVDecoder() *OpenCVDecoder {
+	return &OpenCVDecoder{detector: gocv.NewQRCodeDetector()}
+}
+
+// Decode detects the QR code in img and reads its text.
+//
+// Parameters:
+// - img: the frame to decode.
+//
+// Returns:
+//   - string: the text of the QR code.
+//   - int: the clockwise rotation of the code in degrees, rounded to 0, 90,
+//     180 or 270.
+//   - error: a *DecodeError if no QR code could be read, or the error of
+//     converting the frame.
+func (d *OpenCVDecoder) Decode(img image.Image) (string, int, error) {
+	var (
+		mat gocv.Mat
+		err error
+	)
+	if gray, ok := img.(*image.Gray); ok {
+		mat, err = gocv.ImageGrayToMatGray(gray)
+	} else {
+		mat, err = gocv.ImageToMatRGB(img)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	defer mat.Close()
+
+	points := gocv.NewMat()
+	defer points.Close()
+	if !d.detector.Detect(mat, &points) || points.Total() < 4 {
+		return "", 0, &DecodeError{Failure: FailureNotFound, Err: ErrNoQRFound}
+	}
+
+	straight := gocv.NewMat()
+	defer straight.Close()
+	text := d.detector.Decode(mat, points, &straight)
+	if text == "" {
+		return "", 0, &DecodeError{Failure: FailureChecksum, Err: ErrUnreadableQR}
+	}
+
+	// the corners start with the top left one, followed by the top right one
+	topLeft, topRight := points.GetVecfAt(0, 0), points.GetVecfAt(0, 1)
+	rotation := internal.Rotation(float64(topRight[0]-topLeft[0]), float64(topRight[1]-topLeft[1]))
+	return text, rotation, nil
+}
+
+// Close releases the detector of OpenCV.
+//
+// Returns:
+// - error: the error of releasing the detector.
+func (d *OpenCVDecoder) Close() error {
+	return d.detector.Close()
+}
@@ -0,0 +1,84 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"crypto/rand"
+	"image"
+	"io"
+	"math"
+	"math/big"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// QRCodesWithDecoys generates the QR codes of the QRSequence like
+// QRCodesWithOptions and interleaves them with decoy frames.
+//
+// Decoy frames carry random data of the same size as a full chunk and are
+// discarded by receivers. They obscure the size and timing of a transfer from
+// onlookers that film or count the frames. The decoys are inserted at random
+// positions, the real frames keep their order. Decoy data and positions are
+// drawn from the source of randomness set with WithRand or SetRand.
+//
+// Parameters:
+//   - opt: the RenderOptions to render the QR codes with.
+//   - rate: the number of decoy frames per real frame, e.g. 0.5 adds one decoy
+//     for every two real frames.
+//
+// Returns:
+//   - []image.Image: the real and decoy QR codes in display order.
+//   - error: an error if the QRSequence is not complete, the rate is negative
+//     or there is an error while generating the QR codes.
+func (s QRSequence) QRCodesWithDecoys(opt RenderOptions, rate float64) ([]image.Image, error) {
+	if rate < 0 || math.IsNaN(rate) {
+		return nil, ErrInvalidDecoyRate
+	}
+
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	plain := opt
+	plain.Watermark = false
+	plain.SyncMarker = false
+	images, err := s.QRCodesWithOptions(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	decoys := int(math.Round(rate * float64(len(images))))
+	for i := 0; i < decoys; i++ {
+		chunk, err := internal.NewDecoyChunk(s.chunks[0], s.random())
+		if err != nil {
+			return nil, err
+		}
+		decoy, err := chunk.Render(opt.internal())
+		if err != nil {
+			return nil, err
+		}
+		decoy = opt.overlayLogo(decoy)
+
+		pos, err := randIntn(s.random(), len(images)+1)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, nil)
+		copy(images[pos+1:], images[pos:])
+		images[pos] = decoy
+	}
+
+	for i, img := range images {
+		images[i] = opt.stamp(img, i)
+	}
+	return images, nil
+}
+
+// randIntn returns a uniform random number in [0, n) read from r.
+func randIntn(r io.Reader, n int) (int, error) {
+	v, err := rand.Int(r, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
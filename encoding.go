@@ -0,0 +1,148 @@
+package qrseq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"io"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// maxDecodedSize bounds the size of a decompressed payload, so a small
+// malicious sequence cannot exhaust the memory of the receiver.
+const maxDecodedSize = 1 << 26
+
+// keySize is the size of an AES-256 key. An encrypted payload is sent as the
+// GCM nonce followed by the sealed payload.
+const keySize = 32
+
+// SetKey sets the AES-256-GCM key a receiving QRSequence decrypts an encrypted
+// payload with. It must be called before the last chunk arrives, otherwise an
+// encrypted payload ends the sequence with a terminal error.
+//
+// Parameters:
+// - key: the 32 byte key the sender encrypted with.
+//
+// Returns:
+// - error: an error if the key has the wrong size.
+func (s *QRSequence) SetKey(key []byte) error {
+	if len(key) != keySize {
+		return ErrInvalidKeySize
+	}
+	s.key = key
+	return nil
+}
+
+// encodePayload applies the encodings selected by the options to a payload and
+// returns the bytes to send and the encodings applied.
+func (s QRSequence) encodePayload(data []byte, o options) ([]byte, uint8, error) {
+	var encoding uint8
+	if o.signKey != nil {
+		data = append(bytes.Clone(data), ed25519.Sign(o.signKey, data)...)
+		encoding |= internal.EncodingSigned
+	}
+	if o.compression == CompressionGzip {
+		buf := new(bytes.Buffer)
+		zw := gzip.NewWriter(buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, 0, err
+		}
+		data = buf.Bytes()
+		encoding |= internal.EncodingGzip
+	}
+	if o.key != nil {
+		aead, err := newAEAD(o.key)
+		if err != nil {
+			return nil, 0, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(s.random(), nonce); err != nil {
+			return nil, 0, err
+		}
+		data = aead.Seal(nonce, nonce, data, nil)
+		encoding |= internal.EncodingAESGCM
+	}
+	return data, encoding, nil
+}
+
+// decodePayload undoes the encodings announced in chunk 0 of a sequence whose
+// chunks have all arrived.
+func (s *QRSequence) decodePayload() error {
+	encoding := s.encoding()
+	if encoding == 0 {
+		return nil
+	}
+	if encoding&^(internal.EncodingGzip|internal.EncodingAESGCM|internal.EncodingPadding|internal.EncodingSigned) != 0 {
+		return ErrUnsupportedEncoding
+	}
+
+	data := internal.GetData(s.chunks)
+	if encoding&internal.EncodingPadding != 0 {
+		var err error
+		if data, err = unpad(data); err != nil {
+			return err
+		}
+	}
+	if encoding&internal.EncodingAESGCM != 0 {
+		if s.key == nil {
+			return ErrNoKey
+		}
+		aead, err := newAEAD(s.key)
+		if err != nil {
+			return err
+		}
+		if len(data) < aead.NonceSize() {
+			return ErrDecryptionFailed
+		}
+		data, err = aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+		if err != nil {
+			return ErrDecryptionFailed
+		}
+	}
+	if encoding&internal.EncodingGzip != 0 {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		data, err = io.ReadAll(io.LimitReader(zr, maxDecodedSize+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > maxDecodedSize {
+			return ErrDecompressedTooLarge
+		}
+	}
+	if encoding&internal.EncodingSigned != 0 {
+		if len(data) < ed25519.SignatureSize {
+			return ErrInvalidSignature
+		}
+		n := len(data) - ed25519.SignatureSize
+		data, s.signature = data[:n:n], data[n:]
+	}
+	s.decoded = data
+	return nil
+}
+
+// newAEAD creates the AES-256-GCM cipher for a key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encoding returns the encodings applied to the payload, as far as chunk 0 has
+// been received.
+func (s QRSequence) encoding() uint8 {
+	if len(s.chunks) == 0 || s.chunks[0] == nil {
+		return 0
+	}
+	return s.chunks[0].Encoding()
+}
@@ -0,0 +1,61 @@
+package qrseq
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"psbt":"cHNidP8BAHECAAAAAQ=="}`), 20)
+	key := bytes.Repeat([]byte{1}, keySize)
+	wrongKey := bytes.Repeat([]byte{2}, keySize)
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "encrypted", opts: []Option{WithEncryption(key)}},
+		{name: "compressed and encrypted", opts: []Option{WithCompression(CompressionGzip), WithEncryption(key)}},
+		{name: "padded", opts: []Option{WithEncryption(key), WithPadding(8)}},
+		{name: "all", opts: []Option{WithCompression(CompressionGzip), WithEncryption(key), WithPadding(4)}},
+	} {
+		sender, err := New(data, append(tc.opts, WithChunkSize(ChunkSize128))...)
+		if err != nil {
+			t.Fatalf("%s: New: %v", tc.name, err)
+		}
+		payloads, err := sender.Payloads()
+		if err != nil {
+			t.Fatalf("%s: Payloads: %v", tc.name, err)
+		}
+		if bytes.Contains(internal.GetData(sender.chunks), data[:16]) {
+			t.Errorf("%s: payload sent in the clear", tc.name)
+		}
+
+		for _, rc := range []struct {
+			name string
+			opts []Option
+			want error
+		}{
+			{name: "right key", opts: []Option{WithEncryption(key)}},
+			{name: "wrong key", opts: []Option{WithEncryption(wrongKey)}, want: ErrDecryptionFailed},
+			{name: "no key", want: ErrNoKey},
+		} {
+			receiver := NewEmpty(rc.opts...)
+			for _, payload := range payloads {
+				receiver.AddPayload(payload)
+			}
+			if !errors.Is(receiver.Err(), rc.want) {
+				t.Errorf("%s, %s: got %v, want %v", tc.name, rc.name, receiver.Err(), rc.want)
+			}
+			if rc.want == nil && !bytes.Equal(receiver.Data(), data) {
+				t.Errorf("%s, %s: received payload differs", tc.name, rc.name)
+			}
+			if rc.want != nil && receiver.Data() != nil {
+				t.Errorf("%s, %s: payload returned", tc.name, rc.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package qrseq
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// Errors returned by the package, so callers can branch on failure modes
+// with errors.Is. Errors of decoding a frame are returned as a *DecodeError,
+// which matches the error of its DecodeFailure, e.g. ErrNoQRFound for
+// FailureNotFound.
+var (
+	// ErrInvalidChunkSize means a chunk size is not one of the ChunkSize
+	// values.
+	ErrInvalidChunkSize = internal.ErrInvalidChunkSize
+	// ErrInvalidChunk means a QR code was read, but does not hold a valid
+	// chunk or fountain frame.
+	ErrInvalidChunk = internal.ErrInvalidChunk
+	// ErrChunkMismatch means a valid chunk belongs to another sequence than
+	// the chunks received before, e.g. because its sequence ID, chunk size or
+	// number of chunks differs.
+	ErrChunkMismatch = errors.New("chunk belongs to another sequence")
+	// ErrChunkCorrupt means the CRC of a chunk does not match, so the QR code
+	// was misread or the chunk corrupted. The DecodeError also wraps a
+	// *CRCError with the details.
+	ErrChunkCorrupt = errors.New("chunk corrupted")
+	// ErrNoQRFound means there is no QR code in a frame.
+	ErrNoQRFound = errors.New("no QR code found")
+	// ErrUnreadableQR means a QR code was found in a frame, but could not be
+	// read.
+	ErrUnreadableQR = errors.New("QR code cannot be read")
+	// ErrFrameBudget means decoding a frame exceeded the frame budget.
+	ErrFrameBudget = errors.New("frame budget exceeded")
+	// ErrFrameBusy means a frame was aborted because the decode of a
+	// previous frame that exceeded the frame budget is still running. The
+	// DecodeError also matches ErrFrameBudget.
+	ErrFrameBusy = errors.New("previous frame still decoding")
+	// ErrInvalidBase45 means the text of a QR code is not valid Base45.
+	ErrInvalidBase45 = internal.ErrInvalidBase45
+	// ErrInvalidUR means a part read by a URDecoder is not a valid part of a
+	// multi-part UR, or belongs to another message.
+	ErrInvalidUR = internal.ErrInvalidUR
+	// ErrFrameTorn means a frame was dropped because it blends two
+	// displayed QR codes, see QRSequence.SetTearDetection.
+	ErrFrameTorn = errors.New("frame blends two QR codes")
+
+	// ErrSequenceIncomplete means the payload was requested from a
+	// QRSequence that has not received all chunks yet.
+	ErrSequenceIncomplete = errors.New("sequence not complete")
+	// ErrPayloadDrained means the payload was requested after it has been
+	// written out with WriteTo.
+	ErrPayloadDrained = errors.New("payload already drained")
+	// ErrEncodedStream means an encoded payload, e.g. a compressed or
+	// encrypted one, was streamed with WriteTo or DataReader, but it can
+	// only be decoded as a whole.
+	ErrEncodedStream = errors.New("encoded payload cannot be streamed, use Data")
+	// ErrChunkPending is returned by the reader of DataReader when the next
+	// chunk of the payload has not been received yet. Reading can be retried
+	// once more chunks have arrived.
+	ErrChunkPending = errors.New("next chunk not received yet")
+	// ErrAlreadyFinalized means Finalize was called a second time.
+	ErrAlreadyFinalized = errors.New("sequence already finalized")
+	// ErrPayloadTooLarge means a payload needs more chunks or fragments than
+	// the framing can describe.
+	ErrPayloadTooLarge = internal.ErrPayloadTooLarge
+	// ErrTooManySessions means a frame would start a session beyond the
+	// maximum of a SessionManager, and all of its sessions are complete.
+	ErrTooManySessions = errors.New("too many sessions")
+	// ErrNotReceiver means a method of a receiving QRSequence was called on
+	// one not created by NewEmpty.
+	ErrNotReceiver = errors.New("not a receiving sequence")
+	// ErrSourceEnded means a camera, stream or video ended before the
+	// sequence was complete.
+	ErrSourceEnded = errors.New("source ended before the sequence was complete")
+
+	// ErrDigestMismatch means the payload does not match the digest embedded
+	// by the sender.
+	ErrDigestMismatch = errors.New("payload digest mismatch")
+	// ErrLengthMismatch means the payload does not match the length embedded
+	// by the sender.
+	ErrLengthMismatch = errors.New("payload length mismatch")
+	// ErrUnexpectedDigest means the payload matches none of the digests set
+	// with ExpectDigest.
+	ErrUnexpectedDigest = errors.New("payload digest not expected")
+	// ErrUnexpectedLength means the payload does not have the length set with
+	// ExpectLength.
+	ErrUnexpectedLength = errors.New("payload length not expected")
+	// ErrNoDigest means Verify was called on a sequence whose sender
+	// embedded no digest.
+	ErrNoDigest = errors.New("no payload digest received")
+	// ErrUnsupportedEncoding means the payload uses an encoding this
+	// version does not know.
+	ErrUnsupportedEncoding = errors.New("unsupported payload encoding")
+	// ErrDecompressedTooLarge means a compressed payload expands beyond the
+	// length the sender announced.
+	ErrDecompressedTooLarge = errors.New("decompressed payload too large")
+	// ErrInvalidPadding means the padding of a payload is malformed.
+	ErrInvalidPadding = errors.New("invalid padding")
+
+	// ErrInvalidKeySize means an encryption key is not 32 bytes long.
+	ErrInvalidKeySize = errors.New("invalid key size")
+	// ErrNoKey means the payload is encrypted, but the receiving QRSequence
+	// has no key.
+	ErrNoKey = errors.New("payload is encrypted but no key is set")
+	// ErrDecryptionFailed means the payload cannot be decrypted, because the
+	// key is wrong or the payload was tampered with.
+	ErrDecryptionFailed = errors.New("payload decryption failed")
+	// ErrConfigNotSealed means a receiver configuration was not encrypted,
+	// so anyone could have sent it.
+	ErrConfigNotSealed = errors.New("receiver configuration not sealed")
+
+	// ErrECLevelTooHigh means a chunk of the chunk size does not fit in a QR
+	// code at the error correction level, see ChunkSize.MaxECLevel.
+	ErrECLevelTooHigh = errors.New("error correction level too high for the chunk size")
+	// ErrEdgeECLevelTooHigh means a chunk of the chunk size does not fit in a
+	// QR code at the EdgeECLevel of RenderOptions.
+	ErrEdgeECLevelTooHigh = errors.New("edge error correction level too high for the chunk size")
+	// ErrLogoChunkSize means a chunk of the chunk size does not fit in a QR
+	// code at ECLevelHigh, which QR codes with a logo are rendered at.
+	ErrLogoChunkSize = errors.New("chunk size too large for a logo")
+	// ErrInvalidECLevel means an error correction level is not one of the
+	// ECLevel values.
+	ErrInvalidECLevel = errors.New("unknown error correction level")
+	// ErrInvalidCompression means a compression is not one of the
+	// Compression values.
+	ErrInvalidCompression = errors.New("unknown compression")
+	// ErrInvalidTextEncoding means a text encoding is not one of the
+	// TextEncoding values.
+	ErrInvalidTextEncoding = errors.New("unknown text encoding")
+	// ErrInvalidMinChunks means the minimum number of chunks passed to
+	// WithPadding is not positive or exceeds the maximum number of chunks.
+	ErrInvalidMinChunks = errors.New("invalid minimum number of chunks")
+	// ErrNilDecoder means WithDecoder was passed a nil Decoder.
+	ErrNilDecoder = errors.New("nil decoder")
+	// ErrNilChunkStore means WithTee was passed a nil ChunkStore.
+	ErrNilChunkStore = errors.New("nil chunk store")
+	// ErrInvalidDecoyRate means a decoy rate is negative.
+	ErrInvalidDecoyRate = errors.New("invalid decoy rate")
+	// ErrInvalidBlockSize means the block size of RenderOptions is not
+	// positive.
+	ErrInvalidBlockSize = internal.ErrInvalidBlockSize
+	// ErrInvalidFragmentLength means the maximum fragment length passed to
+	// NewUREncoder is too small.
+	ErrInvalidFragmentLength = internal.ErrInvalidFragmentLength
+	// ErrInvalidFrameRate means a frame rate is not positive.
+	ErrInvalidFrameRate = errors.New("invalid frame rate")
+	// ErrInvalidLoops means the number of loops of an animation is negative.
+	ErrInvalidLoops = errors.New("invalid number of loops")
+	// ErrInvalidKeep means the number of frames a FrameSink keeps is
+	// negative.
+	ErrInvalidKeep = errors.New("invalid number of kept frames")
+	// ErrInvalidFramesPerPage means the number of frames per page of a
+	// Backup is not positive.
+	ErrInvalidFramesPerPage = errors.New("invalid number of frames per page")
+	// ErrUnknownAnimationFormat means an animation format is not one of the
+	// AnimationFormat values.
+	ErrUnknownAnimationFormat = errors.New("unknown animation format")
+	// ErrUnknownExportFormat means an export format of Stats is not known.
+	ErrUnknownExportFormat = errors.New("unknown export format")
+	// ErrPageOutOfRange means a page number is not a page of the Backup.
+	ErrPageOutOfRange = errors.New("page out of range")
+	// ErrChunkOutOfRange means a chunk number is not a chunk of the
+	// sequence.
+	ErrChunkOutOfRange = errors.New("chunk number out of range")
+	// ErrNotDirectory means a path that must be a directory is not one.
+	ErrNotDirectory = errors.New("not a directory")
+	// ErrNoViewer means the ViewerConfig of a Viewer has no path.
+	ErrNoViewer = errors.New("no viewer path")
+
+	// ErrNotPairingFrame means a sequence read by a Pairing does not carry a
+	// pairing frame.
+	ErrNotPairingFrame = errors.New("not a pairing frame")
+	// ErrPairingVersion means a pairing frame has a version this version
+	// does not support.
+	ErrPairingVersion = errors.New("unsupported pairing version")
+	// ErrUnknownPairingFrame means a pairing frame is of an unknown kind.
+	ErrUnknownPairingFrame = errors.New("unknown pairing frame")
+	// ErrInvalidCommitment means a pairing commitment is malformed.
+	ErrInvalidCommitment = errors.New("invalid pairing commitment")
+	// ErrOwnKey means a Pairing read its own key back, e.g. from a mirror.
+	ErrOwnKey = errors.New("peer key is own key")
+	// ErrCommitmentMismatch means the key revealed by a peer does not match
+	// the commitment it showed before, so it may have been replaced.
+	ErrCommitmentMismatch = errors.New("peer key does not match its commitment")
+	// ErrPeerChanged means a Pairing read another key than the peer it has
+	// set, which a machine in the middle would send after the own key was
+	// revealed.
+	ErrPeerChanged = errors.New("peer key changed")
+	// ErrNoPeer means the key of the peer of a Pairing is not known yet.
+	ErrNoPeer = errors.New("no peer")
+	// ErrNotConfirmed means WithPairing was passed a Pairing whose short
+	// authentication string has not been confirmed.
+	ErrNotConfirmed = errors.New("pairing not confirmed")
+	// ErrPairingFrameTooLarge means a pairing frame does not fit into a
+	// single QR code.
+	ErrPairingFrameTooLarge = errors.New("pairing frame does not fit into one QR code")
+	// ErrInvalidPublicKey means a public key is not an Ed25519 public key.
+	ErrInvalidPublicKey = errors.New("invalid public key")
+	// ErrUntrustedKey means a signature was made by none of the keys of a
+	// TrustStore.
+	ErrUntrustedKey = errors.New("signature by untrusted key")
+	// ErrInvalidPrivateKey means a private key is not an Ed25519 private
+	// key.
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	// ErrUnsigned means Finalize requires a signature, but the payload was
+	// not signed.
+	ErrUnsigned = errors.New("payload not signed")
+	// ErrInvalidSignature means a signed payload is too short to hold its
+	// signature.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrNotConfig means a payload is not a receiver configuration.
+	ErrNotConfig = errors.New("not a receiver configuration")
+	// ErrConfigVersion means a receiver configuration has a version this
+	// version does not support.
+	ErrConfigVersion = errors.New("unsupported receiver configuration version")
+	// ErrNotFeed means a stream is not a chunk feed.
+	ErrNotFeed = errors.New("not a chunk feed")
+	// ErrFeedVersion means a chunk feed has a version this version does not
+	// support.
+	ErrFeedVersion = errors.New("unsupported chunk feed version")
+	// ErrFeedTruncated means a chunk feed ends inside a message.
+	ErrFeedTruncated = errors.New("truncated chunk feed")
+	// ErrFeedMessageTooLarge means a message of a chunk feed exceeds the
+	// maximum size.
+	ErrFeedMessageTooLarge = errors.New("feed message too large")
+	// ErrUnknownFeedMessage means a message of a chunk feed is of an unknown
+	// type.
+	ErrUnknownFeedMessage = errors.New("unknown feed message")
+	// ErrInvalidFeedMessage means a message of a chunk feed is malformed.
+	ErrInvalidFeedMessage = errors.New("invalid feed message")
+	// ErrInvalidFeedImage means an image sent to a DecodeWorker is
+	// malformed.
+	ErrInvalidFeedImage = errors.New("invalid feed image")
+	// ErrWorkerExited means a DecodeWorker exited before it answered.
+	ErrWorkerExited = errors.New("decode worker exited")
+	// ErrNotRecording means a stream is not a session recording.
+	ErrNotRecording = errors.New("not a session recording")
+	// ErrRecordingVersion means a session recording has a version this
+	// version does not support.
+	ErrRecordingVersion = errors.New("unsupported session recording version")
+	// ErrRecordingTruncated means a session recording ends inside a frame.
+	ErrRecordingTruncated = errors.New("truncated session recording")
+	// ErrNotFrameRing means a shared memory file is not a frame ring.
+	ErrNotFrameRing = errors.New("not a frame ring")
+	// ErrFrameRingVersion means a frame ring has a version this version
+	// does not support.
+	ErrFrameRingVersion = errors.New("unsupported frame ring version")
+	// ErrInvalidFrameRingSize means the size of a frame ring or its frames
+	// is invalid.
+	ErrInvalidFrameRingSize = errors.New("invalid frame ring size")
+	// ErrFrameSizeMismatch means a frame written to a frame ring does not
+	// have the size of its frames.
+	ErrFrameSizeMismatch = errors.New("frame size does not match frame ring")
+	// ErrEmptySchemaID means a schema identifier is empty.
+	ErrEmptySchemaID = errors.New("empty schema identifier")
+	// ErrNilSchema means a nil schema was registered.
+	ErrNilSchema = errors.New("nil schema")
+	// ErrSchemaRegistered means a schema identifier was registered twice.
+	ErrSchemaRegistered = errors.New("schema already registered")
+	// ErrNoSchema means a schema envelope declares no schema. It is wrapped
+	// in a *SchemaError.
+	ErrNoSchema = errors.New("no schema declared")
+	// ErrNoSchemaPayload means a schema envelope holds no payload. It is
+	// wrapped in a *SchemaError.
+	ErrNoSchemaPayload = errors.New("no payload")
+	// ErrSchemaNotRegistered means a schema envelope declares a schema that
+	// is not registered. It is wrapped in a *SchemaError.
+	ErrSchemaNotRegistered = errors.New("schema not registered")
+	// ErrTrailingData means a JSON payload holds data after its value.
+	ErrTrailingData = errors.New("trailing data after JSON value")
+
+	// ErrInvalidPNG means a PNG image of an animation is malformed.
+	ErrInvalidPNG = errors.New("invalid PNG data")
+	// ErrNoWatermark means a frame carries no watermark strip.
+	ErrNoWatermark = errors.New("no watermark")
+	// ErrWatermarkChecksum means the checksum of a watermark does not match,
+	// so it was misread.
+	ErrWatermarkChecksum = errors.New("watermark checksum mismatch")
+	// ErrNoSyncMarker means a frame is too small to carry a sync marker.
+	ErrNoSyncMarker = errors.New("no sync marker")
+	// ErrUnsupportedFramebuffer means a framebuffer has a pixel format or
+	// layout this version cannot draw.
+	ErrUnsupportedFramebuffer = errors.New("unsupported framebuffer format")
+	// ErrFrameTooLarge means a frame is larger than the framebuffer it is
+	// shown on.
+	ErrFrameTooLarge = errors.New("frame larger than framebuffer")
+	// ErrUnsupportedCameraFormat means a camera does not capture in the
+	// requested PixelFormat, or it is not one of the PixelFormat values.
+	ErrUnsupportedCameraFormat = errors.New("unsupported camera format")
+	// ErrInvalidFrameSize means the frame size of a camera is invalid.
+	ErrInvalidFrameSize = errors.New("invalid camera frame size")
+	// ErrNotCaptureDevice means a device cannot stream frames.
+	ErrNotCaptureDevice = errors.New("not a streaming capture device")
+	// ErrNoCameraBuffers means a camera provided no buffers to stream into.
+	ErrNoCameraBuffers = errors.New("no camera buffers")
+	// ErrInvalidCameraBuffer means a camera returned an unknown buffer.
+	ErrInvalidCameraBuffer = errors.New("invalid camera buffer")
+	// ErrShortCameraFrame means a camera frame holds less data than its
+	// size needs.
+	ErrShortCameraFrame = errors.New("short camera frame")
+	// ErrNotMJPEGStream means a URL does not serve an MJPEG stream, e.g.
+	// because the server answered with an error status.
+	ErrNotMJPEGStream = errors.New("not an MJPEG stream")
+	// ErrMJPEGFrameTooLarge means a frame of an MJPEG stream exceeds the
+	// maximum size.
+	ErrMJPEGFrameTooLarge = errors.New("MJPEG frame too large")
+	// ErrFFmpeg means ffmpeg failed to decode a video. The error wraps it
+	// with the message ffmpeg printed.
+	ErrFFmpeg = errors.New("ffmpeg failed")
+	// ErrVideoClosed means a frame was read from a closed Video.
+	ErrVideoClosed = errors.New("video closed")
+	// ErrInvalidPGM means a frame decoded by ffmpeg is not a valid PGM image.
+	ErrInvalidPGM = errors.New("invalid PGM frame")
+	// ErrInvalidPGMSize means a PGM frame is empty or too large.
+	ErrInvalidPGMSize = errors.New("invalid PGM frame size")
+	// ErrPGMDepth means a PGM frame does not have 8 bit samples.
+	ErrPGMDepth = errors.New("unsupported PGM sample depth")
+)
+
+// ChunkMismatchError is the error returned by AddChunk if a valid chunk
+// conflicts with the sequence established by the chunks received before. It
+// wraps ErrChunkMismatch.
+type ChunkMismatchError struct {
+	Field string // the conflicting field: "layout", "chunk size", "total", "length", "sequence ID" or "app tag"
+	Want  int64  // the value of the sequence, -1 if it has no sequence ID
+	Got   int64  // the value of the chunk, -1 if it has no sequence ID
+}
+
+func (e *ChunkMismatchError) Error() string {
+	return ErrChunkMismatch.Error() + ": " + e.Field + " " + strconv.FormatInt(e.Got, 10) +
+		", want " + strconv.FormatInt(e.Want, 10)
+}
+
+func (e *ChunkMismatchError) Unwrap() error {
+	return ErrChunkMismatch
+}
+
+// EncodeError is the error returned when rendering a QR code if the QR code
+// encoder fails, e.g. because the text does not fit. It wraps the error of
+// the encoder.
+type EncodeError = internal.EncodeError
+
+// failureErrors maps the failures of decoding a frame to their errors.
+var failureErrors = map[DecodeFailure]error{
+	FailureNotFound: ErrNoQRFound,
+	FailureChecksum: ErrUnreadableQR,
+	FailureFormat:   ErrUnreadableQR,
+	FailureInvalid:  ErrInvalidChunk,
+	FailureAborted:  ErrFrameBudget,
+	FailureCorrupt:  ErrChunkCorrupt,
+	FailureTorn:     ErrFrameTorn,
+}
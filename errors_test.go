@@ -0,0 +1,53 @@
+package qrseq
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOptionErrors(t *testing.T) {
+	data := []byte("errors")
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want error
+	}{
+		{name: "level too high", opts: []Option{WithChunkSize(ChunkSize2048), WithECLevel(ECLevelHigh)}, want: ErrECLevelTooHigh},
+		{name: "unknown level", opts: []Option{WithECLevel(ECLevelHigh + 1)}, want: ErrInvalidECLevel},
+		{name: "unknown compression", opts: []Option{WithCompression(CompressionGzip + 1)}, want: ErrInvalidCompression},
+		{name: "unknown text encoding", opts: []Option{WithTextEncoding(TextRaw + 1)}, want: ErrInvalidTextEncoding},
+		{name: "invalid padding", opts: []Option{WithPadding(0)}, want: ErrInvalidMinChunks},
+	} {
+		if _, err := New(data, tc.opts...); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestAddChunkInvalidChunkNumber(t *testing.T) {
+	// a legacy chunk numbered 5 of 2
+	chunk := make([]byte, ChunkSize32)
+	chunk[0], chunk[1], chunk[2] = 5, 2, byte(ChunkSize32)
+
+	result, err := NewEmpty().AddChunk(chunk)
+	if result != ChunkRejected || !errors.Is(err, ErrInvalidChunk) {
+		t.Errorf("got %v, %v, want a rejected chunk and %v", result, err, ErrInvalidChunk)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		add  func() error
+		want error
+	}{
+		{name: "base45", add: func() error { return NewEmpty().AddPayload("%A") }, want: ErrInvalidBase45},
+		{name: "ur type", add: func() error { return NewURDecoder().AddPart("ur:crypto-psbt/aeadaolazmjendeoti") }, want: ErrInvalidUR},
+		{name: "ur bytewords", add: func() error { return NewURDecoder().AddPart("ur:bytes/zzzz") }, want: ErrInvalidUR},
+		{name: "fragment length", add: func() error { _, err := NewUREncoder([]byte("ur"), 1); return err }, want: ErrInvalidFragmentLength},
+	} {
+		if err := tc.add(); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package qrseq
+
+// OnChunk sets a function that is called whenever a chunk is received for the
+// first time, so UIs can update their progress without polling Progress.
+//
+// The callbacks are called synchronously by DecodeImage, DecodeText and the
+// other methods passing frames to the QRSequence, and must not pass frames
+// to it themselves.
+//
+// Parameters:
+//   - f: the function called with the number of the chunk and the total
+//     number of chunks, or nil to remove it.
+func (s *QRSequence) OnChunk(f func(nr, tot int)) {
+	s.onChunk = f
+}
+
+// OnDuplicate sets a function that is called whenever a chunk arrives that
+// has already been received, e.g. to tell the user to wait for the next
+// frame. Chunks of a fountain coded sequence are never duplicates.
+//
+// Parameters:
+//   - f: the function called with the number of the chunk and the total
+//     number of chunks, or nil to remove it.
+func (s *QRSequence) OnDuplicate(f func(nr, tot int)) {
+	s.onDuplicate = f
+}
+
+// OnComplete sets a function that is called once the last chunk arrived and
+// the payload passed its checks, e.g. to advance to the next screen. If the
+// checks fail, it is not called and the error is reported by Err and Result
+// instead.
+//
+// Parameters:
+//   - f: the function called with the payload as returned by Data, or nil to
+//     remove it.
+func (s *QRSequence) OnComplete(f func(data []byte)) {
+	s.onComplete = f
+}
@@ -1,3 +1,5 @@
+//go:build !core
+
 package main
 
 import (
@@ -30,7 +32,10 @@ one of those pictures which are so contrived that the eyes follow you about
 when you move. BIG BROTHER IS WATCHING YOU, the caption beneath it ran.`
 
 func main() {
-	seq := qrseq.New([]byte(inputData), qrseq.ChunkSize64)
+	seq, err := qrseq.New([]byte(inputData), qrseq.WithChunkSize(qrseq.ChunkSize64))
+	if err != nil {
+		panic(err)
+	}
 
 	images, err := seq.QRCodes(3)
 	if err != nil {
@@ -0,0 +1,45 @@
+package qrseq
+
+import "crypto/sha256"
+
+// ExpectDigest primes a receiving QRSequence with the SHA-256 digests of the
+// payloads it may accept.
+//
+// For workflows where the payload is agreed on in advance, the digest is
+// communicated out of band. Once all chunks have arrived, the payload is only
+// accepted if its digest is one of the expected ones. Otherwise the sequence
+// never completes: Data stays nil, Err and DecodeImage return the terminal
+// error and it is delivered on the Result channel.
+//
+// Calling ExpectDigest again adds to the digests already expected.
+//
+// Parameters:
+// - digests: the digests of the acceptable payloads.
+func (s *QRSequence) ExpectDigest(digests ...[sha256.Size]byte) {
+	s.expected = append(s.expected, digests...)
+}
+
+// Err returns the terminal error of a receiving QRSequence, or nil if the
+// sequence has not failed.
+//
+// Returns:
+// - error: the error that ended the receive session.
+func (s QRSequence) Err() error {
+	return s.err
+}
+
+// checkDigest verifies the reassembled payload of a sequence whose chunks have
+// all arrived against the expected digests, if there are any.
+func (s *QRSequence) checkDigest() error {
+	if len(s.expected) == 0 {
+		return nil
+	}
+
+	digest := s.payloadDigest()
+	for _, expected := range s.expected {
+		if digest == expected {
+			return nil
+		}
+	}
+	return ErrUnexpectedDigest
+}
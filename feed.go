@@ -0,0 +1,211 @@
+package qrseq
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+)
+
+// A chunk feed starts with feedMagic followed by the protocol version. Every
+// message is stored as its kind (uint8) and the length of its body (uint32,
+// little endian), followed by the body: the text of a QR code for feedText
+// and the bytes of a decoded frame for feedFrame. The other kinds are only
+// exchanged with a DecodeWorker.
+const (
+	feedMagic   = "QRSF"
+	feedVersion = 1
+
+	feedText    = 1
+	feedFrame   = 2
+	feedImage   = 3 // width, height (uint32 each) and gray pixels
+	feedFailure = 4 // DecodeFailure (uint8) and error message
+
+	// maxFeedMessage bounds the body of a message, well above the capacity of
+	// the largest QR code.
+	maxFeedMessage = 1 << 16
+)
+
+// FeedWriter writes decoded QR codes to a chunk feed, so capture and
+// reassembly can run in separate processes connected by a unix socket or a
+// named pipe.
+type FeedWriter struct {
+	w io.Writer
+}
+
+// NewFeedWriter creates a new FeedWriter that writes the feed to w.
+//
+// Parameters:
+// - w: the io.Writer the feed is written to.
+//
+// Returns:
+// - *FeedWriter: the new FeedWriter.
+// - error: an error if the feed header cannot be written.
+func NewFeedWriter(w io.Writer) (*FeedWriter, error) {
+	if _, err := w.Write(append([]byte(feedMagic), feedVersion)); err != nil {
+		return nil, err
+	}
+	return &FeedWriter{w: w}, nil
+}
+
+// DialFeed connects to a receiver serving a chunk feed on the unix socket at
+// path.
+//
+// Parameters:
+// - path: the path of the unix socket.
+//
+// Returns:
+// - *FeedWriter: a FeedWriter writing to the connection.
+// - error: an error if the connection fails.
+func DialFeed(path string) (*FeedWriter, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := NewFeedWriter(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return fw, nil
+}
+
+// WriteText writes the text of a QR code, as passed to QRSequence.DecodeText.
+func (fw *FeedWriter) WriteText(text string) error {
+	if len(text) > maxFeedMessage {
+		return ErrFeedMessageTooLarge
+	}
+	return fw.write(feedText, []byte(text))
+}
+
+// WriteFrame writes the bytes of a decoded frame, as passed to
+// QRSequence.AddChunkFromBytes.
+func (fw *FeedWriter) WriteFrame(frame []byte) error {
+	if len(frame) > maxFeedMessage {
+		return ErrFeedMessageTooLarge
+	}
+	return fw.write(feedFrame, frame)
+}
+
+func (fw *FeedWriter) write(kind byte, body []byte) error {
+	header := make([]byte, 5)
+	header[0] = kind
+	binary.LittleEndian.PutUint32(header[1:5], uint32(len(body)))
+	if _, err := fw.w.Write(append(header, body...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (fw *FeedWriter) Close() error {
+	if c, ok := fw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ReadFeed reads a chunk feed written by a FeedWriter and adds every message
+// to the QRSequence.
+//
+// Messages that cannot be decoded are counted in Stats and skipped, like
+// unreadable frames of a camera. Reading stops at the end of the feed or as
+// soon as the sequence is complete.
+//
+// Parameters:
+// - r: the io.Reader to read the feed from, e.g. a connection or a named pipe.
+//
+// Returns:
+//   - error: an error if the feed is malformed or cannot be read, or the
+//     terminal error of the QRSequence.
+func (s *QRSequence) ReadFeed(r io.Reader) error {
+	br, err := readFeedHeader(r)
+	if err != nil {
+		return err
+	}
+
+	for s.err == nil && !s.IsComplete() {
+		kind, body, err := readFeedMessage(br, maxFeedMessage)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case feedText:
+			_ = s.DecodeText(string(body))
+		case feedFrame:
+			_ = s.receive(body)
+		default:
+			return ErrUnknownFeedMessage
+		}
+	}
+	return s.err
+}
+
+// readFeedHeader checks the header of a chunk feed and returns a buffered
+// reader for its messages.
+func readFeedHeader(r io.Reader) (*bufio.Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(feedMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(feedMagic)]) != feedMagic {
+		return nil, ErrNotFeed
+	}
+	if header[len(feedMagic)] != feedVersion {
+		return nil, ErrFeedVersion
+	}
+	return br, nil
+}
+
+// readFeedMessage reads the next message of a chunk feed, whose body may be at
+// most limit bytes long. It returns io.EOF at the end of the feed.
+func readFeedMessage(br *bufio.Reader, limit uint32) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(br, header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, ErrFeedTruncated
+	}
+	length := binary.LittleEndian.Uint32(header[1:5])
+	if length > limit {
+		return 0, nil, ErrFeedMessageTooLarge
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(br, body); err != nil {
+		return 0, nil, ErrFeedTruncated
+	}
+	return header[0], body, nil
+}
+
+// ServeFeed accepts connections on l, typically a unix socket created with
+// net.Listen("unix", path), and reads a chunk feed from each of them in turn
+// until the QRSequence is complete.
+//
+// A connection with a malformed feed is closed and the next one is accepted.
+// The listener is not closed.
+//
+// Parameters:
+// - l: the net.Listener to accept feeding connections on.
+//
+// Returns:
+//   - error: the error of l.Accept, or the terminal error of the QRSequence,
+//     nil once the sequence is complete.
+func (s *QRSequence) ServeFeed(l net.Listener) error {
+	for s.err == nil && !s.IsComplete() {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		_ = s.ReadFeed(conn)
+		conn.Close()
+	}
+	return s.err
+}
@@ -0,0 +1,61 @@
+package qrseq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadFeedMessageLimit(t *testing.T) {
+	message := func(length uint32, body int) []byte {
+		b := []byte{feedText, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(b[1:], length)
+		return append(b, make([]byte, body)...)
+	}
+	for _, tc := range []struct {
+		name    string
+		message []byte
+		want    error
+	}{
+		{name: "at limit", message: message(maxFeedMessage, maxFeedMessage)},
+		{name: "over limit", message: message(maxFeedMessage+1, maxFeedMessage+1), want: ErrFeedMessageTooLarge},
+		{name: "announced over limit", message: message(1<<31, 0), want: ErrFeedMessageTooLarge},
+		{name: "truncated body", message: message(10, 5), want: ErrFeedTruncated},
+		{name: "truncated header", message: []byte{feedText, 0}, want: ErrFeedTruncated},
+		{name: "end of feed", want: io.EOF},
+	} {
+		_, body, err := readFeedMessage(bufio.NewReader(bytes.NewReader(tc.message)), maxFeedMessage)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+		if err == nil && len(body) != maxFeedMessage {
+			t.Errorf("%s: got a body of %d bytes", tc.name, len(body))
+		}
+	}
+}
+
+func TestFeedMessageTooLarge(t *testing.T) {
+	buf := new(bytes.Buffer)
+	fw, err := NewFeedWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.WriteText(strings.Repeat("A", maxFeedMessage+1)); !errors.Is(err, ErrFeedMessageTooLarge) {
+		t.Errorf("WriteText: got %v, want %v", err, ErrFeedMessageTooLarge)
+	}
+	if err := fw.WriteFrame(make([]byte, maxFeedMessage+1)); !errors.Is(err, ErrFeedMessageTooLarge) {
+		t.Errorf("WriteFrame: got %v, want %v", err, ErrFeedMessageTooLarge)
+	}
+
+	// a writer that does not check the limit
+	if err := fw.write(feedFrame, make([]byte, maxFeedMessage+1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewEmpty().ReadFeed(buf); !errors.Is(err, ErrFeedMessageTooLarge) {
+		t.Errorf("ReadFeed: got %v, want %v", err, ErrFeedMessageTooLarge)
+	}
+}
@@ -0,0 +1,121 @@
+package qrseq
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// Metadata describes a payload accepted by Finalize.
+type Metadata struct {
+	ChunkSize ChunkSize
+	Chunks    int
+	Length    int
+	Digest    [sha256.Size]byte
+	Duration  time.Duration // time between the first and the last new chunk
+	Signer    string        // name of the trusted key that signed the payload
+}
+
+// Policy is an application check run by Finalize on the reassembled payload. A
+// non-nil error rejects the payload.
+type Policy func(data []byte, md Metadata) error
+
+// ExpectLength makes Finalize reject payloads that are not exactly n bytes
+// long.
+//
+// Parameters:
+// - n: the expected payload length in bytes.
+func (s *QRSequence) ExpectLength(n int) {
+	s.expectedLength = n
+	s.checkLength = true
+}
+
+// RequireSignature makes Finalize reject payloads that were not signed, see
+// WithSigning, by one of the keys pinned in a TrustStore.
+//
+// Parameters:
+// - t: the TrustStore holding the keys of the trusted senders.
+func (s *QRSequence) RequireSignature(t *TrustStore) {
+	s.trust = t
+}
+
+// AddPolicy adds a Policy that Finalize runs after its own checks, in the order
+// the policies were added.
+//
+// Parameters:
+// - p: the Policy to add.
+func (s *QRSequence) AddPolicy(p Policy) {
+	s.policies = append(s.policies, p)
+}
+
+// Finalize performs all end-of-transfer validation of a completed receiving
+// QRSequence in one call and moves it into a terminal state.
+//
+// It checks the payload against the digest embedded by the sender, the
+// expected digests and the expected length, verifies its signature if
+// RequireSignature was called and runs the policies. Using Finalize instead
+// of Data makes these checks impossible to skip by accident. If a check fails, its error becomes the
+// terminal error of the sequence. Finalize can only succeed once.
+//
+// Returns:
+//   - []byte: the validated payload.
+//   - Metadata: the metadata of the payload.
+//   - error: an error if the sequence is not complete, was already finalized,
+//     its payload has been drained or a check fails.
+func (s *QRSequence) Finalize() ([]byte, Metadata, error) {
+	if s.err != nil {
+		return nil, Metadata{}, s.err
+	}
+	if s.finalized {
+		return nil, Metadata{}, ErrAlreadyFinalized
+	}
+	if !s.IsComplete() {
+		return nil, Metadata{}, ErrSequenceIncomplete
+	}
+	if s.drainHash != nil {
+		return nil, Metadata{}, ErrPayloadDrained
+	}
+
+	data := s.Data()
+	md := Metadata{
+		ChunkSize: s.ChunkSize,
+		Chunks:    len(s.chunks),
+		Length:    len(data),
+		Digest:    sha256.Sum256(data),
+		Duration:  s.duration(),
+	}
+
+	if err := s.finalCheck(data, &md); err != nil {
+		s.err = err
+		return nil, Metadata{}, err
+	}
+	s.finalized = true
+	return data, md, nil
+}
+
+func (s *QRSequence) finalCheck(data []byte, md *Metadata) error {
+	if err := s.checkSentDigest(); err != nil {
+		return err
+	}
+	if err := s.checkDigest(); err != nil {
+		return err
+	}
+	if s.checkLength && md.Length != s.expectedLength {
+		return ErrUnexpectedLength
+	}
+	if s.trust != nil {
+		if s.signature == nil {
+			return ErrUnsigned
+		}
+		signer, err := s.trust.Verify(data, s.signature)
+		if err != nil {
+			return err
+		}
+		md.Signer = signer
+	}
+	for _, policy := range s.policies {
+		if err := policy(data, *md); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package qrseq
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestFinalizeVerifiesSignature(t *testing.T) {
+	data := []byte("signed payload")
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize))
+	trust, err := NewTrustStore(&MemoryTrustBackend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trust.Add("sender", priv.Public().(ed25519.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want error
+	}{
+		{name: "trusted", opts: []Option{WithSigning(priv), WithCompression(CompressionGzip)}},
+		{name: "untrusted", opts: []Option{WithSigning(other)}, want: ErrUntrustedKey},
+		{name: "unsigned", want: ErrUnsigned},
+	} {
+		sender, err := New(data, tc.opts...)
+		if err != nil {
+			t.Fatalf("%s: New: %v", tc.name, err)
+		}
+		payloads, err := sender.Payloads()
+		if err != nil {
+			t.Fatalf("%s: Payloads: %v", tc.name, err)
+		}
+		receiver := NewEmpty()
+		receiver.RequireSignature(trust)
+		for _, payload := range payloads {
+			if err := receiver.AddPayload(payload); err != nil {
+				t.Fatalf("%s: AddPayload: %v", tc.name, err)
+			}
+		}
+
+		got, md, err := receiver.Finalize()
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+		if tc.want == nil && (!bytes.Equal(got, data) || md.Signer != "sender") {
+			t.Errorf("%s: got %q signed by %q", tc.name, got, md.Signer)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package qrseq
+
+import (
+	"time"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// Fountain sends a payload as an endless stream of LT (Luby transform) coded
+// frames.
+//
+// With plain sequences a receiver that misses a frame has to wait for the
+// whole cycle to come around again. Fountain frames instead each combine a
+// random set of payload blocks, so any frames seen help, and the receiver
+// completes after collecting slightly more distinct frames than there are
+// blocks. The first Blocks frames carry one block each, so a receiver that
+// sees all of them completes right away.
+//
+// Fountain frames are decoded by a receiving QRSequence like any other frame.
+// Like chunks, they carry a random sequence ID, which keeps the frames of
+// concurrent senders apart, a CRC, which rejects misread frames before they
+// reach the decoder, and optionally an application tag.
+type Fountain struct {
+	enc       *internal.FountainEncoder
+	chunkSize ChunkSize
+	next      uint32
+}
+
+// NewFountain creates a Fountain for the given payload. Its sequence ID is
+// drawn from the source set with WithRand and its frames carry the tag set
+// with WithAppTag, other options are ignored.
+//
+// Parameters:
+// - data: the payload to send.
+// - chunkSize: a ChunkSize enum value specifying the size of each frame.
+// - opts: the options to apply.
+//
+// Returns:
+//   - *Fountain: the new Fountain.
+//   - error: an error if an option is invalid, the chunk size is invalid or
+//     the payload is too large for the chunk size.
+func NewFountain(data []byte, chunkSize ChunkSize, opts ...Option) (*Fountain, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := internal.NewFountainEncoder(data, uint16(chunkSize), readSeqID(orRand(o.rand)), o.appTag)
+	if err != nil {
+		return nil, err
+	}
+	return &Fountain{enc: enc, chunkSize: chunkSize}, nil
+}
+
+// Blocks returns the number of blocks the payload is split into, which is the
+// minimum number of frames a receiver needs.
+func (f *Fountain) Blocks() int {
+	return f.enc.Blocks()
+}
+
+// NextPayload returns the text of the next frame, for callers that render the
+// QR codes themselves.
+func (f *Fountain) NextPayload() string {
+	frame := f.enc.Frame(f.next)
+	f.next++
+	return internal.EncodeText(frame, internal.TextBase64)
+}
+
+// addFountainFrame adds a parsed fountain frame with header h and encoded
+// block to a receiving QRSequence. The first frame fixes the layout of the
+// sequence; frames of other sequences are rejected with a
+// *ChunkMismatchError.
+func (s *QRSequence) addFountainFrame(h internal.FountainHeader, block, frame []byte) (ChunkResult, error) {
+	if s.ChunkSize == ChunkSizeUnknown {
+		s.ChunkSize = ChunkSize(h.ChunkSize)
+		s.chunks = make([]*internal.QRChunk, h.Blocks)
+		s.firstSeen = make([]time.Time, h.Blocks)
+		s.nrReceived = 0
+		s.layout = internal.LayoutFountain
+		s.seqID, s.hasSeqID = h.SeqID, true
+		s.fountain = internal.NewFountainDecoder(h)
+	}
+	if err := s.checkFountainFrame(h); err != nil {
+		return ChunkRejected, err
+	}
+
+	dec := s.fountain
+	if dec.Seen(h) {
+		return ChunkDuplicate, nil
+	}
+	if err := s.teeFrame(frame); err != nil {
+		return ChunkIgnored, err
+	}
+	for _, nr := range dec.Add(h, block) {
+		s.setChunk(dec.Chunk(nr))
+	}
+	return ChunkAccepted, nil
+}
+
+// checkFountainFrame checks that a fountain frame belongs to the sequence
+// established by the frames received before.
+//
+// Parameters:
+// - h: the header of the frame.
+//
+// Returns:
+// - error: a *ChunkMismatchError naming the first conflicting field.
+func (s *QRSequence) checkFountainFrame(h internal.FountainHeader) error {
+	if s.fountain == nil {
+		return &ChunkMismatchError{Field: "layout", Want: int64(s.layout), Got: int64(internal.LayoutFountain)}
+	}
+	want := s.fountain.Header()
+	switch {
+	case h.ChunkSize != want.ChunkSize:
+		return &ChunkMismatchError{Field: "chunk size", Want: int64(want.ChunkSize), Got: int64(h.ChunkSize)}
+	case h.Blocks != want.Blocks:
+		return &ChunkMismatchError{Field: "total", Want: int64(want.Blocks), Got: int64(h.Blocks)}
+	case h.Length != want.Length:
+		return &ChunkMismatchError{Field: "length", Want: int64(want.Length), Got: int64(h.Length)}
+	case h.SeqID != want.SeqID:
+		return &ChunkMismatchError{Field: "sequence ID", Want: int64(want.SeqID), Got: int64(h.SeqID)}
+	case h.AppTag != want.AppTag:
+		return &ChunkMismatchError{Field: "app tag", Want: int64(want.AppTag), Got: int64(h.AppTag)}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package qrseq
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+func TestFountainSeqIDFromRand(t *testing.T) {
+	data := bytes.Repeat([]byte("fountain"), 100)
+	payloads := func() []string {
+		f, err := NewFountain(data, ChunkSize128, WithRand(rand.New(rand.NewSource(1))))
+		if err != nil {
+			t.Fatalf("NewFountain: %v", err)
+		}
+		var p []string
+		for range f.Blocks() {
+			p = append(p, f.NextPayload())
+		}
+		return p
+	}
+	if !slices.Equal(payloads(), payloads()) {
+		t.Error("fountains with the same source of randomness differ")
+	}
+
+	pages := func() [][]string {
+		b, err := NewBackup(data, ChunkSize128, 4, WithRand(rand.New(rand.NewSource(1))))
+		if err != nil {
+			t.Fatalf("NewBackup: %v", err)
+		}
+		return b.Pages
+	}
+	if !slices.EqualFunc(pages(), pages(), slices.Equal) {
+		t.Error("backups with the same source of randomness differ")
+	}
+}
+
+func TestFountainAppTag(t *testing.T) {
+	data := bytes.Repeat([]byte("tagged"), 50)
+	for _, tc := range []struct {
+		name     string
+		sent     uint16
+		received uint16
+		ok       bool
+	}{
+		{name: "same tag", sent: 7, received: 7, ok: true},
+		{name: "untagged receiver", sent: 7, ok: true},
+		{name: "other tag", sent: 7, received: 8},
+		{name: "untagged frames", received: 7},
+	} {
+		f, err := NewFountain(data, ChunkSize128, WithAppTag(tc.sent))
+		if err != nil {
+			t.Fatalf("%s: NewFountain: %v", tc.name, err)
+		}
+		receiver := NewEmpty(WithAppTag(tc.received))
+		for range f.Blocks() {
+			frame, _ := internal.DecodeText(f.NextPayload())
+			_, err = receiver.AddChunk(frame)
+		}
+
+		var mismatch *ChunkMismatchError
+		if tc.ok && (err != nil || !bytes.Equal(receiver.Data(), data)) {
+			t.Errorf("%s: got %v, want the payload", tc.name, err)
+		}
+		if !tc.ok && (!errors.As(err, &mismatch) || mismatch.Field != "app tag") {
+			t.Errorf("%s: got %v, want an app tag mismatch", tc.name, err)
+		}
+	}
+}
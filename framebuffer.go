@@ -0,0 +1,220 @@
+//go:build linux && !core
+
+package qrseq
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ioctl requests and structure sizes of the Linux framebuffer API, see
+// linux/fb.h.
+const (
+	fbioGetVScreenInfo = 0x4600
+	fbioGetFScreenInfo = 0x4602
+
+	fbVarScreenInfoSize = 160
+	fbFixScreenInfoSize = 96 // large enough on 32 and 64 bit platforms
+)
+
+// Framebuffer shows frames on a Linux framebuffer device such as /dev/fb0, so
+// minimal air-gapped systems without X or Wayland can act as senders.
+//
+// Frames are drawn centered on a white screen. Devices with 16, 24 and 32 bits
+// per pixel are supported, in any channel layout the driver reports.
+type Framebuffer struct {
+	mem    []byte
+	width  int
+	height int
+	stride int
+	bpp    int
+	offset int             // offset of the visible area in mem
+	area   image.Rectangle // area covered by the last frame
+	red    fbChannel
+	green  fbChannel
+	blue   fbChannel
+}
+
+// fbChannel is the position of a color channel in a pixel.
+type fbChannel struct {
+	offset uint32
+	length uint32
+}
+
+// OpenFramebuffer maps a framebuffer device.
+//
+// Parameters:
+// - path: the path of the device, e.g. /dev/fb0.
+//
+// Returns:
+//   - *Framebuffer: the mapped framebuffer.
+//   - error: an error if the device cannot be mapped or has an unsupported
+//     pixel format.
+func OpenFramebuffer(path string) (*Framebuffer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vinfo [fbVarScreenInfoSize]byte
+	if err := fbIoctl(f, fbioGetVScreenInfo, vinfo[:]); err != nil {
+		return nil, err
+	}
+	var finfo [fbFixScreenInfoSize]byte
+	if err := fbIoctl(f, fbioGetFScreenInfo, finfo[:]); err != nil {
+		return nil, err
+	}
+
+	u32 := func(b []byte, i int) uint32 { return binary.NativeEndian.Uint32(b[i : i+4]) }
+	fb := &Framebuffer{
+		width:  int(u32(vinfo[:], 0)),
+		height: int(u32(vinfo[:], 4)),
+		bpp:    int(u32(vinfo[:], 24)) / 8,
+		red:    fbChannel{offset: u32(vinfo[:], 32), length: u32(vinfo[:], 36)},
+		green:  fbChannel{offset: u32(vinfo[:], 44), length: u32(vinfo[:], 48)},
+		blue:   fbChannel{offset: u32(vinfo[:], 56), length: u32(vinfo[:], 60)},
+	}
+	// line_length follows the id, smem_start, four uint32 and three uint16
+	// fields, aligned to 4 bytes
+	lineLength := (16 + int(unsafe.Sizeof(uintptr(0))) + 4*4 + 3*2 + 3) &^ 3
+	fb.stride = int(u32(finfo[:], lineLength))
+	yresVirtual := int(u32(vinfo[:], 12))
+	fb.offset = int(u32(vinfo[:], 20))*fb.stride + int(u32(vinfo[:], 16))*fb.bpp
+
+	if fb.bpp < 2 || fb.bpp > 4 || fb.width < 1 || fb.height < 1 || fb.stride < fb.width*fb.bpp {
+		return nil, ErrUnsupportedFramebuffer
+	}
+	fb.mem, err = syscall.Mmap(int(f.Fd()), 0, fb.stride*max(yresVirtual, fb.height), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	if fb.offset+fb.height*fb.stride > len(fb.mem) {
+		syscall.Munmap(fb.mem)
+		return nil, ErrUnsupportedFramebuffer
+	}
+	return fb, nil
+}
+
+func fbIoctl(f *os.File, req uintptr, buf []byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(&buf[0])))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Bounds returns the size of the visible area of the framebuffer.
+func (fb *Framebuffer) Bounds() image.Rectangle {
+	return image.Rect(0, 0, fb.width, fb.height)
+}
+
+// Show draws a frame centered on a white screen.
+//
+// Parameters:
+// - img: the frame to show.
+//
+// Returns:
+// - error: an error if the frame does not fit on the screen.
+func (fb *Framebuffer) Show(img image.Image) error {
+	b := img.Bounds()
+	if b.Dx() > fb.width || b.Dy() > fb.height {
+		return ErrFrameTooLarge
+	}
+
+	// the screen is only cleared when the frame size changes, frames of the
+	// same size cover each other completely
+	area := image.Rect(0, 0, b.Dx(), b.Dy()).Add(image.Pt((fb.width-b.Dx())/2, (fb.height-b.Dy())/2))
+	if area != fb.area {
+		fb.fill(fb.Bounds())
+		fb.area = area
+	}
+	for y := 0; y < b.Dy(); y++ {
+		row := fb.mem[fb.offset+(area.Min.Y+y)*fb.stride+area.Min.X*fb.bpp:]
+		for x := 0; x < b.Dx(); x++ {
+			gray := color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+			fb.put(row[x*fb.bpp:], gray)
+		}
+	}
+	return nil
+}
+
+// fill paints an area of the screen white.
+func (fb *Framebuffer) fill(r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := fb.mem[fb.offset+y*fb.stride:]
+		for x := r.Min.X; x < r.Max.X; x++ {
+			fb.put(row[x*fb.bpp:], 0xff)
+		}
+	}
+}
+
+// put stores a gray level as a pixel in the format of the framebuffer, in
+// native byte order.
+func (fb *Framebuffer) put(pixel []byte, gray uint8) {
+	var v uint32
+	for _, c := range []fbChannel{fb.red, fb.green, fb.blue} {
+		if c.length > 0 && c.length <= 8 {
+			v |= uint32(gray>>(8-c.length)) << c.offset
+		}
+	}
+	switch fb.bpp {
+	case 2:
+		binary.NativeEndian.PutUint16(pixel, uint16(v))
+	case 3:
+		var buf [4]byte
+		binary.NativeEndian.PutUint32(buf[:], v)
+		if binary.NativeEndian.Uint16([]byte{1, 0}) == 1 {
+			copy(pixel[:3], buf[:3])
+		} else {
+			copy(pixel[:3], buf[1:])
+		}
+	case 4:
+		binary.NativeEndian.PutUint32(pixel, v)
+	}
+}
+
+// Play shows the frames of a complete QRSequence in a loop.
+//
+// Parameters:
+// - seq: the sending QRSequence.
+// - opt: the RenderOptions to render the frames with.
+// - fps: the number of frames shown per second.
+// - loops: the number of times the sequence is shown.
+//
+// Returns:
+//   - error: an error if the sequence is not complete, fps is not positive or
+//     a frame cannot be rendered or does not fit on the screen.
+func (fb *Framebuffer) Play(seq *QRSequence, opt RenderOptions, fps float64, loops int) error {
+	if fps <= 0 {
+		return ErrInvalidFrameRate
+	}
+	images, err := seq.playFrames(opt)
+	if err != nil {
+		return err
+	}
+
+	interval := time.Duration(float64(time.Second) / fps)
+	clock := seq.timeSource()
+	next := clock.Now()
+	for loop := 0; loop < loops; loop++ {
+		for _, img := range images {
+			sleepUntil(clock, next)
+			if err := fb.Show(img); err != nil {
+				return err
+			}
+			next = next.Add(interval)
+		}
+	}
+	return nil
+}
+
+// Close unmaps the framebuffer.
+func (fb *Framebuffer) Close() error {
+	return syscall.Munmap(fb.mem)
+}
@@ -0,0 +1,249 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// FS returns a read-only file system holding the frames of the QRSequence as
+// PNG files named frame_0000.png, frame_0001.png and so on.
+//
+// Frames are rendered when they are opened, so tools that serve or archive
+// file trees, like http.FS or zip.Writer.AddFS, can consume a sequence without
+// rendering all frames up front.
+//
+// Parameters:
+// - opt: the RenderOptions to render the frames with.
+//
+// Returns:
+//   - fs.FS: the file system of frames.
+//   - error: an error if the QRSequence is not complete or the edge chunks do
+//     not fit their error correction level.
+func (s QRSequence) FS(opt RenderOptions) (fs.FS, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	return &frameFS{chunks: s.chunks, opt: opt}, nil
+}
+
+// EncodePNG writes the QR code of a single chunk of the QRSequence as a PNG
+// image, rendered like the frames of QRCodesWithOptions.
+//
+// Parameters:
+// - w: the io.Writer the image is written to.
+// - nr: the number of the chunk.
+// - opt: the RenderOptions to render the QR code with.
+//
+// Returns:
+//   - error: an error if the QRSequence is not complete, nr is out of range or
+//     the image cannot be rendered or written.
+func (s QRSequence) EncodePNG(w io.Writer, nr int, opt RenderOptions) error {
+	if !s.IsComplete() {
+		return ErrSequenceIncomplete
+	}
+	if nr < 0 || nr >= len(s.chunks) {
+		return ErrChunkOutOfRange
+	}
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return err
+	}
+	img, err := opt.render(s.chunks[nr], nr)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// SavePNGs writes the frames of the QRSequence as PNG files into dir, named
+// like the files of FS. The directory is created if it does not exist, and
+// every file is written under a temporary name and renamed into place.
+//
+// Parameters:
+// - dir: the directory the frames are written to.
+// - opt: the RenderOptions to render the frames with.
+//
+// Returns:
+//   - error: an error if the QRSequence is not complete or a frame cannot be
+//     rendered or written.
+func (s QRSequence) SavePNGs(dir string, opt RenderOptions) error {
+	if !s.IsComplete() {
+		return ErrSequenceIncomplete
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return err
+	}
+	for i, chunk := range s.chunks {
+		img, err := opt.render(chunk, i)
+		if err != nil {
+			return err
+		}
+		if err := writeImageFile(filepath.Join(dir, frameName(i)), img, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type frameFS struct {
+	chunks []*internal.QRChunk
+	opt    RenderOptions
+}
+
+func frameName(i int) string {
+	return fmt.Sprintf("frame_%04d.png", i)
+}
+
+// index returns the chunk number of the frame with the given file name.
+func (f *frameFS) index(name string) (int, bool) {
+	digits, ok := strings.CutPrefix(name, "frame_")
+	if !ok {
+		return 0, false
+	}
+	digits, ok = strings.CutSuffix(digits, ".png")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(digits)
+	if err != nil || i < 0 || i >= len(f.chunks) || frameName(i) != name {
+		return 0, false
+	}
+	return i, true
+}
+
+func (f *frameFS) render(i int) ([]byte, error) {
+	img, err := f.opt.render(f.chunks[i], i)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f *frameFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &frameDir{fsys: f}, nil
+	}
+
+	i, ok := f.index(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	data, err := f.render(i)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &frameFile{
+		Reader: bytes.NewReader(data),
+		info:   frameInfo{name: name, size: int64(len(data))},
+	}, nil
+}
+
+// frameFile is an open frame. It supports seeking, as required by
+// http.FileServer.
+type frameFile struct {
+	*bytes.Reader
+	info frameInfo
+}
+
+func (f *frameFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *frameFile) Close() error               { return nil }
+
+// frameDir is the open root directory of a frameFS.
+type frameDir struct {
+	fsys   *frameFS
+	offset int
+}
+
+func (d *frameDir) Stat() (fs.FileInfo, error) {
+	return frameInfo{name: ".", dir: true}, nil
+}
+
+func (d *frameDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: syscall.EISDIR}
+}
+
+func (d *frameDir) Close() error { return nil }
+
+func (d *frameDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := len(d.fsys.chunks) - d.offset
+	if n > 0 && remaining == 0 {
+		return nil, io.EOF
+	}
+	if n <= 0 || n > remaining {
+		n = remaining
+	}
+
+	entries := make([]fs.DirEntry, n)
+	for i := range entries {
+		entries[i] = frameEntry{fsys: d.fsys, nr: d.offset + i}
+	}
+	d.offset += n
+	return entries, nil
+}
+
+// frameEntry is a directory entry of a frame. Its size is only known once the
+// frame is rendered, so Info renders it.
+type frameEntry struct {
+	fsys *frameFS
+	nr   int
+}
+
+func (e frameEntry) Name() string      { return frameName(e.nr) }
+func (e frameEntry) IsDir() bool       { return false }
+func (e frameEntry) Type() fs.FileMode { return 0 }
+
+func (e frameEntry) Info() (fs.FileInfo, error) {
+	data, err := e.fsys.render(e.nr)
+	if err != nil {
+		return nil, err
+	}
+	return frameInfo{name: e.Name(), size: int64(len(data))}, nil
+}
+
+type frameInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i frameInfo) Name() string       { return i.name }
+func (i frameInfo) Size() int64        { return i.size }
+func (i frameInfo) ModTime() time.Time { return time.Time{} }
+func (i frameInfo) IsDir() bool        { return i.dir }
+func (i frameInfo) Sys() any           { return nil }
+
+func (i frameInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
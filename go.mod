@@ -1,10 +1,10 @@
 module github.com/airsigner/qrseq
 
-go 1.22.3
+go 1.23
 
 require (
 	github.com/makiuchi-d/gozxing v0.1.1
-	github.com/yeqown/go-qrcode/v2 v2.2.4
+	github.com/yeqown/go-qrcode/v2 v2.2.5
 )
 
 require (
@@ -0,0 +1,212 @@
+package qrseq
+
+import (
+	"image"
+	"image/color"
+)
+
+// Guidance is an actionable hint for the user pointing the camera, derived
+// from frames that could not be decoded or that decoded in a poor
+// orientation.
+type Guidance uint8
+
+const (
+	// GuidanceNone means frames decode, or their failures have no cause the
+	// user could fix.
+	GuidanceNone Guidance = iota
+	// GuidanceTooDark means the frames are underexposed.
+	GuidanceTooDark
+	// GuidanceTooBright means the frames are overexposed.
+	GuidanceTooBright
+	// GuidanceLowContrast means the frames are washed out, usually by glare
+	// on the screen.
+	GuidanceLowContrast
+	// GuidanceHoldSteady means the frames are blurred by motion or focus.
+	GuidanceHoldSteady
+	// GuidanceMoveCloser means the frames are sharp and well exposed, but no
+	// code was found in them, so it is too small or out of view.
+	GuidanceMoveCloser
+	// GuidanceRotateDevice means the frames decode, but the codes appear
+	// rotated by 90 degrees, so the camera is held in portrait orientation
+	// in front of a landscape screen or vice versa and the codes cover
+	// fewer camera pixels than they could.
+	GuidanceRotateDevice
+)
+
+// guidanceStreak is the number of consecutive failed frames that must agree
+// on a hint before it is delivered, so single bad frames do not make hints
+// flicker.
+const guidanceStreak = 3
+
+// Thresholds of the frame analysis, for 8 bit luma.
+const (
+	darkHigh       = 70
+	brightLow      = 190
+	minContrast    = 48
+	minSharpness   = 0.25
+	analysisLength = 512 // rows sampled at most
+)
+
+// String returns the hint as a message suitable for users.
+func (g Guidance) String() string {
+	switch g {
+	case GuidanceNone:
+		return ""
+	case GuidanceTooDark:
+		return "image too dark"
+	case GuidanceTooBright:
+		return "image too bright"
+	case GuidanceLowContrast:
+		return "avoid glare on the screen"
+	case GuidanceHoldSteady:
+		return "hold steady"
+	case GuidanceMoveCloser:
+		return "move closer"
+	case GuidanceRotateDevice:
+		return "rotate device"
+	}
+	return "unknown guidance"
+}
+
+// Guidance returns a channel on which hints for the user are delivered while
+// frames are passed to DecodeImage, so UIs can tell users how to fix failing
+// scans.
+//
+// Frames are analyzed only once Guidance has been called. A hint is
+// delivered when several consecutive frames agree on it, and GuidanceNone is
+// delivered when an upright frame decodes again after a hint. Rotated frames
+// decode like upright ones, GuidanceRotateDevice only points out that turning
+// the device would make the codes larger. The
+// channel holds only the latest hint, older hints that were not received are
+// dropped. It is never closed; stop receiving once Result delivers.
+//
+// Returns:
+// - <-chan Guidance: the channel delivering the hints.
+func (s *QRSequence) Guidance() <-chan Guidance {
+	if s.guidance == nil {
+		s.guidance = make(chan Guidance, 1)
+	}
+	return s.guidance
+}
+
+// guide analyzes the outcome of a frame and delivers a hint if it changed.
+// The rotation of the code is only used if the frame decoded. It does nothing
+// if nobody asked for guidance.
+func (s *QRSequence) guide(img image.Image, rotation int, err *DecodeError) {
+	if s.guidance == nil {
+		return
+	}
+
+	g := GuidanceNone
+	switch {
+	case err != nil:
+		g = AnalyzeFrame(img, err.Failure)
+	case rotation%180 != 0:
+		g = GuidanceRotateDevice
+	}
+	if g == s.guidanceCandidate {
+		s.guidanceCount++
+	} else {
+		s.guidanceCandidate, s.guidanceCount = g, 1
+	}
+
+	// an upright frame that decodes clears a hint right away
+	if g == GuidanceNone && err == nil {
+		s.guidanceCount = guidanceStreak
+	}
+	if s.guidanceCount >= guidanceStreak && g != s.guidanceLast {
+		s.deliverGuidance(g)
+	}
+}
+
+// deliverGuidance replaces the hint waiting on the guidance channel.
+func (s *QRSequence) deliverGuidance(g Guidance) {
+	select {
+	case <-s.guidance:
+	default:
+	}
+	s.guidance <- g
+	s.guidanceLast = g
+}
+
+// AnalyzeFrame derives a hint for the user from a frame that could not be
+// decoded, judging its exposure from the darkest and brightest parts of the
+// luma histogram and its sharpness from the steepest edges relative to the
+// contrast. Frames of a screen are mostly light, so the mean luma is not
+// used.
+//
+// Parameters:
+// - img: the frame that failed to decode.
+// - failure: why the frame failed to decode.
+//
+// Returns:
+//   - Guidance: the hint, or GuidanceNone if the failure has no cause the user
+//     could fix.
+func AnalyzeFrame(img image.Image, failure DecodeFailure) Guidance {
+	switch failure {
+	case FailureNotFound, FailureChecksum, FailureFormat, FailureCorrupt:
+	default:
+		return GuidanceNone
+	}
+
+	var levels, edges [256]int
+	b := img.Bounds()
+	if b.Dx() < 2 || b.Dy() < 1 {
+		return GuidanceNone
+	}
+	step := max(1, b.Dy()/analysisLength)
+	samples := 0
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		prev := luma(img, b.Min.X, y)
+		for x := b.Min.X + 1; x < b.Max.X; x++ {
+			l := luma(img, x, y)
+			levels[l]++
+			edges[absDiff(l, prev)]++
+			samples++
+			prev = l
+		}
+	}
+
+	low, high := percentile(levels, samples, 0.02), percentile(levels, samples, 0.98)
+	switch {
+	case high < darkHigh:
+		return GuidanceTooDark
+	case low > brightLow:
+		return GuidanceTooBright
+	case high-low < minContrast:
+		return GuidanceLowContrast
+	case float64(percentile(edges, samples, 0.99)) < minSharpness*float64(high-low):
+		return GuidanceHoldSteady
+	case failure == FailureNotFound:
+		return GuidanceMoveCloser
+	}
+	return GuidanceNone
+}
+
+func luma(img image.Image, x, y int) uint8 {
+	if gray, ok := img.(*image.Gray); ok {
+		return gray.Pix[gray.PixOffset(x, y)]
+	}
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// percentile returns the smallest value of a histogram of n samples that at
+// least the fraction p of the samples does not exceed.
+func percentile(hist [256]int, n int, p float64) int {
+	want := int(p * float64(n))
+	count := 0
+	for v, c := range hist {
+		count += c
+		if count > want {
+			return v
+		}
+	}
+	return 255
+}
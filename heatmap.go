@@ -0,0 +1,175 @@
+package qrseq
+
+import (
+	"encoding/csv"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Heatmap holds a capture difficulty value for every chunk index of a
+// sequence. Higher values mean the chunk was harder to capture. A value of
+// +Inf marks a chunk that was never captured.
+type Heatmap []float64
+
+var (
+	heatmapEasy    = color.RGBA{R: 0x1a, G: 0x98, B: 0x50, A: 0xff}
+	heatmapHard    = color.RGBA{R: 0xd7, G: 0x30, B: 0x27, A: 0xff}
+	heatmapMissing = color.RGBA{A: 0xff}
+)
+
+// DelayHeatmap builds a Heatmap from the first-seen times of the QRSequence.
+//
+// The value of each chunk is the number of seconds between the first received
+// chunk of the sequence and the moment that chunk was first received. Chunks
+// that have not been received are +Inf.
+//
+// Returns:
+//   - Heatmap: the per-chunk capture delays, or nil if the sequence has no
+//     receive history.
+func (s QRSequence) DelayHeatmap() Heatmap {
+	if len(s.firstSeen) == 0 {
+		return nil
+	}
+
+	start := s.firstSeen[0]
+	for _, t := range s.firstSeen {
+		if !t.IsZero() && (start.IsZero() || t.Before(start)) {
+			start = t
+		}
+	}
+
+	h := make(Heatmap, len(s.firstSeen))
+	for i, t := range s.firstSeen {
+		if t.IsZero() {
+			h[i] = math.Inf(1)
+			continue
+		}
+		h[i] = t.Sub(start).Seconds()
+	}
+	return h
+}
+
+// MissHeatmap builds a Heatmap counting, for every chunk index, in how many of
+// the given receive sessions that chunk was missing.
+//
+// Sessions may disagree on the number of chunks, the heatmap covers the
+// largest one. Sessions that never received a chunk are skipped.
+//
+// Parameters:
+// - sessions: the receiving QRSequences to aggregate.
+//
+// Returns:
+// - Heatmap: the per-chunk miss counts.
+func MissHeatmap(sessions ...*QRSequence) Heatmap {
+	size := 0
+	for _, s := range sessions {
+		size = max(size, len(s.chunks))
+	}
+
+	h := make(Heatmap, size)
+	for _, s := range sessions {
+		if s.ChunkSize == ChunkSizeUnknown {
+			continue
+		}
+		for i, chunk := range s.chunks {
+			if chunk == nil {
+				h[i]++
+			}
+		}
+	}
+	return h
+}
+
+// WriteCSV writes the Heatmap as CSV with a header row followed by one
+// "chunk,value" row per chunk index. Chunks that were never captured are
+// written with the value "missing".
+//
+// Parameters:
+// - w: the io.Writer to write the CSV to.
+//
+// Returns:
+// - error: an error if writing to w fails.
+func (h Heatmap) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"chunk", "value"}); err != nil {
+		return err
+	}
+	for i, v := range h {
+		value := "missing"
+		if !math.IsInf(v, 1) {
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		if err := cw.Write([]string{strconv.Itoa(i), value}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Image renders the Heatmap as a grid of square cells, one per chunk index, in
+// row-major order.
+//
+// The cell color runs from green for the lowest value to red for the highest
+// value. Chunks that were never captured are drawn black.
+//
+// Parameters:
+// - cellSize: the width and height of a cell in pixels.
+// - columns: the number of cells per row, or 0 to lay out a square grid.
+//
+// Returns:
+// - image.Image: the rendered heatmap, or nil if the heatmap is empty.
+func (h Heatmap) Image(cellSize, columns int) image.Image {
+	if len(h) == 0 || cellSize < 1 {
+		return nil
+	}
+	if columns < 1 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(h)))))
+	}
+	rows := (len(h) + columns - 1) / columns
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range h {
+		if math.IsInf(v, 1) {
+			continue
+		}
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, columns*cellSize, rows*cellSize))
+	for i, v := range h {
+		c := heatmapMissing
+		if !math.IsInf(v, 1) {
+			t := 0.0
+			if hi > lo {
+				t = (v - lo) / (hi - lo)
+			}
+			c = lerpColor(heatmapEasy, heatmapHard, t)
+		}
+
+		x0 := (i % columns) * cellSize
+		y0 := (i / columns) * cellSize
+		for y := y0; y < y0+cellSize; y++ {
+			for x := x0; x < x0+cellSize; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+func lerpColor(a, b color.RGBA, t float64) color.RGBA {
+	lerp := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+	return color.RGBA{
+		R: lerp(a.R, b.R),
+		G: lerp(a.G, b.G),
+		B: lerp(a.B, b.B),
+		A: 0xff,
+	}
+}
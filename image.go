@@ -0,0 +1,443 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"iter"
+
+	"github.com/airsigner/qrseq/internal"
+	"github.com/makiuchi-d/gozxing"
+)
+
+// QRCodes generates a slice of QR codes for each chunk in the QRSequence.
+//
+// It takes an integer parameter `blockSize` which specifies the size of the QR
+// code blocks.
+//
+// Returns:
+//   - []image.Image: a slice of QR codes generated for each chunk in the
+//     QRSequence.
+//   - error: an error if the QRSequence is not complete or if there is an error
+//     while generating the QR codes.
+func (s QRSequence) QRCodes(blockSize int) ([]image.Image, error) {
+	return s.QRCodesWithOptions(RenderOptions{BlockSize: blockSize})
+}
+
+// QRCodesWithOptions generates a slice of QR codes for each chunk in the
+// QRSequence, rendered according to the given options.
+//
+// Parameters:
+// - opt: the RenderOptions to render the QR codes with.
+//
+// Returns:
+//   - []image.Image: a slice of QR codes generated for each chunk in the
+//     QRSequence.
+//   - error: an error if the QRSequence is not complete or if there is an error
+//     while generating the QR codes.
+func (s QRSequence) QRCodesWithOptions(opt RenderOptions) ([]image.Image, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]image.Image, 0, len(s.chunks))
+	for i, chunk := range s.chunks {
+		qr, err := opt.render(chunk, i)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, qr)
+	}
+
+	return images, nil
+}
+
+// playFrames renders the frames players show in a loop like
+// QRCodesWithOptions. With a sync marker, a sequence with an odd number of
+// chunks is rendered twice, so the marker toggles where the loop starts over.
+func (s QRSequence) playFrames(opt RenderOptions) ([]image.Image, error) {
+	if !opt.SyncMarker || len(s.chunks)%2 == 0 || !s.IsComplete() {
+		return s.QRCodesWithOptions(opt)
+	}
+
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]image.Image, 0, 2*len(s.chunks))
+	for i := 0; i < 2*len(s.chunks); i++ {
+		qr, err := opt.render(s.chunks[i%len(s.chunks)], i)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, qr)
+	}
+	return images, nil
+}
+
+// DecodeImage decodes an image into a QRSequence.
+//
+// It takes an image.Image as a parameter and attempts to decode it into a
+// QRChunk.
+// If the QRSequence is already complete, it returns nil, unless the
+// CompletionMode asks for frames after completion.
+// If the QRSequence ended with a terminal error, that error is returned.
+// If the decoding is successful, the chunk is added to the QRSequence and nil
+// is returned.
+// If there is an error during decoding, it is returned as a *DecodeError that
+// classifies the failure. Either way the outcome is counted in Stats.
+//
+// Parameters:
+// - img: an image.Image to be decoded into a QRChunk.
+//
+// Returns:
+//   - error: an error if there was an issue decoding the image or if the
+//     QRSequence ended with a terminal error.
+func (s *QRSequence) DecodeImage(img image.Image) error {
+	if s.err != nil {
+		return s.err
+	}
+	if !s.acceptsFrames() {
+		return nil
+	}
+
+	frame, err := s.readFrame(img)
+	if err != nil {
+		return err
+	}
+	return s.receive(frame)
+}
+
+// render renders the QR code of a chunk at the error correction level of its
+// position, stamping it with a sync marker and a watermark strip if the
+// options ask for them.
+func (opt RenderOptions) render(chunk *internal.QRChunk, index int) (image.Image, error) {
+	o := opt.internal()
+	o.ECLevel = opt.ecLevel(chunk.Nr(), chunk.Tot())
+	img, err := chunk.Render(o)
+	if err != nil {
+		return nil, err
+	}
+	return opt.stamp(opt.overlayLogo(img), index), nil
+}
+
+// overlayLogo draws the logo of the options in the middle of a rendered QR
+// code, scaled to fit a light square of a fifth of the width of the code with
+// a margin of one block.
+func (opt RenderOptions) overlayLogo(img image.Image) image.Image {
+	if opt.Logo == nil {
+		return img
+	}
+	bs := opt.internal().BlockSize
+	b := img.Bounds()
+	side := max((b.Dx()-2*bs)/5/bs*bs, 3*bs)
+	logo := opt.Logo.Bounds()
+	if logo.Empty() {
+		return img
+	}
+
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, img, b.Min, draw.Src)
+	box := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+	var light image.Image = image.White
+	if opt.Background != nil {
+		light = image.NewUniform(opt.Background)
+	}
+	draw.Draw(out, box, light, image.Point{}, draw.Src)
+
+	// scale the logo to fit the box without its margin, keeping its aspect
+	inner := side - 2*bs
+	w, h := inner, inner*logo.Dy()/logo.Dx()
+	if logo.Dy() > logo.Dx() {
+		w, h = inner*logo.Dx()/logo.Dy(), inner
+	}
+	dst := image.Rect(0, 0, w, h).Add(box.Min).Add(image.Pt((side-w)/2, (side-h)/2))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := opt.Logo.At(logo.Min.X+x*logo.Dx()/w, logo.Min.Y+y*logo.Dy()/h)
+			out.Set(dst.Min.X+x, dst.Min.Y+y, blend(c))
+		}
+	}
+	return out
+}
+
+// blend composes a possibly translucent color over white.
+func blend(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	light := 0xffff - a
+	return color.RGBA64{R: uint16(r + light), G: uint16(g + light), B: uint16(b + light), A: 0xffff}
+}
+
+// stamp adds the sync marker of the given index and a watermark strip with the
+// index and the current time to a rendered frame if the options ask for them.
+func (opt RenderOptions) stamp(img image.Image, index int) image.Image {
+	if opt.SyncMarker && index%2 == 1 {
+		var dark color.Color = color.Gray{}
+		if opt.Foreground != nil {
+			dark = opt.Foreground
+		}
+		img = addSyncMarker(img, dark, opt.internal().BlockSize)
+	}
+	if !opt.Watermark {
+		return img
+	}
+	clock := opt.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return AddWatermark(img, Watermark{Index: index, Time: clock.Now()}, opt.internal().BlockSize)
+}
+
+// readFrame reads the frame in img within the frame budget, without adding it
+// to the QRSequence. Failures are counted in Stats and analyzed for Guidance.
+func (s *QRSequence) readFrame(img image.Image) ([]byte, error) {
+	frame, rotation, err := s.decodeFrame(func() ([]byte, int, error) {
+		return s.readImage(img)
+	})
+	return s.frameRead(img, frame, rotation, err)
+}
+
+// readImage reads the frame in the QR code of img with the Decoder of the
+// QRSequence and returns it with the rotation of the code. It changes no
+// state, so it may run concurrently with other calls.
+func (s *QRSequence) readImage(img image.Image) ([]byte, int, error) {
+	if s.tearDetection && isTorn(img) {
+		return nil, 0, &DecodeError{Failure: FailureTorn, Err: ErrFrameTorn}
+	}
+	read := internal.ReadImageRotation
+	if s.decoder != nil {
+		read = s.decoder.Decode
+	}
+	text, rotation, err := read(img)
+	if err != nil {
+		return nil, 0, err
+	}
+	frame, err := internal.DecodeText(text)
+	return frame, rotation, err
+}
+
+// frameRead counts a failure to read the frame in img in Stats and analyzes
+// the outcome for Guidance.
+func (s *QRSequence) frameRead(img image.Image, frame []byte, rotation int, err error) ([]byte, error) {
+	if err != nil {
+		decodeErr := newDecodeError(err)
+		s.countFailure(decodeErr)
+		s.guide(img, 0, decodeErr)
+		return nil, decodeErr
+	}
+	s.guide(img, rotation, nil)
+	return frame, nil
+}
+
+// DecodeImage decodes an image into the wrapped QRSequence like
+// QRSequence.DecodeImage. The QR code is read without holding the lock, so
+// frames passed by several goroutines are decoded in parallel.
+//
+// Parameters:
+// - img: an image.Image to be decoded into a QRChunk.
+//
+// Returns:
+//   - error: an error if there was an issue decoding the image or if the
+//     QRSequence ended with a terminal error.
+func (q *SyncSequence) DecodeImage(img image.Image) error {
+	q.mu.Lock()
+	err, accepts := q.seq.err, q.seq.acceptsFrames()
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if !accepts {
+		return nil
+	}
+
+	frame, rotation, err := q.seq.readImage(img)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.seq.err != nil {
+		return q.seq.err
+	}
+	if !q.seq.acceptsFrames() {
+		return nil
+	}
+	frame, err = q.seq.frameRead(img, frame, rotation, err)
+	if err != nil {
+		return err
+	}
+	return q.seq.receive(frame)
+}
+
+// newDecodeError classifies an error returned while decoding a frame.
+func newDecodeError(err error) *DecodeError {
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		return decodeErr
+	}
+
+	var (
+		notFound gozxing.NotFoundException
+		checksum gozxing.ChecksumException
+		format   gozxing.FormatException
+	)
+
+	failure := FailureInvalid
+	switch {
+	case errors.As(err, &notFound):
+		failure = FailureNotFound
+	case errors.As(err, &checksum):
+		failure = FailureChecksum
+	case errors.As(err, &format):
+		failure = FailureFormat
+	}
+	return &DecodeError{Failure: failure, Err: err}
+}
+
+// Images returns an iterator over the QR codes of the QRSequence that renders
+// each frame only when it is reached, instead of materializing all of them
+// like QRCodes.
+//
+// The iterator yields nothing if the QRSequence is not complete and stops
+// early if a frame cannot be rendered.
+//
+// Parameters:
+// - blockSize: the size of the QR code blocks.
+//
+// Returns:
+// - iter.Seq2[int, image.Image]: the iterator over chunk numbers and QR codes.
+func (s QRSequence) Images(blockSize int) iter.Seq2[int, image.Image] {
+	return func(yield func(int, image.Image) bool) {
+		if !s.IsComplete() {
+			return
+		}
+		opt, err := s.renderOptions(RenderOptions{BlockSize: blockSize})
+		if err != nil {
+			return
+		}
+		for i, chunk := range s.chunks {
+			img, err := opt.render(chunk, i)
+			if err != nil {
+				return
+			}
+			if !yield(i, img) {
+				return
+			}
+		}
+	}
+}
+
+// QRCode renders the one-frame pairing QR code this device displays, see
+// Sequence.
+//
+// Parameters:
+// - opt: the RenderOptions to render the QR code with.
+//
+// Returns:
+//   - image.Image: the pairing QR code.
+//   - error: an error if the pairing frame does not fit into one QR code or
+//     there is an error while generating the QR code.
+func (p *Pairing) QRCode(opt RenderOptions) (image.Image, error) {
+	images, err := p.Sequence().QRCodesWithOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) != 1 {
+		return nil, ErrPairingFrameTooLarge
+	}
+	return images[0], nil
+}
+
+// DecodeImage decodes the pairing QR code of the other device and reads it
+// with ReadSequence.
+//
+// Parameters:
+// - img: an image.Image to be decoded.
+//
+// Returns:
+//   - error: an error if the image cannot be decoded or does not hold a
+//     pairing frame.
+func (p *Pairing) DecodeImage(img image.Image) error {
+	seq := NewEmpty()
+	if err := seq.DecodeImage(img); err != nil {
+		return err
+	}
+	return p.ReadSequence(seq)
+}
+
+// NextQRCode renders the QR code of the next frame of the Fountain.
+//
+// Parameters:
+// - opt: the RenderOptions to render the QR code with.
+//
+// Returns:
+//   - image.Image: the QR code of the next frame.
+//   - error: ErrLogoChunkSize if opt has a logo the frames are too large for,
+//     or an error if there is an error while generating the QR code.
+func (f *Fountain) NextQRCode(opt RenderOptions) (image.Image, error) {
+	if err := opt.checkLogo(f.chunkSize, TextBase64); err != nil {
+		return nil, err
+	}
+	index := int(f.next)
+	img, err := internal.RenderText(f.NextPayload(), opt.internal())
+	if err != nil {
+		return nil, err
+	}
+	return opt.stamp(opt.overlayLogo(img), index), nil
+}
+
+// NextQRCode renders the QR code of the next part of the UR.
+//
+// Parameters:
+// - opt: the RenderOptions to render the QR code with.
+//
+// Returns:
+// - image.Image: the QR code of the next part.
+// - error: an error if there is an error while generating the QR code.
+func (e *UREncoder) NextQRCode(opt RenderOptions) (image.Image, error) {
+	img, err := internal.RenderText(e.NextPart(), opt.internal())
+	if err != nil {
+		return nil, err
+	}
+	return opt.overlayLogo(img), nil
+}
+
+// DecodeImage decodes a UR part from an image and adds it to the URDecoder.
+//
+// Parameters:
+// - img: an image.Image to be decoded.
+//
+// Returns:
+//   - error: a *DecodeError if no QR code could be read, or the error of
+//     AddPart.
+func (d *URDecoder) DecodeImage(img image.Image) error {
+	text, err := internal.ReadImage(img)
+	if err != nil {
+		return newDecodeError(err)
+	}
+	return d.AddPart(text)
+}
+
+// DecodeImage decodes an image and routes the frame it carries to the session
+// of its sequence ID, like AddFrame.
+//
+// Parameters:
+// - img: an image.Image to be decoded.
+//
+// Returns:
+//   - *QRSequence: the sequence of the session the frame was routed to, or nil
+//     if it was not routed.
+//   - error: a *DecodeError if no frame could be read, or the error of
+//     AddFrame.
+func (m *SessionManager) DecodeImage(img image.Image) (*QRSequence, error) {
+	text, err := internal.ReadImage(img)
+	if err != nil {
+		return nil, newDecodeError(err)
+	}
+	return m.AddPayload(text)
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// base45Alphabet is the Base45 alphabet of RFC 9285. It is the character set
+// of the QR code alphanumeric mode, which packs two characters into 11 bits
+// instead of the 16 bits byte mode needs.
+const base45Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+var base45Values = func() [256]int8 {
+	var v [256]int8
+	for i := range v {
+		v[i] = -1
+	}
+	for i := 0; i < len(base45Alphabet); i++ {
+		v[base45Alphabet[i]] = int8(i)
+	}
+	return v
+}()
+
+// base45Len returns the length of the Base45 encoding of n bytes.
+func base45Len(n int) int {
+	return n/2*3 + n%2*2
+}
+
+// encodeBase45 encodes data as Base45. Every two bytes become three
+// characters, a trailing byte becomes two.
+func encodeBase45(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(base45Len(len(data)))
+	for i := 0; i < len(data); i += 2 {
+		n, digits := int(data[i]), 2
+		if i+1 < len(data) {
+			n, digits = n<<8|int(data[i+1]), 3
+		}
+		for ; digits > 0; digits-- {
+			sb.WriteByte(base45Alphabet[n%45])
+			n /= 45
+		}
+	}
+	return sb.String()
+}
+
+// decodeBase45 decodes Base45, rejecting characters outside the alphabet and
+// groups whose value does not fit in the bytes they encode.
+func decodeBase45(s string) ([]byte, error) {
+	if len(s)%3 == 1 {
+		return nil, fmt.Errorf("%w: length", ErrInvalidBase45)
+	}
+
+	data := make([]byte, 0, len(s)/3*2+1)
+	for i := 0; i < len(s); i += 3 {
+		group := s[i:min(i+3, len(s))]
+		n, weight := 0, 1
+		for j := 0; j < len(group); j++ {
+			v := base45Values[group[j]]
+			if v < 0 {
+				return nil, fmt.Errorf("%w: character", ErrInvalidBase45)
+			}
+			n += int(v) * weight
+			weight *= 45
+		}
+		if len(group) == 3 {
+			if n > 0xffff {
+				return nil, fmt.Errorf("%w: group", ErrInvalidBase45)
+			}
+			data = append(data, byte(n>>8), byte(n))
+		} else {
+			if n > 0xff {
+				return nil, fmt.Errorf("%w: group", ErrInvalidBase45)
+			}
+			data = append(data, byte(n))
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"image"
+	"image/color"
+)
+
+// Degrade simulates a camera capture of img.
+//
+// It converts the image to grayscale, rescales it by the given factor using
+// supersampled area averaging and finally applies a box blur with the given
+// radius. A factor above 1 downscales the image, a factor below 1 upscales it
+// and a radius of 0 disables the blur.
+//
+// Parameters:
+//   - img: the image to degrade.
+//   - factor: the downscale factor, e.g. 2 halves the width and the height.
+//   - radius: the radius of the box blur in pixels of the rescaled image.
+//
+// Returns:
+//   - *image.Gray: the degraded image.
+func Degrade(img image.Image, factor float64, radius int) *image.Gray {
+	if factor <= 0 {
+		factor = 1
+	}
+	b := img.Bounds()
+	w := max(1, int(float64(b.Dx())/factor))
+	h := max(1, int(float64(b.Dy())/factor))
+
+	const samples = 4
+	out := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					px := b.Min.X + int((float64(x)+(float64(sx)+0.5)/samples)*factor)
+					py := b.Min.Y + int((float64(y)+(float64(sy)+0.5)/samples)*factor)
+					sum += int(color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y)
+				}
+			}
+			out.Pix[out.PixOffset(x, y)] = uint8(sum / (samples * samples))
+		}
+	}
+
+	if radius > 0 {
+		boxBlur(out, radius)
+	}
+	return out
+}
+
+// boxBlur blurs img in place with a separable box filter of the given radius.
+// Pixels outside the image are clamped to the nearest edge pixel.
+func boxBlur(img *image.Gray, radius int) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	tmp := make([]uint8, len(img.Pix))
+	size := 2*radius + 1
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum := 0
+			for k := -radius; k <= radius; k++ {
+				sx := min(max(x+k, 0), w-1)
+				sum += int(img.Pix[y*img.Stride+sx])
+			}
+			tmp[y*img.Stride+x] = uint8(sum / size)
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum := 0
+			for k := -radius; k <= radius; k++ {
+				sy := min(max(y+k, 0), h-1)
+				sum += int(tmp[sy*img.Stride+x])
+			}
+			img.Pix[y*img.Stride+x] = uint8(sum / size)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package internal
+
+import "errors"
+
+// Errors exported by the qrseq package, defined here so frames can be
+// rejected with them.
+var (
+	ErrInvalidChunk          = errors.New("invalid chunk")
+	ErrInvalidChunkSize      = errors.New("invalid chunk size")
+	ErrPayloadTooLarge       = errors.New("payload too large")
+	ErrInvalidBlockSize      = errors.New("invalid block size")
+	ErrInvalidBase45         = errors.New("invalid base45")
+	ErrInvalidUR             = errors.New("invalid ur")
+	ErrInvalidFragmentLength = errors.New("invalid fragment length")
+)
+
+// EncodeError is returned by the rendering functions if the QR code encoder
+// fails, e.g. because the text does not fit into a QR code. It wraps the
+// error of the encoder.
+type EncodeError struct {
+	Err error
+}
+
+func (e *EncodeError) Error() string {
+	return "cannot encode QR code: " + e.Err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,424 @@
+package internal
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"sort"
+)
+
+// A fountain frame consists of the marker, the frame type, the chunk size
+// (uint16), the number of source blocks (uint16), the payload length (uint32),
+// the seed of the frame, the ID of the sequence and a CRC-32 (IEEE) of the
+// rest of the frame (uint32 each), all little endian, followed by one encoded
+// block filling the rest of the chunk size.
+//
+// A parity frame has the same layout with the ParityFrame type, but in place
+// of the seed the number of the first source block it combines and the number
+// of source blocks (uint16 each), so the blocks can be chosen by the sender.
+//
+// Frames of the TaggedFountainFrame and TaggedParityFrame types carry the
+// application tag (uint16) between the CRC and the encoded block.
+const (
+	fountainCRCOffset  = 18
+	fountainHeaderSize = fountainCRCOffset + crcSize
+)
+
+// FountainHeader describes a fountain frame.
+type FountainHeader struct {
+	ChunkSize uint16
+	Blocks    int    // number of source blocks
+	Length    int    // payload length in bytes
+	Seed      uint32 // selects the source blocks combined in the frame
+	SeqID     uint32 // the random ID of the sequence
+	AppTag    uint16 // the application tag, or zero if the frame has none
+
+	// Parity marks a parity frame, which combines the Count source blocks
+	// starting at First instead of those selected by the seed.
+	Parity bool
+	First  int
+	Count  int
+}
+
+// headerSize returns the size of the header of a frame, including the
+// application tag.
+func (h FountainHeader) headerSize() int {
+	if h.AppTag != 0 {
+		return fountainHeaderSize + appTagSize
+	}
+	return fountainHeaderSize
+}
+
+// blockSize returns the number of payload bytes of a source block.
+func (h FountainHeader) blockSize() int {
+	return int(h.ChunkSize) - h.headerSize()
+}
+
+// frameType returns the type of the frames of the header, a parity frame if
+// parity is set.
+func (h FountainHeader) frameType(parity bool) byte {
+	switch {
+	case parity && h.AppTag != 0:
+		return TaggedParityFrame
+	case parity:
+		return ParityFrame
+	case h.AppTag != 0:
+		return TaggedFountainFrame
+	}
+	return FountainFrame
+}
+
+// IsFountainFrame reports whether frame is a fountain or parity frame.
+func IsFountainFrame(frame []byte) bool {
+	return FrameLayout(frame) == LayoutFountain
+}
+
+// ParseFountainFrame parses a fountain frame into its header and encoded block.
+//
+// Parameters:
+// - frame: the bytes of the frame.
+//
+// Returns:
+//   - FountainHeader: the header of the frame.
+//   - []byte: the encoded block.
+//   - error: a *CRCError if the CRC does not match, or an error if the frame is
+//     not a valid fountain frame.
+func ParseFountainFrame(frame []byte) (FountainHeader, []byte, error) {
+	if !IsFountainFrame(frame) || len(frame) < fountainHeaderSize {
+		return FountainHeader{}, nil, ErrInvalidChunk
+	}
+
+	h := FountainHeader{
+		ChunkSize: binary.LittleEndian.Uint16(frame[2:4]),
+		Blocks:    int(binary.LittleEndian.Uint16(frame[4:6])),
+		Length:    int(binary.LittleEndian.Uint32(frame[6:10])),
+		Seed:      binary.LittleEndian.Uint32(frame[10:14]),
+		SeqID:     binary.LittleEndian.Uint32(frame[14:18]),
+	}
+	if want, got := binary.LittleEndian.Uint32(frame[fountainCRCOffset:]), fountainCRC(frame); got != want {
+		return FountainHeader{}, nil, &CRCError{Nr: int(h.Seed), Want: want, Got: got}
+	}
+	if frame[1] == TaggedFountainFrame || frame[1] == TaggedParityFrame {
+		if len(frame) < fountainHeaderSize+appTagSize {
+			return FountainHeader{}, nil, ErrInvalidChunk
+		}
+		if h.AppTag = binary.LittleEndian.Uint16(frame[fountainHeaderSize:]); h.AppTag == 0 {
+			return FountainHeader{}, nil, ErrInvalidChunk
+		}
+	}
+	if frame[1] == ParityFrame || frame[1] == TaggedParityFrame {
+		h.Seed = 0
+		h.Parity = true
+		h.First = int(binary.LittleEndian.Uint16(frame[10:12]))
+		h.Count = int(binary.LittleEndian.Uint16(frame[12:14]))
+	}
+	if !IsValidChunkSize(h.ChunkSize) || h.Blocks < 1 || h.Blocks > MaxChunks {
+		return FountainHeader{}, nil, ErrInvalidChunk
+	}
+	if h.Parity && (h.Count < 1 || h.First+h.Count > h.Blocks) {
+		return FountainHeader{}, nil, ErrInvalidChunk
+	}
+	bs := h.blockSize()
+	if bs < 1 || len(frame) != int(h.ChunkSize) || h.Length > h.Blocks*bs || h.Length < (h.Blocks-1)*bs {
+		return FountainHeader{}, nil, ErrInvalidChunk
+	}
+	return h, frame[h.headerSize():], nil
+}
+
+// fountainCRC returns the CRC of a fountain frame, computed over the frame
+// without the CRC field.
+func fountainCRC(frame []byte) uint32 {
+	crc := crc32.ChecksumIEEE(frame[:fountainCRCOffset])
+	return crc32.Update(crc, crc32.IEEETable, frame[fountainHeaderSize:])
+}
+
+// FountainEncoder produces an endless stream of LT coded frames of a payload.
+type FountainEncoder struct {
+	header FountainHeader
+	blocks [][]byte
+	cdf    []float64
+}
+
+// NewFountainEncoder splits data into source blocks that fill fountain frames
+// of the given chunk size.
+//
+// Parameters:
+// - data: the payload to encode.
+// - chunkSize: the size of every frame in bytes.
+// - seqID: the ID of the sequence, carried by every frame.
+// - appTag: the application tag carried by every frame, or zero for none.
+//
+// Returns:
+//   - *FountainEncoder: the new encoder.
+//   - error: an error if the chunk size is invalid or the payload needs more
+//     than the 65535 source blocks the header can describe.
+func NewFountainEncoder(data []byte, chunkSize uint16, seqID uint32, appTag uint16) (*FountainEncoder, error) {
+	if !IsValidChunkSize(chunkSize) {
+		return nil, ErrInvalidChunkSize
+	}
+
+	h := FountainHeader{ChunkSize: chunkSize, Length: len(data), SeqID: seqID, AppTag: appTag}
+	bs := h.blockSize()
+	h.Blocks = max(1, (len(data)+bs-1)/bs)
+	if h.Blocks > math.MaxUint16 {
+		return nil, ErrPayloadTooLarge
+	}
+
+	blocks := make([][]byte, h.Blocks)
+	for i := range blocks {
+		blocks[i] = make([]byte, bs)
+		copy(blocks[i], data[min(i*bs, len(data)):])
+	}
+	return &FountainEncoder{header: h, blocks: blocks, cdf: solitonCDF(h.Blocks)}, nil
+}
+
+// Blocks returns the number of source blocks of the payload.
+func (e *FountainEncoder) Blocks() int {
+	return e.header.Blocks
+}
+
+// Frame returns the fountain frame with the given seed.
+//
+// The first frames, whose seed is lower than the number of source blocks,
+// carry one source block each. All later frames combine a random set of
+// source blocks selected by the seed.
+func (e *FountainEncoder) Frame(seed uint32) []byte {
+	frame := make([]byte, e.header.ChunkSize)
+	frame[0] = ExtendedMarker
+	frame[1] = e.header.frameType(false)
+	binary.LittleEndian.PutUint16(frame[2:4], e.header.ChunkSize)
+	binary.LittleEndian.PutUint16(frame[4:6], uint16(e.header.Blocks))
+	binary.LittleEndian.PutUint32(frame[6:10], uint32(e.header.Length))
+	binary.LittleEndian.PutUint32(frame[10:14], seed)
+
+	block := frame[e.header.headerSize():]
+	for _, i := range fountainIndices(seed, e.header.Blocks, e.cdf) {
+		xorInto(block, e.blocks[i])
+	}
+	return e.seal(frame)
+}
+
+// ParityFrame returns the parity frame combining the count source blocks
+// starting at first, which lets a receiver recover any one of them from the
+// others.
+func (e *FountainEncoder) ParityFrame(first, count int) []byte {
+	frame := make([]byte, e.header.ChunkSize)
+	frame[0] = ExtendedMarker
+	frame[1] = e.header.frameType(true)
+	binary.LittleEndian.PutUint16(frame[2:4], e.header.ChunkSize)
+	binary.LittleEndian.PutUint16(frame[4:6], uint16(e.header.Blocks))
+	binary.LittleEndian.PutUint32(frame[6:10], uint32(e.header.Length))
+	binary.LittleEndian.PutUint16(frame[10:12], uint16(first))
+	binary.LittleEndian.PutUint16(frame[12:14], uint16(count))
+
+	block := frame[e.header.headerSize():]
+	for i := first; i < first+count; i++ {
+		xorInto(block, e.blocks[i])
+	}
+	return e.seal(frame)
+}
+
+// seal sets the sequence ID, the application tag and the CRC of a frame.
+func (e *FountainEncoder) seal(frame []byte) []byte {
+	binary.LittleEndian.PutUint32(frame[14:18], e.header.SeqID)
+	if e.header.AppTag != 0 {
+		binary.LittleEndian.PutUint16(frame[fountainHeaderSize:], e.header.AppTag)
+	}
+	binary.LittleEndian.PutUint32(frame[fountainCRCOffset:], fountainCRC(frame))
+	return frame
+}
+
+// FountainDecoder reconstructs a payload from fountain frames by peeling: every
+// frame that combines a single unknown source block reveals it, which may in
+// turn reduce other frames to a single unknown block.
+type FountainDecoder struct {
+	header  FountainHeader
+	cdf     []float64
+	blocks  [][]byte
+	pending []*fountainSymbol
+	seen    map[uint64]bool // seeds, and first and count of parity frames
+}
+
+type fountainSymbol struct {
+	indices []int
+	data    []byte
+}
+
+// NewFountainDecoder creates a decoder for the sequence of the given frame
+// header.
+func NewFountainDecoder(h FountainHeader) *FountainDecoder {
+	return &FountainDecoder{
+		header: h,
+		cdf:    solitonCDF(h.Blocks),
+		blocks: make([][]byte, h.Blocks),
+		seen:   make(map[uint64]bool),
+	}
+}
+
+// Header returns the header of the sequence of the decoder, as given by its
+// first frame.
+func (d *FountainDecoder) Header() FountainHeader {
+	return d.header
+}
+
+// Seen reports whether a frame with the same encoded block has been added.
+func (d *FountainDecoder) Seen(h FountainHeader) bool {
+	return d.seen[h.key()]
+}
+
+// key identifies the encoded block of a frame: the seed of a data frame, the
+// group of a parity frame.
+func (h FountainHeader) key() uint64 {
+	if h.Parity {
+		return 1<<32 | uint64(h.First)<<16 | uint64(h.Count)
+	}
+	return uint64(h.Seed)
+}
+
+// Add adds the encoded block of a frame and returns the numbers of the source
+// blocks that could be recovered with it.
+func (d *FountainDecoder) Add(h FountainHeader, block []byte) []int {
+	key := h.key()
+	var indices []int
+	if h.Parity {
+		for i := h.First; i < h.First+h.Count; i++ {
+			indices = append(indices, i)
+		}
+	} else {
+		indices = fountainIndices(h.Seed, d.header.Blocks, d.cdf)
+	}
+	if d.seen[key] {
+		return nil
+	}
+	d.seen[key] = true
+
+	d.pending = append(d.pending, &fountainSymbol{
+		indices: indices,
+		data:    append([]byte(nil), block...),
+	})
+
+	var recovered []int
+	for progress := true; progress; {
+		progress = false
+		pending := d.pending[:0]
+		for _, sym := range d.pending {
+			d.reduce(sym)
+			switch len(sym.indices) {
+			case 0:
+				continue
+			case 1:
+				d.blocks[sym.indices[0]] = sym.data
+				recovered = append(recovered, sym.indices[0])
+				progress = true
+				continue
+			}
+			pending = append(pending, sym)
+		}
+		d.pending = pending
+	}
+	sort.Ints(recovered)
+	return recovered
+}
+
+// reduce removes all recovered source blocks from sym.
+func (d *FountainDecoder) reduce(sym *fountainSymbol) {
+	indices := sym.indices[:0]
+	for _, i := range sym.indices {
+		if d.blocks[i] != nil {
+			xorInto(sym.data, d.blocks[i])
+			continue
+		}
+		indices = append(indices, i)
+	}
+	sym.indices = indices
+}
+
+// Chunk returns the recovered source block nr as a chunk of the sequence, with
+// the padding of the last block removed.
+func (d *FountainDecoder) Chunk(nr int) *QRChunk {
+	bs := d.header.blockSize()
+	data := d.blocks[nr]
+	if end := d.header.Length - nr*bs; end < bs {
+		data = data[:end]
+	}
+	return newChunk(nr, d.header.Blocks, d.header.ChunkSize, data)
+}
+
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// fountainIndices returns the source blocks combined in the frame with the
+// given seed. Encoder and decoder derive them identically from the seed.
+func fountainIndices(seed uint32, k int, cdf []float64) []int {
+	if int64(seed) < int64(k) {
+		return []int{int(seed)}
+	}
+
+	rng := splitMix64(uint64(seed))
+	u := float64(rng.next()>>11) / (1 << 53)
+	degree := sort.SearchFloat64s(cdf, u) + 1
+	degree = min(degree, k)
+
+	// partial Fisher-Yates shuffle picking degree distinct blocks
+	perm := make([]int, k)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := 0; i < degree; i++ {
+		j := i + int(rng.next()%uint64(k-i))
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm[:degree]
+}
+
+// solitonCDF returns the cumulative robust soliton distribution of the degrees
+// 1 to k, where cdf[d-1] is the probability of a degree of at most d.
+func solitonCDF(k int) []float64 {
+	const c, delta = 0.1, 0.5
+
+	r := c * math.Log(float64(k)/delta) * math.Sqrt(float64(k))
+	spike := int(float64(k) / r)
+
+	p := make([]float64, k)
+	for d := 1; d <= k; d++ {
+		if d == 1 {
+			p[d-1] = 1 / float64(k)
+		} else {
+			p[d-1] = 1 / (float64(d) * float64(d-1))
+		}
+		switch {
+		case d < spike:
+			p[d-1] += r / (float64(d) * float64(k))
+		case d == spike:
+			p[d-1] += max(0, r*math.Log(r/delta)/float64(k))
+		}
+	}
+
+	var sum float64
+	for _, v := range p {
+		sum += v
+	}
+	cdf := make([]float64, k)
+	var acc float64
+	for i, v := range p {
+		acc += v / sum
+		cdf[i] = acc
+	}
+	cdf[k-1] = 1
+	return cdf
+}
+
+// splitMix64 is a small deterministic PRNG, so fountain frames decode the same
+// regardless of the Go version.
+type splitMix64 uint64
+
+func (s *splitMix64) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
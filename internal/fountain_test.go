@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestFountainDecodesWithFrameLoss(t *testing.T) {
+	data := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	for _, tc := range []struct {
+		name string
+		loss float64
+	}{
+		{name: "no loss", loss: 0},
+		{name: "light loss", loss: 0.2},
+		{name: "heavy loss", loss: 0.6},
+	} {
+		enc, err := NewFountainEncoder(data, 256, 7, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rng := rand.New(rand.NewSource(2))
+
+		var dec *FountainDecoder
+		recovered := make(map[int]bool)
+		frames := 0
+		for seed := uint32(0); len(recovered) < enc.Blocks(); seed++ {
+			if seed > uint32(20*enc.Blocks()) {
+				t.Fatalf("%s: %d of %d blocks after %d frames", tc.name, len(recovered), enc.Blocks(), frames)
+			}
+			if rng.Float64() < tc.loss {
+				continue
+			}
+			frames++
+			h, block, err := ParseFountainFrame(enc.Frame(seed))
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			if dec == nil {
+				dec = NewFountainDecoder(h)
+			}
+			for _, nr := range dec.Add(h, block) {
+				recovered[nr] = true
+			}
+		}
+
+		chunks := make([]*QRChunk, enc.Blocks())
+		for nr := range chunks {
+			chunks[nr] = dec.Chunk(nr)
+		}
+		if !bytes.Equal(GetData(chunks), data) {
+			t.Errorf("%s: decoded payload differs", tc.name)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package internal
+
+// Extended frames start with ExtendedMarker, which is never a valid chunk
+// number of a legacy chunk, followed by the frame type.
+const (
+	ExtendedMarker = 0xff
+	FountainFrame  = 0x01
+	ChunkFrameV2   = 0x02
+	ParityFrame    = 0x03
+
+	TaggedFountainFrame = 0x04
+	TaggedParityFrame   = 0x05
+)
+
+// Frame layouts, ordered by the version of the framing that introduced them. A
+// receiver locked into a layout re-baselines when a frame of a newer layout
+// appears.
+const (
+	LayoutUnknown uint8 = iota
+	LayoutLegacy
+	LayoutFountain
+	LayoutV2
+)
+
+// FrameLayout returns the layout of a decoded frame, or LayoutUnknown for
+// extended frames of an unknown type.
+func FrameLayout(frame []byte) uint8 {
+	if len(frame) == 0 || frame[0] != ExtendedMarker {
+		return LayoutLegacy
+	}
+	if len(frame) < 2 {
+		return LayoutUnknown
+	}
+	switch frame[1] {
+	case FountainFrame, ParityFrame, TaggedFountainFrame, TaggedParityFrame:
+		return LayoutFountain
+	case ChunkFrameV2:
+		return LayoutV2
+	}
+	return LayoutUnknown
+}
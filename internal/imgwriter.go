@@ -1,28 +1,91 @@
+//go:build !core
+
 package internal
 
 import (
 	"image"
 	"image/color"
+	"math"
 
 	"github.com/yeqown/go-qrcode/v2"
 )
 
-type Option struct {
-	Padding   int
-	BlockSize int
-}
-
 type imgWriter struct {
 	img      image.Image
 	option   *Option
 	callback func(image.Image)
 }
 
-var (
-	backgroundColor = color.White
-	foregroundColor = color.Black
+// Positions of the colors in the palette of the rendered image.
+const (
+	backgroundIndex uint8 = iota
+	foregroundIndex
+	lightEdgeIndex
+	darkEdgeIndex
 )
 
+// colors returns the palette of the rendered image with the contrast and gamma
+// compensation of the Option applied. Colors are computed per render call
+// from the Option, so concurrent renders with different colors do not
+// interfere.
+func (o *Option) colors() color.Palette {
+	bg, fg := o.Background, o.Foreground
+	if bg == nil {
+		bg = color.White
+	}
+	if fg == nil {
+		fg = color.Black
+	}
+	colors := [][3]float64{channels(bg), channels(fg)}
+	if o.Palette == PaletteGray4 {
+		// The edge levels are symmetric around the middle between the module
+		// colors, so any binarizer thresholding between the two still sees
+		// the module colors unchanged.
+		colors = append(colors, mix(colors[0], colors[1], 1.0/3), mix(colors[0], colors[1], 2.0/3))
+	}
+
+	palette := make(color.Palette, 0, len(colors))
+	for _, c := range colors {
+		var rgb [3]uint8
+		for i, y := range c {
+			if o.Contrast > 0 && o.Contrast < 1 {
+				y = 0.5 + (y-0.5)*o.Contrast
+			}
+			if o.Gamma > 0 {
+				y = math.Pow(y, 1/o.Gamma)
+			}
+			rgb[i] = uint8(math.Round(y * 0xff))
+		}
+		if rgb[0] == rgb[1] && rgb[1] == rgb[2] {
+			palette = append(palette, color.Gray{Y: rgb[0]})
+		} else {
+			palette = append(palette, color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff})
+		}
+	}
+	return palette
+}
+
+// channels returns the red, green and blue channels of an opaque color
+// between 0 and 1. Neutral colors are converted like color.GrayModel does,
+// so gray palettes render exactly as before colors were configurable.
+func channels(c color.Color) [3]float64 {
+	r, g, b, _ := c.RGBA()
+	if r == g && g == b {
+		y := float64(color.GrayModel.Convert(c).(color.Gray).Y) / 0xff
+		return [3]float64{y, y, y}
+	}
+	return [3]float64{float64(r>>8) / 0xff, float64(g>>8) / 0xff, float64(b>>8) / 0xff}
+}
+
+// mix returns the color at fraction t of the way from a to b.
+func mix(a, b [3]float64, t float64) [3]float64 {
+	var c [3]float64
+	for i := range c {
+		c[i] = a[i] + (b[i]-a[i])*t
+	}
+	return c
+}
+
 // NewImageWriter creates a new instance of the imgWriter struct and returns
 // it as a qrcode.Writer.
 //
@@ -47,14 +110,16 @@ func NewImageWriter(callback func(image.Image), opt *Option) qrcode.Writer {
 // The function calculates the width and height of the image based on the matrix
 // size and padding.
 // It creates a new image.Paletted with the calculated dimensions and a palette
-// containing the background and foreground colors.
-// It calculates the indices of the background and foreground colors in the
-// image's palette.
+// containing the background and foreground colors, compensated for the gamma
+// and contrast of the output device.
 // It defines a helper function rectangle that sets the color of a rectangular
 // area in the image.
 // It sets the background color of the entire image.
 // It iterates over the matrix and sets the foreground color of the non-zero
 // values.
+// With a dot gain, it shrinks the dark modules where they border light ones.
+// With PaletteGray4 and a block size of at least 3 pixels, it then softens the
+// edges between dark and light modules.
 // It sets the image in the imgWriter struct and returns nil.
 func (w *imgWriter) Write(mat qrcode.Matrix) error {
 	padding := w.option.Padding
@@ -62,12 +127,9 @@ func (w *imgWriter) Write(mat qrcode.Matrix) error {
 	width := mat.Width()*blockWidth + 2*padding
 	height := width
 
-	img := image.NewPaletted(
-		image.Rect(0, 0, width, height),
-		[]color.Color{backgroundColor, foregroundColor},
-	)
-	bgColor := uint8(img.Palette.Index(backgroundColor))
-	fgColor := uint8(img.Palette.Index(foregroundColor))
+	img := image.NewPaletted(image.Rect(0, 0, width, height), w.option.colors())
+	bgColor := backgroundIndex
+	fgColor := foregroundIndex
 
 	rectangle := func(x1, y1 int, x2, y2 int, img *image.Paletted, color uint8) {
 		for x := x1; x < x2; x++ {
@@ -91,10 +153,104 @@ func (w *imgWriter) Write(mat qrcode.Matrix) error {
 		}
 	})
 
+	if w.option.DotGain > 0 {
+		compensateDotGain(img, mat.Bitmap(), padding, blockWidth, w.option.DotGain)
+	}
+	if w.option.Palette == PaletteGray4 && blockWidth >= 3 {
+		softenEdges(img, mat.Bitmap(), padding, blockWidth)
+	}
+
 	w.img = img
 	return nil
 }
 
+// softenEdges redraws the outermost pixel row or column of every module that
+// borders a module of the opposite color: dark modules get the dark edge
+// level, light modules the light edge level. The quiet zone counts as light.
+func softenEdges(img *image.Paletted, bitmap [][]bool, padding, blockWidth int) {
+	isSet := bitmapLookup(bitmap)
+	for y, row := range bitmap {
+		for x, set := range row {
+			edge := lightEdgeIndex
+			if set {
+				edge = darkEdgeIndex
+			}
+
+			sx := x*blockWidth + padding
+			sy := y*blockWidth + padding
+			ex := sx + blockWidth - 1
+			ey := sy + blockWidth - 1
+			for i := 0; i < blockWidth; i++ {
+				if isSet(x-1, y) != set {
+					img.Pix[img.PixOffset(sx, sy+i)] = edge
+				}
+				if isSet(x+1, y) != set {
+					img.Pix[img.PixOffset(ex, sy+i)] = edge
+				}
+				if isSet(x, y-1) != set {
+					img.Pix[img.PixOffset(sx+i, sy)] = edge
+				}
+				if isSet(x, y+1) != set {
+					img.Pix[img.PixOffset(sx+i, ey)] = edge
+				}
+			}
+		}
+	}
+}
+
+// compensateDotGain paints the outer `gain` pixels of every dark module side
+// that borders a light module with the background color, so the ink spread of
+// a printer grows the module back to its nominal size. At least one pixel of
+// every module is kept.
+func compensateDotGain(img *image.Paletted, bitmap [][]bool, padding, blockWidth, gain int) {
+	gain = min(gain, (blockWidth-1)/2)
+	if gain < 1 {
+		return
+	}
+
+	isSet := bitmapLookup(bitmap)
+	for y, row := range bitmap {
+		for x, set := range row {
+			if !set {
+				continue
+			}
+
+			sx := x*blockWidth + padding
+			sy := y*blockWidth + padding
+			ex := sx + blockWidth
+			ey := sy + blockWidth
+			for i := 0; i < blockWidth; i++ {
+				for g := 0; g < gain; g++ {
+					if !isSet(x-1, y) {
+						img.Pix[img.PixOffset(sx+g, sy+i)] = backgroundIndex
+					}
+					if !isSet(x+1, y) {
+						img.Pix[img.PixOffset(ex-1-g, sy+i)] = backgroundIndex
+					}
+					if !isSet(x, y-1) {
+						img.Pix[img.PixOffset(sx+i, sy+g)] = backgroundIndex
+					}
+					if !isSet(x, y+1) {
+						img.Pix[img.PixOffset(sx+i, ey-1-g)] = backgroundIndex
+					}
+				}
+			}
+		}
+	}
+}
+
+// bitmapLookup returns a function reporting whether the module at x, y of the
+// bitmap is dark. Modules outside the bitmap belong to the quiet zone and are
+// light.
+func bitmapLookup(bitmap [][]bool) func(x, y int) bool {
+	return func(x, y int) bool {
+		if y < 0 || y >= len(bitmap) || x < 0 || x >= len(bitmap[y]) {
+			return false
+		}
+		return bitmap[y][x]
+	}
+}
+
 // Close closes the imgWriter and invokes the callback function with the image.
 //
 // It does not return any value.
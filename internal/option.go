@@ -0,0 +1,67 @@
+package internal
+
+import "image/color"
+
+type Option struct {
+	Padding   int
+	BlockSize int
+	Palette   Palette
+	// Background and Foreground are the colors of the light and dark
+	// modules. Nil means white and black.
+	Background color.Color
+	Foreground color.Color
+
+	// Gamma is the gamma of the output device. The gray levels are
+	// pre-compensated with its inverse. Zero means no correction.
+	Gamma float64
+	// Contrast scales the distance of the gray levels to mid gray, between 0
+	// (exclusive) and 1. Zero means full contrast.
+	Contrast float64
+	// DotGain is the number of pixels by which dark modules spread on the
+	// output device. Dark modules are shrunk by that amount wherever they
+	// border a light module.
+	DotGain int
+	// ECLevel is the error correction level of the QR code. Zero means the
+	// default level, ECLevelQuartile.
+	ECLevel ECLevel
+	// TextEncoding is the encoding of the text of the QR code of a chunk.
+	TextEncoding TextEncoding
+}
+
+// TextEncoding selects how the bytes of a frame are encoded into the text of
+// its QR code.
+type TextEncoding uint8
+
+const (
+	// TextBase64 encodes frames as base64, which QR codes hold in byte mode.
+	TextBase64 TextEncoding = iota
+	// TextBase45 encodes frames as Base45 behind base45Prefix, which QR codes
+	// hold in the denser alphanumeric mode.
+	TextBase45
+	// TextRaw puts the bytes of extended frames into the QR code as they
+	// are, in byte mode. Legacy chunks fall back to base64.
+	TextRaw
+)
+
+// ECLevel is the error correction level of a QR code.
+type ECLevel uint8
+
+const (
+	ECLevelDefault ECLevel = iota
+	ECLevelLow
+	ECLevelMedium
+	ECLevelQuartile
+	ECLevelHigh
+)
+
+// Palette selects the gray levels used to render the QR code modules.
+type Palette uint8
+
+const (
+	// PaletteMono renders pure black modules on a pure white background.
+	PaletteMono Palette = iota
+	// PaletteGray4 renders black and white module cores, but draws the one
+	// pixel wide edges between dark and light modules in two intermediate
+	// gray levels to soften hard transitions.
+	PaletteGray4
+)
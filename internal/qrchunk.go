@@ -2,14 +2,13 @@ package internal
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
-	"errors"
-	"image"
-
-	"github.com/makiuchi-d/gozxing"
-	qrzxing "github.com/makiuchi-d/gozxing/qrcode"
-	"github.com/yeqown/go-qrcode/v2"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
 )
 
 const (
@@ -19,145 +18,490 @@ const (
 	ChunkSize256  uint16 = 256
 	ChunkSize512  uint16 = 512
 	ChunkSize1024 uint16 = 1024
+	ChunkSize2048 uint16 = 2048
 )
 
-func isValidChunkSize(cs uint16) bool {
+// MaxChunks is the largest number of chunks a sequence can have. The v2
+// header has room for 32 bit counters, receivers limit them to keep the
+// memory a single frame can make them allocate bounded. Legacy chunks are
+// limited to 255 chunks by their 8 bit counters.
+const MaxChunks = 1 << 16
+
+// headerSize is the number of bytes of a legacy chunk taken by the header.
+const headerSize = 4
+
+// A v2 chunk starts with ExtendedMarker, ChunkFrameV2 and a flags byte for
+// optional fields, followed by the chunk size (uint16), the chunk number and
+// the total number of chunks (uint32 each), all little endian. The optional
+// fields flagged follow the header in the order of their flags.
+const headerSizeV2 = 13
+
+// Flags of the optional fields of a v2 chunk.
+const (
+	// FlagCRC marks a CRC-32 (IEEE) of the header, the other optional fields
+	// and the data, little endian.
+	FlagCRC uint8 = 1 << iota
+	// FlagDigest marks the SHA-256 digest of the whole payload, carried by
+	// chunk 0.
+	FlagDigest
+	// FlagSeqID marks the random ID of the sequence (uint32, little endian),
+	// which tells apart chunks of different transfers.
+	FlagSeqID
+	// FlagLength marks the length of the whole payload in bytes (uint32,
+	// little endian), carried by chunk 0.
+	FlagLength
+	// FlagEncoding marks the encodings applied to the payload before
+	// chunking (uint8), carried by chunk 0.
+	FlagEncoding
+	// FlagAppTag marks the tag of the application that sent the sequence
+	// (uint16, little endian), which lets applications ignore the sequences
+	// of others.
+	FlagAppTag
+)
+
+// knownFlags are the flags of all optional fields this version can parse.
+const knownFlags = FlagCRC | FlagDigest | FlagSeqID | FlagLength | FlagEncoding | FlagAppTag
+
+// Sizes of the optional fields.
+const (
+	crcSize      = 4
+	digestSize   = sha256.Size
+	seqIDSize    = 4
+	lengthSize   = 4
+	encodingSize = 1
+	appTagSize   = 2
+)
+
+// Encodings of the payload, combined in the encoding field of chunk 0.
+const (
+	// EncodingGzip marks a gzip compressed payload.
+	EncodingGzip uint8 = 1 << iota
+	// EncodingAESGCM marks a payload encrypted with AES-256-GCM, after it has
+	// been compressed.
+	EncodingAESGCM
+	// EncodingPadding marks a payload padded ISO/IEC 7816-4 style, after all
+	// other encodings.
+	EncodingPadding
+	// EncodingSigned marks a payload followed by its Ed25519 signature,
+	// before all other encodings.
+	EncodingSigned
+)
+
+// DataSize returns the number of payload bytes a full chunk of size cs created
+// by CreateChunks carries. Chunk 0 carries fewer, see Capacity.
+func DataSize(cs uint16) int {
+	return int(cs) - headerSizeV2 - crcSize - seqIDSize
+}
+
+// Capacity returns the number of payload bytes a sequence of the given number
+// of chunks of size cs created by CreateChunks with the given encoding and
+// appTag holds.
+//
+// Chunk 0 carries the length, the digest and the encoding of the payload in
+// place of payload bytes. The digest is left out if the chunk size is too
+// small to hold it. Every chunk carries the appTag, if not zero.
+func Capacity(cs uint16, chunks int, encoding uint8, appTag uint16) int {
+	if chunks == 0 {
+		return 0
+	}
+	return chunks*chunkDataSize(cs, appTag) - chunk0Overhead(cs, encoding)
+}
+
+// chunkDataSize returns the number of payload bytes a full chunk of size cs
+// created by CreateChunks with the given appTag carries.
+func chunkDataSize(cs uint16, appTag uint16) int {
+	if appTag != 0 {
+		return DataSize(cs) - appTagSize
+	}
+	return DataSize(cs)
+}
+
+// hasDigest reports whether chunk 0 of a sequence of chunk size cs created by
+// CreateChunks carries the digest of the payload.
+func hasDigest(cs uint16) bool {
+	return DataSize(cs)-lengthSize-encodingSize > digestSize
+}
+
+// chunk0Overhead returns the number of bytes chunk 0 of a sequence of chunk
+// size cs created by CreateChunks takes for the payload length, digest and
+// encoding.
+func chunk0Overhead(cs uint16, encoding uint8) int {
+	size := lengthSize
+	if hasDigest(cs) {
+		size += digestSize
+	}
+	if encoding != 0 {
+		size += encodingSize
+	}
+	return size
+}
+
+// CRCError is returned by NewChunk if the CRC of a chunk does not match its
+// header and data, which means the QR code was misread or the chunk was
+// corrupted on its way.
+type CRCError struct {
+	Nr   int    // the chunk number as read, which may itself be corrupted
+	Want uint32 // the CRC carried by the chunk
+	Got  uint32 // the CRC computed over the header and data
+}
+
+func (e *CRCError) Error() string {
+	return fmt.Sprintf("chunk %d: crc mismatch: got %08x, want %08x", e.Nr, e.Got, e.Want)
+}
+
+// IsValidChunkSize reports whether cs is one of the supported chunk sizes.
+func IsValidChunkSize(cs uint16) bool {
 	switch cs {
-	case ChunkSize32, ChunkSize64, ChunkSize128, ChunkSize256, ChunkSize512, ChunkSize1024:
+	case ChunkSize32, ChunkSize64, ChunkSize128, ChunkSize256, ChunkSize512, ChunkSize1024, ChunkSize2048:
 		return true
 	default:
 		return false
 	}
 }
 
+// maxTextLength is the number of bytes a QR code of version 40, the largest
+// one, holds in byte mode at each error correction level.
+var maxTextLength = map[ECLevel]int{
+	ECLevelLow:      2953,
+	ECLevelMedium:   2331,
+	ECLevelQuartile: 1663,
+	ECLevelHigh:     1273,
+}
+
+// maxAlphanumericLength is the number of characters a QR code of version 40
+// holds in alphanumeric mode at each error correction level.
+var maxAlphanumericLength = map[ECLevel]int{
+	ECLevelLow:      4296,
+	ECLevelMedium:   3391,
+	ECLevelQuartile: 2420,
+	ECLevelHigh:     1852,
+}
+
+// MaxECLevel returns the highest error correction level at which a QR code
+// holds the text of a full chunk of size cs in the given encoding, or
+// ECLevelDefault if no QR code holds it.
+func MaxECLevel(cs uint16, enc TextEncoding) ECLevel {
+	n, capacity := base64.StdEncoding.EncodedLen(int(cs)), maxTextLength
+	switch enc {
+	case TextBase45:
+		n, capacity = len(base45Prefix)+base45Len(int(cs)), maxAlphanumericLength
+	case TextRaw:
+		n = int(cs)
+	}
+	for level := ECLevelHigh; level >= ECLevelLow; level-- {
+		if n <= capacity[level] {
+			return level
+		}
+	}
+	return ECLevelDefault
+}
+
 type QRChunk struct {
-	nr   uint8  // chunk number
-	tot  uint8  // total number of chunks
-	cs   uint16 // chunk size in bytes (data is chunksize - 4 bytes (nr, tot, cs))
-	data []byte
+	layout   uint8  // LayoutLegacy or LayoutV2
+	flags    uint8  // optional fields of a v2 chunk
+	digest   []byte // digest of the payload, if flagged
+	seqID    uint32 // ID of the sequence, if flagged
+	length   uint32 // length of the payload, if flagged
+	encoding uint8  // encodings of the payload, if flagged
+	appTag   uint16 // tag of the sending application, if flagged
+	nr       uint32 // chunk number
+	tot      uint32 // total number of chunks
+	cs       uint16 // chunk size in bytes (data is chunksize - header size)
+	data     []byte
+}
+
+// newChunk creates a v2 chunk with a CRC.
+func newChunk(nr, tot int, cs uint16, data []byte) *QRChunk {
+	return &QRChunk{
+		layout: LayoutV2,
+		flags:  FlagCRC,
+		nr:     uint32(nr),
+		tot:    uint32(tot),
+		cs:     cs,
+		data:   data,
+	}
 }
 
 // NewChunk creates a new QRChunk from the given byte slice.
 //
-// The function takes a byte slice as input and extracts the necessary
-// information to create a new QRChunk. It first extracts the values for nr
-// and tot from the first two bytes of the input data. Then, it reads the
-// chunk size from the next two bytes and checks if it is a valid chunk size
-// using the isValidChunkSize function. If the chunk size is invalid, the
-// function returns nil. Otherwise, it creates a new QRChunk with the
-// extracted values and the remaining data.
+// The function takes the bytes of a legacy or a v2 chunk and extracts the
+// chunk number, the total number of chunks and the chunk size from its header.
+// It checks that the chunk size is valid using the IsValidChunkSize function
+// and that the chunk number is in range. If the chunk carries a CRC, it is
+// verified against the header and data. Otherwise, it creates a new QRChunk
+// with the extracted values and the remaining data.
 //
 // Parameters:
 //   - data: a byte slice containing the data for the QRChunk.
 //
 // Returns:
-//   - *QRChunk: a pointer to the newly created QRChunk, or nil if the chunk
-//     size is invalid.
-func NewChunk(data []byte) *QRChunk {
-	nr := uint8(data[0])
-	tot := uint8(data[1])
+//   - *QRChunk: a pointer to the newly created QRChunk.
+//   - error: a *CRCError if the CRC does not match, or an error if the data is
+//     shorter than the header, uses unknown optional fields, the chunk size is
+//     invalid or the chunk number is out of range.
+func NewChunk(data []byte) (*QRChunk, error) {
+	if FrameLayout(data) == LayoutV2 {
+		return newChunkV2(data)
+	}
+	if len(data) < headerSize {
+		return nil, ErrInvalidChunk
+	}
+	nr := uint32(data[0])
+	tot := uint32(data[1])
 
 	cs := uint16(0)
 	csBuf := bytes.NewReader(data[2:4])
 	err := binary.Read(csBuf, binary.LittleEndian, &cs)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	if !isValidChunkSize(cs) {
-		return nil
+	if !IsValidChunkSize(cs) {
+		return nil, ErrInvalidChunkSize
+	}
+	if tot != 0 && nr >= tot {
+		return nil, ErrInvalidChunk
 	}
 
 	return &QRChunk{
-		nr:  nr,
-		tot: tot,
-		cs:  cs,
+		layout: LayoutLegacy,
+		nr:     nr,
+		tot:    tot,
+		cs:     cs,
 		data: func() []byte {
 			if len(data) > int(cs) {
 				return data[4:cs]
 			}
 			return data[4:]
 		}(),
+	}, nil
+}
+
+// newChunkV2 creates a new QRChunk from the bytes of a v2 chunk.
+func newChunkV2(data []byte) (*QRChunk, error) {
+	if len(data) < headerSizeV2 {
+		return nil, ErrInvalidChunk
+	}
+	flags := data[2]
+	if flags&^knownFlags != 0 {
+		return nil, ErrInvalidChunk
+	}
+
+	cs := binary.LittleEndian.Uint16(data[3:5])
+	nr := binary.LittleEndian.Uint32(data[5:9])
+	tot := binary.LittleEndian.Uint32(data[9:13])
+	if !IsValidChunkSize(cs) {
+		return nil, ErrInvalidChunkSize
+	}
+	if tot > MaxChunks || (tot != 0 && nr >= tot) {
+		return nil, ErrInvalidChunk
+	}
+
+	c := &QRChunk{
+		layout: LayoutV2,
+		flags:  flags,
+		nr:     nr,
+		tot:    tot,
+		cs:     cs,
+	}
+	data = data[:min(len(data), int(cs))]
+	if len(data) < headerSizeV2+c.fieldsSize() {
+		return nil, ErrInvalidChunk
+	}
+	off := headerSizeV2
+	if flags&FlagCRC != 0 {
+		want := binary.LittleEndian.Uint32(data[off:])
+		got := crc32.Update(crc32.ChecksumIEEE(data[:headerSizeV2]), crc32.IEEETable, data[off+crcSize:])
+		if got != want {
+			return nil, &CRCError{Nr: int(nr), Want: want, Got: got}
+		}
+		off += crcSize
+	}
+	if flags&FlagDigest != 0 {
+		if nr != 0 {
+			return nil, ErrInvalidChunk
+		}
+		c.digest = data[off : off+digestSize]
+		off += digestSize
+	}
+	if flags&FlagSeqID != 0 {
+		c.seqID = binary.LittleEndian.Uint32(data[off:])
+		off += seqIDSize
+	}
+	if flags&FlagLength != 0 {
+		if nr != 0 {
+			return nil, ErrInvalidChunk
+		}
+		c.length = binary.LittleEndian.Uint32(data[off:])
+		off += lengthSize
+	}
+	if flags&FlagEncoding != 0 {
+		if nr != 0 {
+			return nil, ErrInvalidChunk
+		}
+		c.encoding = data[off]
+		off += encodingSize
+	}
+	if flags&FlagAppTag != 0 {
+		c.appTag = binary.LittleEndian.Uint16(data[off:])
+		off += appTagSize
+	}
+	c.data = data[off:]
+	return c, nil
+}
+
+// base45Prefix marks the text of a QR code as Base45. It is not part of the
+// base64 alphabet, so DecodeText tells the encodings apart, but part of the
+// alphanumeric one, so the QR code stays in alphanumeric mode.
+const base45Prefix = "%"
+
+// EncodeText encodes the bytes of a frame into the text of its QR code.
+func EncodeText(frame []byte, enc TextEncoding) string {
+	switch {
+	case enc == TextBase45:
+		return base45Prefix + encodeBase45(frame)
+	case enc == TextRaw && isRaw(string(frame)):
+		return string(frame)
 	}
+	return base64.StdEncoding.EncodeToString(frame)
+}
+
+// DecodeText decodes the text of a QR code into the bytes of the frame it
+// carries, which is a chunk or an extended frame such as a fountain frame.
+// Texts starting with base45Prefix are Base45, raw extended frames are taken
+// as they are, all others are base64.
+func DecodeText(text string) ([]byte, error) {
+	if isRaw(text) {
+		return []byte(text), nil
+	}
+	if b45, ok := strings.CutPrefix(text, base45Prefix); ok {
+		return decodeBase45(b45)
+	}
+	return base64.StdEncoding.DecodeString(text)
+}
+
+// isRaw reports whether the text of a QR code is a raw frame. Only extended
+// frames are sent raw, their ExtendedMarker is neither base64 nor Base45 nor
+// valid UTF-8.
+func isRaw(text string) bool {
+	return len(text) > 0 && text[0] == ExtendedMarker
 }
 
-// NewChunkFromImage decodes an image into a QRChunk.
+// NewChunkFromText decodes the text of a QR code into a QRChunk.
 //
-// It takes an image.Image as a parameter and attempts to decode it into a
-// QRChunk. It first creates a BinaryBitmap from the image using the
-// gozxing.NewBinaryBitmapFromImage function. Then it creates a QRCodeReader
-// and uses it to decode the BinaryBitmap into a QRCodeData object. The
-// QRCodeData object contains the text of the QR code, which is then decoded
-// from base64 to bytes using the base64.StdEncoding.DecodeString function.
-// Finally, it creates a new QRChunk using the NewChunk function and returns it
-// along with any error that occurred during the decoding process. If the
-// decoded chunk is invalid, it returns an error.
+// The text is the base64 or Base45 encoding of the chunk header and data, as
+// returned by Text.
 //
 // Parameters:
-// - img: an image.Image to be decoded into a QRChunk.
+// - text: the text of the QR code.
 //
 // Returns:
 //   - *QRChunk: the decoded QRChunk.
-//   - error: an error if there was an issue decoding the image or if the
-//     decoded chunk is invalid.
-func NewChunkFromImage(img image.Image) (*QRChunk, error) {
-	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
-	if err != nil {
-		return nil, err
-	}
-
-	reader := qrzxing.NewQRCodeReader()
-	data, err := reader.Decode(bmp, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	bytes, err := base64.StdEncoding.DecodeString(data.GetText())
+//   - error: an error if the text is not valid base64 or Base45 or the
+//     decoded chunk is invalid, a *CRCError if its CRC does not match.
+func NewChunkFromText(text string) (*QRChunk, error) {
+	bytes, err := DecodeText(text)
 	if err != nil {
 		return nil, err
 	}
 
-	chunk := NewChunk(bytes)
-	if chunk == nil {
-		return nil, errors.New("invalid chunk")
-	}
-	return chunk, nil
+	return NewChunk(bytes)
 }
 
 // CreateChunks generates a slice of QRChunk pointers based on the given data
 // and chunk size.
 //
 // Parameters:
-// - data: a byte slice containing the data to be split into chunks.
-// - chunkSize: an unsigned 16-bit integer specifying the size of each chunk.
+//   - data: a byte slice containing the data to be split into chunks.
+//   - chunkSize: an unsigned 16-bit integer specifying the size of each chunk.
+//   - seqID: the ID of the sequence, carried by every chunk.
+//   - encoding: the encodings applied to data, carried by chunk 0 if not zero.
+//   - appTag: the tag of the sending application, carried by every chunk if
+//     not zero.
 //
 // Returns:
-// - []*QRChunk: a slice of pointers to QRChunk objects.
-func CreateChunks(data []byte, chunkSize uint16) []*QRChunk {
-	ds := chunkSize - 4
-	tot := len(data) / int(ds)
-	if len(data)%int(ds) != 0 {
+//   - []*QRChunk: a slice of pointers to QRChunk objects.
+func CreateChunks(data []byte, chunkSize uint16, seqID uint32, encoding uint8, appTag uint16) []*QRChunk {
+	ds := chunkDataSize(chunkSize, appTag)
+	size := len(data)
+	if size > 0 {
+		size += chunk0Overhead(chunkSize, encoding)
+	}
+	tot := size / ds
+	if size%ds != 0 {
 		tot++
 	}
 	chunks := make([]*QRChunk, 0, tot)
 
+	s := 0
 	for i := 0; i < tot; i++ {
-		chunks = append(chunks, &QRChunk{
-			nr:  uint8(i),
-			tot: uint8(tot),
-			cs:  chunkSize,
-			data: func(i int) []byte {
-				s := i * int(ds)
-				e := s + int(ds)
-				if e > len(data) {
-					return data[s:]
-				}
-				return data[s:e]
-			}(i),
-		})
+		e := s + ds
+		if i == 0 {
+			e -= chunk0Overhead(chunkSize, encoding)
+		}
+		e = min(e, len(data))
+		chunk := newChunk(i, tot, chunkSize, data[s:e])
+		chunk.flags |= FlagSeqID
+		chunk.seqID = seqID
+		if appTag != 0 {
+			chunk.flags |= FlagAppTag
+			chunk.appTag = appTag
+		}
+		chunks = append(chunks, chunk)
+		s = e
+	}
+	if tot == 0 {
+		return chunks
+	}
+	chunks[0].flags |= FlagLength
+	chunks[0].length = uint32(len(data))
+	if hasDigest(chunkSize) {
+		digest := sha256.Sum256(data)
+		chunks[0].flags |= FlagDigest
+		chunks[0].digest = digest[:]
+	}
+	if encoding != 0 {
+		chunks[0].flags |= FlagEncoding
+		chunks[0].encoding = encoding
 	}
 	return chunks
 }
 
+// NewDecoyChunk creates a decoy chunk filled with random data.
+//
+// A decoy chunk has a total of zero chunks, which no real chunk can have, and
+// is discarded by receivers. Its chunk number and payload are random and the
+// payload is as large as a full chunk like the given one, so its QR code
+// cannot be told apart from a real one without decoding it.
+//
+// Parameters:
+// - like: a chunk of the sequence the decoy is mixed into.
+// - rand: the source of randomness.
+//
+// Returns:
+// - *QRChunk: the decoy chunk.
+// - error: an error if reading from rand fails.
+func NewDecoyChunk(like *QRChunk, rand io.Reader) (*QRChunk, error) {
+	c := &QRChunk{
+		layout: like.layout,
+		flags:  like.flags &^ (FlagDigest | FlagLength | FlagEncoding),
+		seqID:  like.seqID,
+		appTag: like.appTag,
+		tot:    0,
+		cs:     like.cs,
+	}
+	buf := make([]byte, 4+c.dataSize())
+	if _, err := io.ReadFull(rand, buf); err != nil {
+		return nil, err
+	}
+
+	c.nr = binary.LittleEndian.Uint32(buf)
+	if like.layout == LayoutLegacy {
+		c.nr &= 0xff
+	}
+	c.data = buf[4:]
+	return c, nil
+}
+
 // GetData generates a byte slice containing the data from the given slice of
 // QRChunk pointers.
 //
@@ -188,13 +532,60 @@ func GetData(chunks []*QRChunk) []byte {
 }
 
 // Nr returns sequence number of this qr chunk.
-func (c QRChunk) Nr() uint8 {
-	return c.nr
+func (c QRChunk) Nr() int {
+	return int(c.nr)
 }
 
 // Tot returns the total number of qr chunks in this sequence.
-func (c QRChunk) Tot() uint8 {
-	return c.tot
+func (c QRChunk) Tot() int {
+	return int(c.tot)
+}
+
+// Digest returns the SHA-256 digest of the whole payload carried by the chunk,
+// or nil if it carries none.
+func (c QRChunk) Digest() []byte {
+	return c.digest
+}
+
+// SeqID returns the ID of the sequence the chunk belongs to and whether the
+// chunk carries one.
+func (c QRChunk) SeqID() (uint32, bool) {
+	return c.seqID, c.flags&FlagSeqID != 0
+}
+
+// WithSeqID returns a copy of the chunk that carries the given sequence ID.
+func (c QRChunk) WithSeqID(seqID uint32) *QRChunk {
+	c.flags |= FlagSeqID
+	c.seqID = seqID
+	return &c
+}
+
+// AppTag returns the tag of the application that sent the chunk and whether
+// the chunk carries one.
+func (c QRChunk) AppTag() (uint16, bool) {
+	return c.appTag, c.flags&FlagAppTag != 0
+}
+
+// Length returns the length of the whole payload carried by the chunk and
+// whether the chunk carries it.
+func (c QRChunk) Length() (int, bool) {
+	return int(c.length), c.flags&FlagLength != 0
+}
+
+// Encoding returns the encodings applied to the payload before chunking, as
+// carried by chunk 0.
+func (c QRChunk) Encoding() uint8 {
+	return c.encoding
+}
+
+// Layout returns the layout of the chunk, LayoutLegacy or LayoutV2.
+func (c QRChunk) Layout() uint8 {
+	return c.layout
+}
+
+// IsDecoy reports whether this is a decoy chunk that carries no payload.
+func (c QRChunk) IsDecoy() bool {
+	return c.tot == 0
 }
 
 // Size returns the chunksize
@@ -207,67 +598,104 @@ func (c QRChunk) Data() []byte {
 	return c.data
 }
 
-// QRCode generates a QR code image based on the data of the QRChunk.
-//
-// It takes an integer parameter `blockSize` which represents the size of the
-// blocks in the QR code.
-// The function returns two values: `img` of type `image.Image` which is the
-// generated QR code image,
-// and `err` of type `error` which indicates any error that occurred during the
-// generation process.
-//
-// If the `blockSize` parameter is less than 1, the function returns an error
-// indicating an invalid block size.
-// The function then creates a new QR code using the `qrcode.New` function,
-// passing the base64-encoded data of the QRChunk.
-// If there is an error creating the QR code, the function returns the error.
-// The function creates a new `ImageWriter` with a callback function that
-// assigns the generated image to the `img` variable.
-// The `ImageWriter` is configured with the `Padding` and `BlockSize` options
-// set to the `blockSize` parameter.
-// The function saves the QR code using the `qr.Save` method, passing the
-// `ImageWriter` as the writer.
-// If there is an error saving the QR code, the function returns the error.
-// The function returns the generated image and any error that occurred during
-// the process.
-func (c QRChunk) QRCode(blockSize int) (img image.Image, err error) {
-	if blockSize < 1 {
-		err = errors.New("invalid block size")
-		return
-	}
-
-	qrdata := make([]byte, 0, len(c.data)+4)
-	qrdata = append(qrdata, c.nr)
-	qrdata = append(qrdata, c.tot)
-
-	csBuff := new(bytes.Buffer)
-	err = binary.Write(csBuff, binary.LittleEndian, c.cs)
-	if err != nil {
-		return
+// Consume drops the first n bytes of the data once they have been handed on,
+// releasing the data when all of it is consumed.
+func (c *QRChunk) Consume(n int) {
+	c.data = c.data[n:]
+	if len(c.data) == 0 {
+		c.data = nil
 	}
-	qrdata = append(qrdata, csBuff.Bytes()...)
-
-	qrdata = append(qrdata, c.data...)
+}
 
-	qr, err := qrcode.New(base64.StdEncoding.EncodeToString(qrdata))
-	if err != nil {
-		return
+// Bytes returns the chunk header followed by the data, the serialized form of
+// the chunk that NewChunk parses.
+func (c QRChunk) Bytes() []byte {
+	if c.layout == LayoutV2 {
+		size := headerSizeV2 + c.fieldsSize()
+		b := make([]byte, size, size+len(c.data))
+		b[0] = ExtendedMarker
+		b[1] = ChunkFrameV2
+		b[2] = c.flags
+		binary.LittleEndian.PutUint16(b[3:5], c.cs)
+		binary.LittleEndian.PutUint32(b[5:9], c.nr)
+		binary.LittleEndian.PutUint32(b[9:13], c.tot)
+		off := headerSizeV2
+		if c.flags&FlagCRC != 0 {
+			off += crcSize
+		}
+		if c.flags&FlagDigest != 0 {
+			copy(b[off:], c.digest)
+			off += digestSize
+		}
+		if c.flags&FlagSeqID != 0 {
+			binary.LittleEndian.PutUint32(b[off:], c.seqID)
+			off += seqIDSize
+		}
+		if c.flags&FlagLength != 0 {
+			binary.LittleEndian.PutUint32(b[off:], c.length)
+			off += lengthSize
+		}
+		if c.flags&FlagEncoding != 0 {
+			b[off] = c.encoding
+			off += encodingSize
+		}
+		if c.flags&FlagAppTag != 0 {
+			binary.LittleEndian.PutUint16(b[off:], c.appTag)
+		}
+		b = append(b, c.data...)
+		if c.flags&FlagCRC != 0 {
+			crc := crc32.Update(crc32.ChecksumIEEE(b[:headerSizeV2]), crc32.IEEETable, b[headerSizeV2+crcSize:])
+			binary.LittleEndian.PutUint32(b[headerSizeV2:], crc)
+		}
+		return b
 	}
 
-	w := NewImageWriter(
-		func(res image.Image) {
-			img = res
-		}, &Option{
-			Padding:   blockSize,
-			BlockSize: blockSize,
-		})
+	b := make([]byte, headerSize, headerSize+len(c.data))
+	b[0] = uint8(c.nr)
+	b[1] = uint8(c.tot)
+	binary.LittleEndian.PutUint16(b[2:4], c.cs)
+	return append(b, c.data...)
+}
+
+// dataSize returns the number of payload bytes a full chunk like c carries.
+func (c QRChunk) dataSize() int {
+	if c.layout == LayoutV2 {
+		return int(c.cs) - headerSizeV2 - c.fieldsSize()
+	}
+	return int(c.cs) - headerSize
+}
 
-	if err = qr.Save(w); err != nil {
-		return
+// fieldsSize returns the number of bytes taken by the optional fields of a v2
+// chunk.
+func (c QRChunk) fieldsSize() int {
+	size := 0
+	if c.flags&FlagCRC != 0 {
+		size += crcSize
+	}
+	if c.flags&FlagDigest != 0 {
+		size += digestSize
+	}
+	if c.flags&FlagSeqID != 0 {
+		size += seqIDSize
+	}
+	if c.flags&FlagLength != 0 {
+		size += lengthSize
 	}
-	return
+	if c.flags&FlagEncoding != 0 {
+		size += encodingSize
+	}
+	if c.flags&FlagAppTag != 0 {
+		size += appTagSize
+	}
+	return size
+}
+
+// Text returns the encoding of Bytes, which is the text encoded into the QR
+// code of the chunk.
+func (c QRChunk) Text(enc TextEncoding) string {
+	return EncodeText(c.Bytes(), enc)
 }
 
 func (c QRChunk) estimatedDataSize() uint64 {
-	return uint64(c.cs-4) * uint64(c.tot)
+	return uint64(c.dataSize()) * uint64(c.tot)
 }
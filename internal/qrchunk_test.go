@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewChunkRejectsCorruptedCRC(t *testing.T) {
+	data := make([]byte, 200)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	chunk := CreateChunks(data, 64, 7, 0, 0)[1]
+
+	for _, tc := range []struct {
+		name string
+		off  int
+	}{
+		{name: "chunk number", off: 5},
+		{name: "crc", off: headerSizeV2},
+		{name: "sequence ID", off: headerSizeV2 + crcSize},
+		{name: "data", off: 63},
+	} {
+		frame := chunk.Bytes()
+		frame[tc.off] ^= 1
+		_, err := NewChunk(frame)
+		var crcErr *CRCError
+		if !errors.As(err, &crcErr) {
+			t.Errorf("%s: got %v, want a *CRCError", tc.name, err)
+		}
+	}
+
+	if _, err := NewChunk(chunk.Bytes()); err != nil {
+		t.Errorf("intact chunk: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+//go:build !core
+
+package internal
+
+import (
+	"image"
+	"io"
+	"math"
+
+	"github.com/makiuchi-d/gozxing"
+	multizxing "github.com/makiuchi-d/gozxing/multi/qrcode"
+	qrzxing "github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/yeqown/go-qrcode/v2"
+)
+
+// NewChunkFromImage decodes an image into a QRChunk.
+//
+// It takes an image.Image as a parameter and attempts to decode it into a
+// QRChunk. It first creates a BinaryBitmap from the image using the
+// gozxing.NewBinaryBitmapFromImage function. Then it creates a QRCodeReader
+// and uses it to decode the BinaryBitmap into a QRCodeData object. The
+// QRCodeData object contains the text of the QR code, which is then decoded
+// into a QRChunk using the NewChunkFromText function. If the decoded chunk is
+// invalid, it returns an error.
+// The QR code is read with the ReadImage function.
+//
+// Parameters:
+// - img: an image.Image to be decoded into a QRChunk.
+//
+// Returns:
+//   - *QRChunk: the decoded QRChunk.
+//   - error: an error if there was an issue decoding the image or if the
+//     decoded chunk is invalid.
+func NewChunkFromImage(img image.Image) (*QRChunk, error) {
+	text, err := ReadImage(img)
+	if err != nil {
+		return nil, err
+	}
+	return NewChunkFromText(text)
+}
+
+// ReadImage reads the text of the QR code in an image.
+//
+// Parameters:
+// - img: an image.Image containing a QR code.
+//
+// Returns:
+// - string: the text of the QR code.
+// - error: the error of the QR code reader if no QR code could be read.
+func ReadImage(img image.Image) (string, error) {
+	text, _, err := ReadImageRotation(img)
+	return text, err
+}
+
+// ReadImageRotation reads the text of the QR code in an image like ReadImage
+// and also returns how the code is rotated in the image.
+//
+// Parameters:
+// - img: an image.Image containing a QR code.
+//
+// Returns:
+//   - string: the text of the QR code.
+//   - int: the clockwise rotation of the code in degrees, rounded to 0, 90,
+//     180 or 270.
+//   - error: the error of the QR code reader if no QR code could be read.
+func ReadImageRotation(img image.Image) (string, int, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", 0, err
+	}
+
+	reader := qrzxing.NewQRCodeReader()
+	data, err := reader.Decode(bmp, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	return resultText(data), rotation(data.GetResultPoints()), nil
+}
+
+// resultText returns the text of a QR code read by gozxing. The reader
+// converts byte mode segments to text in a guessed character set, so raw
+// frames are taken from the byte segments instead.
+func resultText(result *gozxing.Result) string {
+	segments, _ := result.GetResultMetadata()[gozxing.ResultMetadataType_BYTE_SEGMENTS].([][]byte)
+	if len(segments) == 0 || !isRaw(string(segments[0])) {
+		return result.GetText()
+	}
+	var raw []byte
+	for _, segment := range segments {
+		raw = append(raw, segment...)
+	}
+	return string(raw)
+}
+
+// ReadImageAll reads the texts of all QR codes in an image, e.g. of a printed
+// page holding several codes.
+//
+// Parameters:
+// - img: an image.Image containing QR codes.
+//
+// Returns:
+//   - []string: the texts of the QR codes that could be read, in no
+//     particular order.
+//   - error: the error of the QR code reader if no QR code could be read.
+func ReadImageAll(img image.Image) ([]string, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := multizxing.NewQRCodeMultiReader().DecodeMultiple(bmp, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, gozxing.NewNotFoundException()
+	}
+	texts := make([]string, len(results))
+	for i, result := range results {
+		texts[i] = resultText(result)
+	}
+	return texts, nil
+}
+
+// rotation returns the rotation of a QR code from the result points of the
+// reader, which start with the bottom left, top left and top right finder
+// patterns. The top edge of the code runs from the top left to the top right
+// one.
+func rotation(points []gozxing.ResultPoint) int {
+	if len(points) < 3 {
+		return 0
+	}
+	return Rotation(points[2].GetX()-points[1].GetX(), points[2].GetY()-points[1].GetY())
+}
+
+// Rotation returns the clockwise rotation of a QR code from the direction of
+// its top edge, from the top left to the top right corner, in image
+// coordinates.
+//
+// Parameters:
+// - dx: the horizontal extent of the top edge.
+// - dy: the vertical extent of the top edge.
+//
+// Returns:
+// - int: the rotation in degrees, rounded to 0, 90, 180 or 270.
+func Rotation(dx, dy float64) int {
+	degrees := math.Atan2(dy, dx) * 180 / math.Pi
+	return (int(math.Round(degrees/90))*90 + 360) % 360
+}
+
+// QRCode generates a QR code image based on the data of the QRChunk.
+//
+// It takes an integer parameter `blockSize` which represents the size of the
+// blocks in the QR code, and renders the chunk with a quiet zone of the same
+// size using the default palette.
+// It is a shorthand for Render.
+func (c QRChunk) QRCode(blockSize int) (image.Image, error) {
+	return c.Render(&Option{
+		Padding:   blockSize,
+		BlockSize: blockSize,
+	})
+}
+
+// Render generates a QR code image based on the data of the QRChunk.
+//
+// It takes an Option which configures the size of the blocks, the padding
+// around the QR code and the palette.
+// The function returns two values: `img` of type `image.Image` which is the
+// generated QR code image,
+// and `err` of type `error` which indicates any error that occurred during the
+// generation process.
+//
+// If the block size is less than 1, the function returns an error
+// indicating an invalid block size.
+// The function then creates a new QR code using the `qrcode.New` function,
+// passing the Text of the QRChunk.
+// If there is an error creating the QR code, the function returns the error.
+// The function creates a new `ImageWriter` with a callback function that
+// assigns the generated image to the `img` variable.
+// The function saves the QR code using the `qr.Save` method, passing the
+// `ImageWriter` as the writer.
+// If there is an error saving the QR code, the function returns the error.
+// The function returns the generated image and any error that occurred during
+// the process.
+func (c QRChunk) Render(opt *Option) (image.Image, error) {
+	return RenderText(c.Text(opt.TextEncoding), opt)
+}
+
+// ecLevels maps the error correction levels to those of the QR code encoder.
+var ecLevels = map[ECLevel]qrcode.EncodeOption{
+	ECLevelLow:      qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionLow),
+	ECLevelMedium:   qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionMedium),
+	ECLevelQuartile: qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionQuart),
+	ECLevelHigh:     qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionHighest),
+}
+
+// RenderText renders a QR code holding the given text, as Render does for the
+// text of a chunk.
+//
+// Parameters:
+// - text: the text of the QR code.
+// - opt: the Option configuring the rendered image.
+//
+// Returns:
+// - img: the rendered QR code.
+// - err: an error if the block size is invalid or the QR code cannot be created.
+func RenderText(text string, opt *Option) (img image.Image, err error) {
+	if opt.BlockSize < 1 {
+		err = ErrInvalidBlockSize
+		return
+	}
+
+	qr, err := newQRCode(text, opt)
+	if err != nil {
+		return
+	}
+
+	w := NewImageWriter(
+		func(res image.Image) {
+			img = res
+		}, opt)
+
+	if err = qr.Save(w); err != nil {
+		err = &EncodeError{Err: err}
+	}
+	return
+}
+
+// RenderSVG writes the QR code of the QRChunk as an SVG document, see
+// NewSVGWriter.
+//
+// Parameters:
+// - w: the io.Writer the document is written to.
+// - opt: the Option configuring the document.
+//
+// Returns:
+//   - error: an error if the block size is invalid, the QR code cannot be
+//     created or writing fails.
+func (c QRChunk) RenderSVG(w io.Writer, opt *Option) error {
+	return RenderTextSVG(w, c.Text(opt.TextEncoding), opt)
+}
+
+// RenderTerminal writes the QR code of the QRChunk as lines of half block
+// characters, see NewTerminalWriter. Only the error correction level of the
+// Option is used.
+//
+// Parameters:
+//   - w: the io.Writer the lines are written to.
+//   - opt: the Option selecting the error correction level.
+//   - quiet: the width of the quiet zone in modules.
+//   - invert: draw the light modules instead of the dark ones.
+//   - ansi: set black on white with ANSI escape sequences.
+//
+// Returns:
+//   - error: an error if the QR code cannot be created or writing fails.
+func (c QRChunk) RenderTerminal(w io.Writer, opt *Option, quiet int, invert, ansi bool) error {
+	qr, err := newQRCode(c.Text(opt.TextEncoding), opt)
+	if err != nil {
+		return err
+	}
+	return qr.Save(NewTerminalWriter(w, quiet, invert, ansi))
+}
+
+// RenderTextSVG writes a QR code holding the given text as an SVG document, as
+// RenderSVG does for the text of a chunk.
+//
+// Parameters:
+// - w: the io.Writer the document is written to.
+// - text: the text of the QR code.
+// - opt: the Option configuring the document.
+//
+// Returns:
+//   - error: an error if the block size is invalid, the QR code cannot be
+//     created or writing fails.
+func RenderTextSVG(w io.Writer, text string, opt *Option) error {
+	if opt.BlockSize < 1 {
+		return ErrInvalidBlockSize
+	}
+	qr, err := newQRCode(text, opt)
+	if err != nil {
+		return err
+	}
+	return qr.Save(NewSVGWriter(w, opt))
+}
+
+// newQRCode encodes text as a QR code at the error correction level of opt.
+func newQRCode(text string, opt *Option) (*qrcode.QRCode, error) {
+	var opts []qrcode.EncodeOption
+	if level, ok := ecLevels[opt.ECLevel]; ok {
+		opts = append(opts, level)
+	}
+	if isRaw(text) {
+		opts = append(opts, qrcode.WithEncodingMode(qrcode.EncModeByte))
+	}
+	qr, err := qrcode.NewWith(text, opts...)
+	if err != nil {
+		return nil, &EncodeError{Err: err}
+	}
+	return qr, nil
+}
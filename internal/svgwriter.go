@@ -0,0 +1,81 @@
+//go:build !core
+
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/yeqown/go-qrcode/v2"
+)
+
+type svgWriter struct {
+	w      io.Writer
+	option *Option
+	err    error
+}
+
+// NewSVGWriter creates a qrcode.Writer that writes the QR code as an SVG
+// document to w.
+//
+// The document uses the block size as its unit, so it has the dimensions of
+// the image rendered by NewImageWriter but scales without loss. The dark
+// modules of each row are merged into runs of a single path. The contrast and
+// gamma of the Option are applied, while the intermediate gray levels of
+// PaletteGray4 and the dot gain compensation are left to the consumer of the
+// vector output.
+//
+// Parameters:
+// - w: the io.Writer the document is written to.
+// - opt: the Option configuring the document.
+//
+// Returns:
+// - qrcode.Writer: the SVG writer.
+func NewSVGWriter(w io.Writer, opt *Option) qrcode.Writer {
+	return &svgWriter{w: w, option: opt}
+}
+
+// Write writes the QR code matrix as an SVG document.
+func (w *svgWriter) Write(mat qrcode.Matrix) error {
+	padding := w.option.Padding
+	blockWidth := w.option.BlockSize
+	size := mat.Width()*blockWidth + 2*padding
+	colors := w.option.colors()
+
+	bw := bufio.NewWriter(w.w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, size, size)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="%s"/>`, size, size, svgColor(colors[backgroundIndex]))
+	fmt.Fprintf(bw, `<path fill="%s" d="`, svgColor(colors[foregroundIndex]))
+
+	bitmap := mat.Bitmap()
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(bw, "M%d %dh%dv%dh-%dz", x*blockWidth+padding, y*blockWidth+padding, run*blockWidth, blockWidth, run*blockWidth)
+			x += run
+		}
+	}
+	fmt.Fprint(bw, `"/></svg>`)
+	w.err = bw.Flush()
+	return w.err
+}
+
+// Close returns the error of writing the document, if any.
+func (w *svgWriter) Close() error {
+	return w.err
+}
+
+// svgColor formats a color as an SVG hex color.
+func svgColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
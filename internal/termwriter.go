@@ -0,0 +1,96 @@
+//go:build !core
+
+package internal
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/yeqown/go-qrcode/v2"
+)
+
+// Half block characters for the upper and lower module of a character cell.
+const (
+	blockNone  = " "
+	blockUpper = "▀"
+	blockLower = "▄"
+	blockFull  = "█"
+)
+
+// ansiColors sets black on bright white and ansiReset restores the colors of
+// the terminal.
+const (
+	ansiColors = "\x1b[30;107m"
+	ansiReset  = "\x1b[0m"
+)
+
+type termWriter struct {
+	w      io.Writer
+	quiet  int
+	invert bool
+	ansi   bool
+	err    error
+}
+
+// NewTerminalWriter creates a qrcode.Writer that writes the QR code as lines
+// of Unicode half block characters to w, two modules per character cell, so
+// it can be shown in a terminal.
+//
+// Parameters:
+//   - w: the io.Writer the lines are written to.
+//   - quiet: the width of the quiet zone around the code in modules.
+//   - invert: draw the light modules instead of the dark ones, for terminals
+//     showing light text on a dark background.
+//   - ansi: set black on white with ANSI escape sequences, so the code
+//     shows the same in any terminal. invert is ignored if it is set.
+//
+// Returns:
+// - qrcode.Writer: the terminal writer.
+func NewTerminalWriter(w io.Writer, quiet int, invert, ansi bool) qrcode.Writer {
+	return &termWriter{w: w, quiet: quiet, invert: invert && !ansi, ansi: ansi}
+}
+
+// Write writes the QR code matrix as lines of half block characters.
+func (w *termWriter) Write(mat qrcode.Matrix) error {
+	bitmap := mat.Bitmap()
+	size := len(bitmap) + 2*w.quiet
+
+	// dark reports whether the module at x, y of the code with its quiet zone
+	// is drawn
+	dark := func(x, y int) bool {
+		x, y = x-w.quiet, y-w.quiet
+		set := y >= 0 && y < len(bitmap) && x >= 0 && x < len(bitmap[y]) && bitmap[y][x]
+		return set != w.invert
+	}
+
+	bw := bufio.NewWriter(w.w)
+	for y := 0; y < size; y += 2 {
+		if w.ansi {
+			bw.WriteString(ansiColors)
+		}
+		for x := 0; x < size; x++ {
+			upper, lower := dark(x, y), y+1 < size && dark(x, y+1)
+			switch {
+			case upper && lower:
+				bw.WriteString(blockFull)
+			case upper:
+				bw.WriteString(blockUpper)
+			case lower:
+				bw.WriteString(blockLower)
+			default:
+				bw.WriteString(blockNone)
+			}
+		}
+		if w.ansi {
+			bw.WriteString(ansiReset)
+		}
+		bw.WriteByte('\n')
+	}
+	w.err = bw.Flush()
+	return w.err
+}
+
+// Close returns the error of writing the lines, if any.
+func (w *termWriter) Close() error {
+	return w.err
+}
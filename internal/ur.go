@@ -0,0 +1,562 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// URType is the UR type of the payloads exchanged in BC-UR mode, a CBOR byte
+// string.
+const URType = "bytes"
+
+// minFragmentLen is the smallest fragment length of a multi-part UR, as used by
+// the reference encoders.
+const minFragmentLen = 10
+
+// bytewords is the Bytewords word list of the Blockchain Commons UR standard.
+// The minimal encoding uses the first and the last letter of each word.
+var bytewords = strings.Fields(`
+	able acid also apex aqua arch atom aunt away axis back bald barn belt beta bias
+	blue body brag brew bulb buzz calm cash cats chef city claw code cola cook cost
+	crux curl cusp cyan dark data days deli dice diet door down draw drop drum dull
+	duty each easy echo edge epic even exam exit eyes fact fair fern figs film fish
+	fizz flap flew flux foxy free frog fuel fund gala game gear gems gift girl glow
+	good gray grim guru gush gyro half hang hard hawk heat help high hill holy hope
+	horn huts iced idea idle inch inky into iris iron item jade jazz join jolt jowl
+	judo jugs jump junk jury keep keno kept keys kick kiln king kite kiwi knob lamb
+	lava lazy leaf legs liar limp lion list logo loud love luau luck lung main many
+	math maze memo menu meow mild mint miss monk nail navy need news next noon note
+	numb obey oboe omit onyx open oval owls paid part peck play plus poem pool pose
+	puff puma purr quad quiz race ramp real redo rich road rock roof ruby ruin runs
+	rust safe saga scar sets silk skew slot soap solo song stub surf swan taco task
+	taxi tent tied time tiny toil tomb toys trip tuna twin ugly undo unit urge user
+	vast very veto vial vibe view visa void vows wall wand warm wasp wave waxy webs
+	what when whiz wolf work yank yawn yell yoga yurt zaps zero zest zinc zone zoom
+`)
+
+var minimalBytewords = func() map[string]byte {
+	m := make(map[string]byte, len(bytewords))
+	for i, w := range bytewords {
+		m[w[:1]+w[3:]] = byte(i)
+	}
+	return m
+}()
+
+// encodeBytewords encodes data with its CRC32 checksum as minimal Bytewords.
+func encodeBytewords(data []byte) string {
+	buf := binary.BigEndian.AppendUint32(append([]byte(nil), data...), crc32.ChecksumIEEE(data))
+
+	var sb strings.Builder
+	sb.Grow(2 * len(buf))
+	for _, b := range buf {
+		w := bytewords[b]
+		sb.WriteByte(w[0])
+		sb.WriteByte(w[3])
+	}
+	return sb.String()
+}
+
+// decodeBytewords decodes minimal Bytewords and verifies their checksum.
+func decodeBytewords(s string) ([]byte, error) {
+	if len(s)%2 != 0 || len(s) < 10 {
+		return nil, fmt.Errorf("%w: bytewords", ErrInvalidUR)
+	}
+
+	buf := make([]byte, len(s)/2)
+	for i := range buf {
+		b, ok := minimalBytewords[s[2*i:2*i+2]]
+		if !ok {
+			return nil, fmt.Errorf("%w: bytewords", ErrInvalidUR)
+		}
+		buf[i] = b
+	}
+
+	data, sum := buf[:len(buf)-4], buf[len(buf)-4:]
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(sum) {
+		return nil, fmt.Errorf("%w: bytewords checksum", ErrInvalidUR)
+	}
+	return data, nil
+}
+
+// CBOR major types used by UR parts.
+const (
+	cborUint  = 0
+	cborBytes = 2
+	cborArray = 4
+)
+
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+func readCBORHead(b []byte) (major byte, n uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, 0, nil, fmt.Errorf("%w: cbor", ErrInvalidUR)
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	size := 0
+	switch {
+	case info < 24:
+		return major, uint64(info), b, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, nil, fmt.Errorf("%w: cbor", ErrInvalidUR)
+	}
+	if len(b) < size {
+		return 0, 0, nil, fmt.Errorf("%w: cbor", ErrInvalidUR)
+	}
+	for _, v := range b[:size] {
+		n = n<<8 | uint64(v)
+	}
+	return major, n, b[size:], nil
+}
+
+func readCBORUint(b []byte) (uint64, []byte, error) {
+	major, n, rest, err := readCBORHead(b)
+	if err == nil && major != cborUint {
+		err = fmt.Errorf("%w: cbor", ErrInvalidUR)
+	}
+	return n, rest, err
+}
+
+func readCBORBytes(b []byte) ([]byte, []byte, error) {
+	major, n, rest, err := readCBORHead(b)
+	if err == nil && (major != cborBytes || uint64(len(rest)) < n) {
+		err = fmt.Errorf("%w: cbor", ErrInvalidUR)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return rest[:n], rest[n:], nil
+}
+
+// URPart is one part of a multi-part UR.
+type URPart struct {
+	SeqNum     uint32
+	SeqLen     int
+	MessageLen int
+	Checksum   uint32
+	Fragment   []byte
+}
+
+func (p URPart) cbor() []byte {
+	b := appendCBORHead(nil, cborArray, 5)
+	b = appendCBORHead(b, cborUint, uint64(p.SeqNum))
+	b = appendCBORHead(b, cborUint, uint64(p.SeqLen))
+	b = appendCBORHead(b, cborUint, uint64(p.MessageLen))
+	b = appendCBORHead(b, cborUint, uint64(p.Checksum))
+	b = appendCBORHead(b, cborBytes, uint64(len(p.Fragment)))
+	return append(b, p.Fragment...)
+}
+
+func parseURPartCBOR(b []byte) (URPart, error) {
+	major, n, b, err := readCBORHead(b)
+	if err != nil || major != cborArray || n != 5 {
+		return URPart{}, fmt.Errorf("%w: part", ErrInvalidUR)
+	}
+
+	var fields [4]uint64
+	for i := range fields {
+		if fields[i], b, err = readCBORUint(b); err != nil {
+			return URPart{}, err
+		}
+	}
+	fragment, _, err := readCBORBytes(b)
+	if err != nil {
+		return URPart{}, err
+	}
+	if fields[0] == 0 || fields[0] > math.MaxUint32 || fields[1] == 0 || fields[1] > math.MaxUint16 ||
+		fields[2] > math.MaxUint32 || fields[3] > math.MaxUint32 {
+		return URPart{}, fmt.Errorf("%w: part", ErrInvalidUR)
+	}
+
+	return URPart{
+		SeqNum:     uint32(fields[0]),
+		SeqLen:     int(fields[1]),
+		MessageLen: int(fields[2]),
+		Checksum:   uint32(fields[3]),
+		Fragment:   fragment,
+	}, nil
+}
+
+// UREncoder produces the parts of a UR of type bytes. Payloads that fit into a
+// single fragment are encoded as a single-part UR, larger ones as an endless
+// stream of fountain coded parts.
+type UREncoder struct {
+	message   []byte
+	checksum  uint32
+	fragments [][]byte
+	seqNum    uint32
+}
+
+// NewUREncoder creates a UREncoder for data with fragments of at most
+// maxFragmentLen bytes.
+func NewUREncoder(data []byte, maxFragmentLen int) (*UREncoder, error) {
+	if maxFragmentLen < minFragmentLen {
+		return nil, ErrInvalidFragmentLength
+	}
+
+	message := appendCBORHead(nil, cborBytes, uint64(len(data)))
+	message = append(message, data...)
+
+	fragmentLen := nominalFragmentLen(len(message), maxFragmentLen)
+	count := (len(message) + fragmentLen - 1) / fragmentLen
+	if count > math.MaxUint16 {
+		return nil, ErrPayloadTooLarge
+	}
+	padded := make([]byte, count*fragmentLen)
+	copy(padded, message)
+	fragments := make([][]byte, count)
+	for i := range fragments {
+		fragments[i] = padded[i*fragmentLen : (i+1)*fragmentLen]
+	}
+
+	return &UREncoder{
+		message:   message,
+		checksum:  crc32.ChecksumIEEE(message),
+		fragments: fragments,
+	}, nil
+}
+
+// nominalFragmentLen returns the length of the fragments a message is split
+// into, the smallest number of equally long fragments not exceeding
+// maxFragmentLen.
+func nominalFragmentLen(messageLen, maxFragmentLen int) int {
+	maxCount := max(1, messageLen/minFragmentLen)
+	fragmentLen := messageLen
+	for count := 1; count <= maxCount; count++ {
+		fragmentLen = (messageLen + count - 1) / count
+		if fragmentLen <= maxFragmentLen {
+			break
+		}
+	}
+	return max(1, fragmentLen)
+}
+
+// SeqLen returns the number of fragments of the UR.
+func (e *UREncoder) SeqLen() int {
+	return len(e.fragments)
+}
+
+// NextPart returns the next part of the UR as text in lower case.
+func (e *UREncoder) NextPart() string {
+	if len(e.fragments) == 1 {
+		return "ur:" + URType + "/" + encodeBytewords(e.message)
+	}
+
+	e.seqNum++
+	part := URPart{
+		SeqNum:     e.seqNum,
+		SeqLen:     len(e.fragments),
+		MessageLen: len(e.message),
+		Checksum:   e.checksum,
+		Fragment:   make([]byte, len(e.fragments[0])),
+	}
+	for _, i := range urFragments(part.SeqNum, part.SeqLen, part.Checksum) {
+		xorInto(part.Fragment, e.fragments[i])
+	}
+	return "ur:" + URType + "/" + strconv.FormatUint(uint64(part.SeqNum), 10) + "-" +
+		strconv.Itoa(part.SeqLen) + "/" + encodeBytewords(part.cbor())
+}
+
+// URDecoder reassembles the payload of a UR of type bytes from its parts.
+type URDecoder struct {
+	header    URPart
+	fragments [][]byte
+	received  int
+	pending   []*fountainSymbol
+	seen      map[uint32]bool
+	data      []byte
+}
+
+// NewURDecoder creates an empty URDecoder.
+func NewURDecoder() *URDecoder {
+	return &URDecoder{seen: make(map[uint32]bool)}
+}
+
+// IsURText reports whether text is a UR.
+func IsURText(text string) bool {
+	return len(text) >= 3 && strings.EqualFold(text[:3], "ur:")
+}
+
+// AddPart adds the text of a UR part, in upper or lower case.
+//
+// Parameters:
+// - text: the text of the part.
+//
+// Returns:
+//   - error: an error if the text is not a valid part of a UR of type bytes or
+//     does not belong to the UR of the parts added before.
+func (d *URDecoder) AddPart(text string) error {
+	if d.data != nil {
+		return nil
+	}
+	if !IsURText(text) {
+		return ErrInvalidUR
+	}
+
+	path := strings.Split(strings.ToLower(text[3:]), "/")
+	if path[0] != URType {
+		return fmt.Errorf("%w: unsupported type %s", ErrInvalidUR, path[0])
+	}
+
+	switch len(path) {
+	case 2:
+		body, err := decodeBytewords(path[1])
+		if err != nil {
+			return err
+		}
+		return d.finish(body)
+	case 3:
+		body, err := decodeBytewords(path[2])
+		if err != nil {
+			return err
+		}
+		part, err := parseURPartCBOR(body)
+		if err != nil {
+			return err
+		}
+		if path[1] != strconv.FormatUint(uint64(part.SeqNum), 10)+"-"+strconv.Itoa(part.SeqLen) {
+			return fmt.Errorf("%w: sequence", ErrInvalidUR)
+		}
+		return d.addPart(part)
+	}
+	return ErrInvalidUR
+}
+
+func (d *URDecoder) addPart(part URPart) error {
+	if d.fragments == nil {
+		fragmentLen := len(part.Fragment)
+		if fragmentLen == 0 || part.SeqLen*fragmentLen < part.MessageLen {
+			return fmt.Errorf("%w: part", ErrInvalidUR)
+		}
+		d.header = part
+		d.fragments = make([][]byte, part.SeqLen)
+	}
+	if part.SeqLen != d.header.SeqLen || part.MessageLen != d.header.MessageLen ||
+		part.Checksum != d.header.Checksum || len(part.Fragment) != len(d.header.Fragment) {
+		return fmt.Errorf("%w: part of another message", ErrInvalidUR)
+	}
+	if d.seen[part.SeqNum] {
+		return nil
+	}
+	d.seen[part.SeqNum] = true
+
+	d.pending = append(d.pending, &fountainSymbol{
+		indices: urFragments(part.SeqNum, part.SeqLen, part.Checksum),
+		data:    append([]byte(nil), part.Fragment...),
+	})
+	for progress := true; progress; {
+		progress = false
+		pending := d.pending[:0]
+		for _, sym := range d.pending {
+			d.reduce(sym)
+			switch len(sym.indices) {
+			case 0:
+				continue
+			case 1:
+				d.fragments[sym.indices[0]] = sym.data
+				d.received++
+				progress = true
+				continue
+			}
+			pending = append(pending, sym)
+		}
+		d.pending = pending
+	}
+
+	if d.received < len(d.fragments) {
+		return nil
+	}
+	message := make([]byte, 0, len(d.fragments)*len(d.header.Fragment))
+	for _, f := range d.fragments {
+		message = append(message, f...)
+	}
+	message = message[:d.header.MessageLen]
+	if crc32.ChecksumIEEE(message) != d.header.Checksum {
+		return fmt.Errorf("%w: checksum", ErrInvalidUR)
+	}
+	return d.finish(message)
+}
+
+func (d *URDecoder) reduce(sym *fountainSymbol) {
+	indices := sym.indices[:0]
+	for _, i := range sym.indices {
+		if d.fragments[i] != nil {
+			xorInto(sym.data, d.fragments[i])
+			continue
+		}
+		indices = append(indices, i)
+	}
+	sym.indices = indices
+}
+
+// finish decodes the CBOR byte string of a complete message.
+func (d *URDecoder) finish(message []byte) error {
+	data, rest, err := readCBORBytes(message)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("%w: message", ErrInvalidUR)
+	}
+	d.data = append([]byte{}, data...)
+	d.pending = nil
+	return nil
+}
+
+// Progress returns the fraction of fragments recovered, between 0 and 1.
+func (d *URDecoder) Progress() float32 {
+	switch {
+	case d.data != nil:
+		return 1
+	case d.fragments == nil:
+		return 0
+	}
+	return float32(d.received) / float32(len(d.fragments))
+}
+
+// Data returns the payload once the UR is complete, otherwise nil.
+func (d *URDecoder) Data() []byte {
+	return d.data
+}
+
+// urFragments returns the fragments mixed into the part with the given
+// sequence number, following the UR fountain encoding.
+func urFragments(seqNum uint32, seqLen int, checksum uint32) []int {
+	if int64(seqNum) <= int64(seqLen) {
+		return []int{int(seqNum) - 1}
+	}
+
+	seed := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, seqNum), checksum)
+	rng := newXoshiro256(seed)
+	degree := urDegree(seqLen, rng)
+
+	remaining := make([]int, seqLen)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	shuffled := make([]int, 0, seqLen)
+	for len(remaining) > 0 {
+		i := rng.nextInt(0, len(remaining)-1)
+		shuffled = append(shuffled, remaining[i])
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	return shuffled[:degree]
+}
+
+// urDegree picks the degree of a mixed part, with probabilities proportional
+// to 1/degree, using Vose's alias method like the reference implementation.
+func urDegree(seqLen int, rng *xoshiro256) int {
+	probs := make([]float64, seqLen)
+	var total float64
+	for i := range probs {
+		probs[i] = 1 / float64(i+1)
+		total += probs[i]
+	}
+
+	n := float64(seqLen)
+	p := make([]float64, seqLen)
+	for i, v := range probs {
+		p[i] = v * n / total
+	}
+
+	var small, large []int
+	for i := seqLen - 1; i >= 0; i-- {
+		if p[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	prob := make([]float64, seqLen)
+	alias := make([]int, seqLen)
+	for len(small) > 0 && len(large) > 0 {
+		a := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		prob[a] = p[a]
+		alias[a] = g
+		p[g] += p[a] - 1
+		if p[g] < 1 {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+	for _, i := range large {
+		prob[i] = 1
+	}
+	for _, i := range small {
+		prob[i] = 1
+	}
+
+	r1, r2 := rng.nextDouble(), rng.nextDouble()
+	i := int(n * r1)
+	if r2 < prob[i] {
+		return i + 1
+	}
+	return alias[i] + 1
+}
+
+// xoshiro256 is the xoshiro256** PRNG seeded from the SHA-256 digest of a seed,
+// as specified for the UR fountain encoding.
+type xoshiro256 [4]uint64
+
+func newXoshiro256(seed []byte) *xoshiro256 {
+	digest := sha256.Sum256(seed)
+	var x xoshiro256
+	for i := range x {
+		x[i] = binary.BigEndian.Uint64(digest[8*i:])
+	}
+	return &x
+}
+
+func (x *xoshiro256) next() uint64 {
+	result := bits.RotateLeft64(x[1]*5, 7) * 9
+	t := x[1] << 17
+	x[2] ^= x[0]
+	x[3] ^= x[1]
+	x[1] ^= x[2]
+	x[0] ^= x[3]
+	x[2] ^= t
+	x[3] = bits.RotateLeft64(x[3], 45)
+	return result
+}
+
+func (x *xoshiro256) nextDouble() float64 {
+	return float64(x.next()) / (math.MaxUint64 + 1.0)
+}
+
+func (x *xoshiro256) nextInt(low, high int) int {
+	return int(x.nextDouble()*float64(high-low+1)) + low
+}
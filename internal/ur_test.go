@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestURMultipartReassembly(t *testing.T) {
+	data := make([]byte, 500)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	for _, tc := range []struct {
+		name  string
+		parts func(parts []string) []string
+	}{
+		{name: "in order", parts: func(parts []string) []string { return parts }},
+		{name: "upper case", parts: func(parts []string) []string {
+			upper := make([]string, len(parts))
+			for i, part := range parts {
+				upper[i] = strings.ToUpper(part)
+			}
+			return upper
+		}},
+		{name: "shuffled", parts: func(parts []string) []string {
+			rand.New(rand.NewSource(2)).Shuffle(len(parts), func(i, j int) { parts[i], parts[j] = parts[j], parts[i] })
+			return parts
+		}},
+		{name: "every third lost", parts: func(parts []string) []string {
+			var kept []string
+			for i, part := range parts {
+				if i%3 != 0 {
+					kept = append(kept, part)
+				}
+			}
+			return kept
+		}},
+		{name: "duplicates", parts: func(parts []string) []string { return append(parts[:1], parts...) }},
+	} {
+		enc, err := NewUREncoder(data, 60)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := make([]string, 4*enc.SeqLen())
+		for i := range parts {
+			parts[i] = enc.NextPart()
+		}
+
+		dec := NewURDecoder()
+		for _, part := range tc.parts(parts) {
+			if err := dec.AddPart(part); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		if !bytes.Equal(dec.Data(), data) {
+			t.Errorf("%s: reassembled payload differs, progress %v", tc.name, dec.Progress())
+		}
+	}
+}
+
+func TestURRejectsPartOfAnotherMessage(t *testing.T) {
+	a, _ := NewUREncoder(bytes.Repeat([]byte{1}, 100), 20)
+	b, _ := NewUREncoder(bytes.Repeat([]byte{2}, 100), 20)
+
+	dec := NewURDecoder()
+	if err := dec.AddPart(a.NextPart()); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.AddPart(b.NextPart()); !errors.Is(err, ErrInvalidUR) {
+		t.Errorf("got %v, want %v", err, ErrInvalidUR)
+	}
+}
@@ -0,0 +1,35 @@
+package qrseq
+
+import "iter"
+
+// Chunk is a single chunk of a QRSequence.
+//
+// Data shares memory with the sequence and must not be modified.
+type Chunk struct {
+	Nr    int // chunk number, starting at 0
+	Total int // total number of chunks of the sequence
+	Data  []byte
+}
+
+// Chunks returns an iterator over the chunks of the QRSequence in order of
+// their chunk number. Chunks that have not been received yet are skipped.
+//
+// Returns:
+// - iter.Seq[Chunk]: the iterator over the chunks.
+func (s QRSequence) Chunks() iter.Seq[Chunk] {
+	return func(yield func(Chunk) bool) {
+		for _, chunk := range s.chunks {
+			if chunk == nil {
+				continue
+			}
+			c := Chunk{
+				Nr:    chunk.Nr(),
+				Total: chunk.Tot(),
+				Data:  chunk.Data(),
+			}
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
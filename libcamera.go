@@ -0,0 +1,231 @@
+//go:build linux && !core && libcamera
+
+package qrseq
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"os/exec"
+	"strconv"
+)
+
+// DefaultLibcameraCommand is the program a Libcamera runs if
+// LibcameraConfig.Command is not set.
+const DefaultLibcameraCommand = "rpicam-vid"
+
+// LibcameraConfig describes how a Libcamera runs the camera.
+type LibcameraConfig struct {
+	// Command is the rpicam-vid compatible program capturing the frames, e.g.
+	// libcamera-vid on older systems. Empty means DefaultLibcameraCommand.
+	Command string
+	// Width and Height are the size of the frames in pixels.
+	Width  int
+	Height int
+	// FPS is the frame rate of the camera. Zero leaves it to the program.
+	FPS float64
+	// Args are further arguments passed to the program, e.g. to set the focus
+	// or the exposure.
+	Args []string
+}
+
+// Libcamera captures frames from a Raspberry Pi camera through the libcamera
+// stack, which does not expose the camera as a plain V4L2 device in the
+// default configuration.
+//
+// It runs rpicam-vid writing MJPEG frames to a pipe and decodes them, so no
+// libcamera bindings or cgo are needed. It is only built with the libcamera
+// build tag.
+type Libcamera struct {
+	cmd *exec.Cmd
+	out io.ReadCloser
+	r   *bufio.Reader
+}
+
+// OpenLibcamera starts capturing frames.
+//
+// Parameters:
+// - config: the camera to run.
+//
+// Returns:
+// - *Libcamera: the capturing camera.
+// - error: an error if the frame size is invalid or the program cannot start.
+func OpenLibcamera(config LibcameraConfig) (*Libcamera, error) {
+	if config.Width < 1 || config.Height < 1 {
+		return nil, ErrInvalidFrameSize
+	}
+	if config.Command == "" {
+		config.Command = DefaultLibcameraCommand
+	}
+
+	args := []string{
+		"--timeout", "0",
+		"--nopreview",
+		"--codec", "mjpeg",
+		"--width", strconv.Itoa(config.Width),
+		"--height", strconv.Itoa(config.Height),
+	}
+	if config.FPS > 0 {
+		args = append(args, "--framerate", strconv.FormatFloat(config.FPS, 'f', -1, 64))
+	}
+	args = append(append(args, config.Args...), "--output", "-")
+
+	cmd := exec.Command(config.Command, args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &Libcamera{cmd: cmd, out: out, r: bufio.NewReaderSize(out, 1<<16)}, nil
+}
+
+// ReadFrame waits for the next frame of the camera.
+//
+// Returns:
+//   - image.Image: the decoded frame.
+//   - error: an error if the program ended, or a *FrameError if the frame
+//     cannot be decoded.
+func (l *Libcamera) ReadFrame() (image.Image, error) {
+	img, corrupt, err := l.readFrame()
+	if corrupt {
+		return nil, &FrameError{Err: err}
+	}
+	return img, err
+}
+
+// readFrame reads the next frame. It reports whether an error comes from
+// decoding a corrupted frame rather than from the pipe.
+func (l *Libcamera) readFrame() (image.Image, bool, error) {
+	data, err := readJPEG(l.r)
+	if err != nil {
+		var formatErr jpeg.FormatError
+		return nil, errors.As(err, &formatErr), err
+	}
+	img, err := decodeMJPEG(data)
+	if err != nil {
+		return nil, true, err
+	}
+	return img, false, nil
+}
+
+// Receive is ReceiveContext with a context that is never done.
+//
+// Parameters:
+// - seq: the receiving QRSequence.
+//
+// Returns:
+// - error: the error of ReceiveContext.
+func (l *Libcamera) Receive(seq *QRSequence) error {
+	return l.ReceiveContext(context.Background(), seq)
+}
+
+// ReceiveContext decodes frames of the camera into the receiving QRSequence
+// until it is complete. Corrupted frames and frames without a readable QR code
+// are skipped.
+//
+// Cancelling ctx also interrupts the frame being waited for by stopping the
+// program, after which the camera has to be closed.
+//
+// Parameters:
+// - ctx: the context to stop receiving with.
+// - seq: the receiving QRSequence.
+//
+// Returns:
+//   - error: the error of ctx if it is done first, an error if the program
+//     ended or the QRSequence ended with a terminal error.
+func (l *Libcamera) ReceiveContext(ctx context.Context, seq *QRSequence) error {
+	return ReceiveFrames(ctx, l, seq, func() { l.cmd.Process.Kill() })
+}
+
+// Close stops the program.
+func (l *Libcamera) Close() error {
+	l.cmd.Process.Kill()
+	l.out.Close()
+	l.cmd.Wait()
+	return nil
+}
+
+// readJPEG reads the next JPEG image from a stream of concatenated images,
+// skipping any bytes before its SOI marker. Malformed images are reported as
+// a jpeg.FormatError, after which the next call resynchronizes on the next
+// SOI marker.
+//
+// The segments before the image data are skipped by their length, since they
+// may hold embedded thumbnails. The image data ends at the first EOI marker,
+// which cannot occur inside it.
+func readJPEG(r *bufio.Reader) ([]byte, error) {
+	var prev byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if prev == 0xff && b == 0xd8 {
+			break
+		}
+		prev = b
+	}
+
+	data := []byte{0xff, 0xd8}
+	for {
+		var marker [4]byte
+		if _, err := io.ReadFull(r, marker[:2]); err != nil {
+			return nil, err
+		}
+		if marker[0] != 0xff {
+			return nil, jpeg.FormatError("invalid segment")
+		}
+		data = append(data, marker[:2]...)
+		if marker[1] == 0xd9 {
+			return data, nil
+		}
+		if _, err := io.ReadFull(r, marker[2:]); err != nil {
+			return nil, err
+		}
+		length := int(marker[2])<<8 | int(marker[3])
+		if length < 2 || len(data)+length > maxMJPEGFrame {
+			return nil, jpeg.FormatError("invalid segment")
+		}
+		data = append(data, marker[2:]...)
+		var err error
+		if data, err = appendN(data, r, length-2); err != nil {
+			return nil, err
+		}
+		if marker[1] == 0xda {
+			return readScan(r, data)
+		}
+	}
+}
+
+// appendN appends the next n bytes of r to data.
+func appendN(data []byte, r io.Reader, n int) ([]byte, error) {
+	start := len(data)
+	data = append(data, make([]byte, n)...)
+	if _, err := io.ReadFull(r, data[start:]); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readScan appends the image data following the SOS segment up to and
+// including the EOI marker.
+func readScan(r *bufio.Reader, data []byte) ([]byte, error) {
+	var prev byte
+	for len(data) <= maxMJPEGFrame {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b)
+		if prev == 0xff && b == 0xd9 {
+			return data, nil
+		}
+		prev = b
+	}
+	return nil, jpeg.FormatError("MJPEG frame too large")
+}
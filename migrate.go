@@ -0,0 +1,36 @@
+package qrseq
+
+import "github.com/airsigner/qrseq/internal"
+
+// IsLegacy reports whether the QRSequence was received from legacy chunks,
+// the format of senders that predate the v2 chunk header. Legacy chunks carry
+// no CRC, sequence ID, length or digest, so archives of them should be
+// converted with Migrate.
+//
+// Returns:
+// - bool: true if the chunks of the QRSequence are legacy chunks.
+func (s QRSequence) IsLegacy() bool {
+	return s.layout == internal.LayoutLegacy
+}
+
+// Migrate creates a sender that carries the payload of a complete QRSequence
+// in v2 chunks of the same chunk size, so sequences archived in the legacy
+// format can be sent and received again with current receivers.
+//
+// The payload bytes are left untouched. The new chunks carry the length and,
+// if the chunk size allows, the digest of the payload, which legacy chunks
+// lacked. A v2 chunk holds fewer payload bytes than a legacy chunk of the
+// same size, so the sender may have more chunks. Migrating a QRSequence that
+// already uses v2 chunks is the same as rechunking it to its own chunk size.
+//
+// Returns:
+//   - *QRSequence: a sender for the payload in v2 chunks.
+//   - error: an error if the QRSequence is not complete, its payload has been
+//     drained or the error correction level of the QRSequence is too high for
+//     its chunk size.
+func (s QRSequence) Migrate() (*QRSequence, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+	return s.Rechunk()
+}
@@ -0,0 +1,189 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxMJPEGFrame bounds the size of a frame of an MJPEG stream.
+const maxMJPEGFrame = 16 << 20
+
+// FrameError is the error returned by the ReadFrame methods of the cameras
+// and streams if a frame was captured but cannot be decoded, e.g. because it
+// was corrupted on the way. The source keeps delivering frames, so such
+// errors can be skipped. It wraps the error of decoding the frame.
+type FrameError struct {
+	Err error
+}
+
+func (e *FrameError) Error() string {
+	return "corrupted frame: " + e.Err.Error()
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// MJPEGStream reads frames from an MJPEG stream served over HTTP as
+// multipart/x-mixed-replace, as IP and document cameras do, so a fixed-mount
+// camera on the isolated network can act as the receiver's eye.
+type MJPEGStream struct {
+	body io.ReadCloser
+	mr   *multipart.Reader
+}
+
+// OpenMJPEGStream requests an MJPEG stream.
+//
+// Parameters:
+// - url: the URL of the stream.
+//
+// Returns:
+//   - *MJPEGStream: the open stream.
+//   - error: an error if the request fails or the response is no multipart
+//     stream.
+func OpenMJPEGStream(url string) (*MJPEGStream, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: unexpected HTTP status %s", ErrNotMJPEGStream, resp.Status)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		resp.Body.Close()
+		return nil, ErrNotMJPEGStream
+	}
+	return &MJPEGStream{
+		body: resp.Body,
+		mr:   multipart.NewReader(resp.Body, params["boundary"]),
+	}, nil
+}
+
+// ReadFrame waits for the next frame of the stream.
+//
+// Returns:
+//   - image.Image: the decoded frame.
+//   - error: an error if the stream ends or fails, or a *FrameError if the
+//     frame cannot be decoded.
+func (m *MJPEGStream) ReadFrame() (image.Image, error) {
+	img, corrupt, err := m.readFrame()
+	if corrupt {
+		return nil, &FrameError{Err: err}
+	}
+	return img, err
+}
+
+// readFrame reads the next frame. It reports whether an error comes from
+// decoding a corrupted frame rather than from the stream.
+func (m *MJPEGStream) readFrame() (image.Image, bool, error) {
+	part, err := m.mr.NextPart()
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := io.ReadAll(io.LimitReader(part, maxMJPEGFrame+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) > maxMJPEGFrame {
+		return nil, false, ErrMJPEGFrameTooLarge
+	}
+	img, err := decodeMJPEG(data)
+	if err != nil {
+		return nil, true, err
+	}
+	return img, false, nil
+}
+
+// Receive is ReceiveContext with a context that is never done.
+//
+// Parameters:
+// - seq: the receiving QRSequence.
+//
+// Returns:
+// - error: the error of ReceiveContext.
+func (m *MJPEGStream) Receive(seq *QRSequence) error {
+	return m.ReceiveContext(context.Background(), seq)
+}
+
+// ReceiveContext decodes frames of the stream into the receiving QRSequence
+// until it is complete. Corrupted frames and frames without a readable QR code
+// are skipped.
+//
+// Cancelling ctx also interrupts the frame being waited for by closing the
+// connection, after which the stream has to be closed.
+//
+// Parameters:
+// - ctx: the context to stop receiving with.
+// - seq: the receiving QRSequence.
+//
+// Returns:
+//   - error: the error of ctx if it is done first, ErrSourceEnded if the
+//     stream ends, an error if it fails, or the terminal error of the
+//     QRSequence.
+func (m *MJPEGStream) ReceiveContext(ctx context.Context, seq *QRSequence) error {
+	return ReceiveFrames(ctx, m, seq, func() { m.body.Close() })
+}
+
+// Close closes the connection of the stream.
+func (m *MJPEGStream) Close() error {
+	return m.body.Close()
+}
+
+// mjpegHuffmanTables is the DHT segment with the standard Huffman tables of
+// the JPEG specification, annex K.3, which MJPEG frames use implicitly when
+// they leave out their own tables. It is taken from the output of
+// image/jpeg, whose encoder uses these tables.
+var mjpegHuffmanTables = sync.OnceValue(func() []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)), nil)
+	data := buf.Bytes()
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		length := int(data[i+2])<<8 | int(data[i+3])
+		if data[i+1] == 0xc4 {
+			return data[i : i+2+length]
+		}
+		i += 2 + length
+	}
+	return nil
+})
+
+// decodeMJPEG decodes an MJPEG frame, adding the standard Huffman tables if
+// the frame has none.
+func decodeMJPEG(data []byte) (image.Image, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, jpeg.FormatError("missing SOI marker")
+	}
+
+	hasTables := false
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		marker := data[i+1]
+		if marker == 0xc4 {
+			hasTables = true
+		}
+		if marker == 0xda {
+			break
+		}
+		i += 2 + (int(data[i+2])<<8 | int(data[i+3]))
+	}
+	if !hasTables {
+		tables := mjpegHuffmanTables()
+		frame := make([]byte, 0, len(data)+len(tables))
+		frame = append(append(append(frame, data[:2]...), tables...), data[2:]...)
+		data = frame
+	}
+	return jpeg.Decode(bytes.NewReader(data))
+}
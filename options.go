@@ -0,0 +1,233 @@
+package qrseq
+
+import (
+	"crypto/ed25519"
+	"io"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// DefaultChunkSize is the chunk size of senders created by New without
+// WithChunkSize.
+const DefaultChunkSize = ChunkSize256
+
+// Option configures a QRSequence created by New or NewEmpty.
+type Option func(*options) error
+
+type options struct {
+	chunkSize   ChunkSize
+	ecLevel     ECLevel
+	compression Compression
+	key         []byte
+	signKey     ed25519.PrivateKey
+	decoder     Decoder
+	tee         ChunkStore
+	text        TextEncoding
+	appTag      uint16
+	padChunks   int
+	rand        io.Reader
+}
+
+// Compression selects how the payload is compressed before chunking.
+type Compression uint8
+
+const (
+	// CompressionNone sends the payload as it is.
+	CompressionNone Compression = iota
+	// CompressionGzip compresses the payload with gzip, which pays off for
+	// text-heavy payloads such as JSON or PSBTs in base64.
+	CompressionGzip
+)
+
+// TextEncoding selects how the chunks are encoded into the text of their QR
+// codes.
+type TextEncoding uint8
+
+const (
+	// TextBase64 encodes the chunks as base64, which QR codes hold in byte
+	// mode.
+	TextBase64 TextEncoding = TextEncoding(internal.TextBase64)
+	// TextBase45 encodes the chunks as Base45 (RFC 9285), as EU Digital
+	// COVID Certificates do. QR codes hold it in alphanumeric mode, which
+	// packs about 1.4 times more payload into a QR code of the same version,
+	// so the QR codes get smaller and easier to scan, and larger chunk sizes
+	// fit at higher error correction levels.
+	TextBase45 TextEncoding = TextEncoding(internal.TextBase45)
+	// TextRaw puts the chunk bytes into the QR code as they are, in byte
+	// mode, which saves the third base64 adds. Receivers tell raw frames
+	// from base64 and Base45 by their first byte. Legacy chunks, which
+	// AsSender keeps for payloads received from legacy senders, are still
+	// sent as base64. Readers that only return text, such as many scanner
+	// apps, cannot read raw frames.
+	TextRaw TextEncoding = TextEncoding(internal.TextRaw)
+)
+
+// WithChunkSize sets the size of the chunks of a sender. It is ignored by
+// receivers, which take the chunk size from the first chunk.
+//
+// Parameters:
+// - chunkSize: a ChunkSize enum value specifying the size of each chunk.
+//
+// Returns:
+// - Option: the option.
+func WithChunkSize(chunkSize ChunkSize) Option {
+	return func(o *options) error {
+		if !internal.IsValidChunkSize(uint16(chunkSize)) {
+			return ErrInvalidChunkSize
+		}
+		o.chunkSize = chunkSize
+		return nil
+	}
+}
+
+// WithECLevel sets the error correction level the QR codes of the sequence
+// are rendered at, unless the RenderOptions passed to a render method set
+// their own. It is kept by the senders derived with AsSender, and by those
+// derived with Rechunk unless they pass it again.
+//
+// Parameters:
+// - level: the error correction level.
+//
+// Returns:
+// - Option: the option.
+func WithECLevel(level ECLevel) Option {
+	return func(o *options) error {
+		if level > ECLevelHigh {
+			return ErrInvalidECLevel
+		}
+		o.ecLevel = level
+		return nil
+	}
+}
+
+// WithCompression compresses the payload before it is chunked. Receivers
+// decompress it transparently on completion, so the option is ignored by
+// them.
+//
+// Parameters:
+// - c: the compression to apply.
+//
+// Returns:
+// - Option: the option.
+func WithCompression(c Compression) Option {
+	return func(o *options) error {
+		if c > CompressionGzip {
+			return ErrInvalidCompression
+		}
+		o.compression = c
+		return nil
+	}
+}
+
+// WithTextEncoding sets the encoding of the text of the QR codes of a sender.
+// It is kept by the senders derived with AsSender, and by those derived with
+// Rechunk unless they pass it again. Receivers accept all encodings.
+//
+// Parameters:
+// - enc: the text encoding.
+//
+// Returns:
+// - Option: the option.
+func WithTextEncoding(enc TextEncoding) Option {
+	return func(o *options) error {
+		if enc > TextRaw {
+			return ErrInvalidTextEncoding
+		}
+		o.text = enc
+		return nil
+	}
+}
+
+// WithAppTag tags a sequence with the tag of the application that sends it,
+// so several products using qrseq in the same environment ignore each
+// other's sequences.
+//
+// Senders carry the tag in the header of every chunk, which takes two bytes
+// of each chunk, and so do the fountain frames of NewFountain and NewBackup.
+// Receivers with a tag reject frames with another tag or without one with a
+// *ChunkMismatchError, before they can establish or re-baseline the sequence. Receivers without a tag accept
+// sequences of any application, while receivers of versions that predate
+// the tag reject tagged chunks as invalid.
+//
+// Parameters:
+// - tag: the application tag, or zero for no tag.
+//
+// Returns:
+// - Option: the option.
+func WithAppTag(tag uint16) Option {
+	return func(o *options) error {
+		o.appTag = tag
+		return nil
+	}
+}
+
+// WithEncryption encrypts and authenticates the payload with AES-256-GCM
+// before it is chunked, after compressing it if compression is enabled.
+// Receivers decrypt the payload with the same key on completion.
+//
+// Parameters:
+// - key: the 32 byte key.
+//
+// Returns:
+// - Option: the option.
+func WithEncryption(key []byte) Option {
+	return func(o *options) error {
+		if len(key) != keySize {
+			return ErrInvalidKeySize
+		}
+		o.key = key
+		return nil
+	}
+}
+
+// WithSigning signs the payload with Ed25519 before it is compressed and
+// encrypted. Receivers check the signature against a TrustStore in Finalize,
+// see RequireSignature.
+//
+// Parameters:
+// - key: the Ed25519 private key of the sender.
+//
+// Returns:
+// - Option: the option.
+func WithSigning(key ed25519.PrivateKey) Option {
+	return func(o *options) error {
+		if len(key) != ed25519.PrivateKeySize {
+			return ErrInvalidPrivateKey
+		}
+		o.signKey = key
+		return nil
+	}
+}
+
+// WithRand sets the source of randomness of the sequence, like SetRand. Unlike
+// SetRand, it applies before a sender encodes the payload, so it also
+// provides the encryption nonce, and senders created with a deterministic
+// source produce the same frames every time. NewFountain and NewBackup draw
+// their sequence ID from it too.
+//
+// Parameters:
+//   - r: the source of randomness, or nil for the default crypto/rand.Reader.
+//
+// Returns:
+// - Option: the option.
+func WithRand(r io.Reader) Option {
+	return func(o *options) error {
+		o.rand = r
+		return nil
+	}
+}
+
+// newOptions applies opts to the default options.
+func newOptions(opts []Option) (options, error) {
+	return applyOptions(options{chunkSize: DefaultChunkSize}, opts)
+}
+
+// applyOptions applies opts on top of the options o.
+func applyOptions(o options, opts []Option) (options, error) {
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return options{}, err
+		}
+	}
+	return o, nil
+}
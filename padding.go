@@ -0,0 +1,75 @@
+package qrseq
+
+import "github.com/airsigner/qrseq/internal"
+
+// WithPadding pads the payload of a sender so that an observer of the frames
+// cannot infer the payload size from the number of frames or the fill of the
+// last one.
+//
+// The payload is padded after it has been compressed and encrypted, so the
+// padding is neither compressed away nor needs to be known before, and fills
+// every chunk completely, including the header fields of the chunks. The
+// padded payload spans a power of two number of chunks, but at least
+// minChunks. Sending all transfers that fit into the same minChunks hides
+// their sizes completely. The padding follows ISO/IEC 7816-4: a 0x80 byte
+// followed by zero bytes. Receivers remove it transparently on completion, so
+// the option is ignored by them.
+//
+// Parameters:
+// - minChunks: the minimum number of chunks of the padded payload.
+//
+// Returns:
+// - Option: the option.
+func WithPadding(minChunks int) Option {
+	return func(o *options) error {
+		if minChunks < 1 || minChunks > internal.MaxChunks {
+			return ErrInvalidMinChunks
+		}
+		o.padChunks = minChunks
+		return nil
+	}
+}
+
+// pad pads the encoded payload data of a sender with the options o, and
+// returns the padded payload and the encodings applied to it.
+func pad(data []byte, encoding uint8, o options) ([]byte, uint8, error) {
+	encoding |= internal.EncodingPadding
+	cs := uint16(o.chunkSize)
+	capacity := func(chunks int) int {
+		return internal.Capacity(cs, chunks, encoding, o.appTag)
+	}
+
+	ds := capacity(2) - capacity(1)
+	overhead := ds - capacity(1)
+	needed := (len(data) + 1 + overhead + ds - 1) / ds
+	chunks := 1
+	for chunks < needed {
+		chunks *= 2
+	}
+	chunks = max(chunks, o.padChunks)
+	if chunks > internal.MaxChunks {
+		chunks = max(needed, o.padChunks)
+	}
+	if chunks > internal.MaxChunks {
+		return nil, 0, ErrPayloadTooLarge
+	}
+
+	padded := make([]byte, capacity(chunks))
+	copy(padded, data)
+	padded[len(data)] = 0x80
+	return padded, encoding, nil
+}
+
+// unpad removes the padding added by pad.
+func unpad(data []byte) ([]byte, error) {
+	for i := len(data) - 1; i >= 0; i-- {
+		switch data[i] {
+		case 0x00:
+			continue
+		case 0x80:
+			return data[:i], nil
+		}
+		break
+	}
+	return nil, ErrInvalidPadding
+}
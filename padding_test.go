@@ -0,0 +1,71 @@
+package qrseq
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestPaddingHidesCompressedSize(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sizes := []int{1, 100, 900, 1500}
+	key := bytes.Repeat([]byte{7}, keySize)
+
+	frames := -1
+	for _, size := range sizes {
+		// half random, half repeated, so gzip shrinks the payloads unevenly
+		data := make([]byte, size)
+		r.Read(data[:size/2])
+
+		sender, err := New(data, WithChunkSize(ChunkSize128), WithCompression(CompressionGzip),
+			WithEncryption(key), WithAppTag(42), WithPadding(16))
+		if err != nil {
+			t.Fatalf("size %d: New: %v", size, err)
+		}
+		if frames == -1 {
+			frames = len(sender.chunks)
+		}
+		if len(sender.chunks) != frames {
+			t.Errorf("size %d: got %d frames, want %d", size, len(sender.chunks), frames)
+		}
+		for nr, chunk := range sender.chunks {
+			if got := len(chunk.Bytes()); got != int(ChunkSize128) {
+				t.Errorf("size %d: chunk %d has %d bytes, want %d", size, nr, got, ChunkSize128)
+			}
+		}
+
+		payloads, err := sender.Payloads()
+		if err != nil {
+			t.Fatalf("size %d: Payloads: %v", size, err)
+		}
+		receiver := NewEmpty(WithEncryption(key), WithAppTag(42))
+		for _, payload := range payloads {
+			if err := receiver.AddPayload(payload); err != nil {
+				t.Fatalf("size %d: AddPayload: %v", size, err)
+			}
+		}
+		if !bytes.Equal(receiver.Data(), data) {
+			t.Errorf("size %d: received payload differs", size)
+		}
+	}
+}
+
+func TestPaddingGrowsInPowersOfTwo(t *testing.T) {
+	for _, tc := range []struct {
+		size, minChunks, want int
+	}{
+		{size: 10, minChunks: 1, want: 1},
+		{size: 100, minChunks: 1, want: 2},
+		{size: 300, minChunks: 1, want: 4},
+		{size: 300, minChunks: 5, want: 5},
+		{size: 1000, minChunks: 2, want: 16},
+	} {
+		sender, err := New(make([]byte, tc.size), WithChunkSize(ChunkSize128), WithPadding(tc.minChunks))
+		if err != nil {
+			t.Fatalf("size %d: New: %v", tc.size, err)
+		}
+		if got := len(sender.chunks); got != tc.want {
+			t.Errorf("size %d, min %d: got %d chunks, want %d", tc.size, tc.minChunks, got, tc.want)
+		}
+	}
+}
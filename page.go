@@ -0,0 +1,100 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// PageImage renders a page of the Backup as a grid of its QR codes, ready to
+// be printed. Positions are filled row by row; unused positions stay blank, so
+// every group keeps its place on all pages.
+//
+// Parameters:
+// - page: the number of the page, starting at 0.
+// - opt: the RenderOptions to render the QR codes with.
+//
+// Returns:
+//   - image.Image: the page.
+//   - error: an error if page is out of range or a QR code cannot be
+//     rendered.
+func (b Backup) PageImage(page int, opt RenderOptions) (image.Image, error) {
+	if page < 0 || page >= len(b.Pages) {
+		return nil, ErrPageOutOfRange
+	}
+
+	texts := b.Pages[page]
+	codes := make([]image.Image, len(texts))
+	var cell image.Point
+	for i, text := range texts {
+		if text == "" {
+			continue
+		}
+		img, err := internal.RenderText(text, opt.internal())
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = img
+		cell.X = max(cell.X, img.Bounds().Dx())
+		cell.Y = max(cell.Y, img.Bounds().Dy())
+	}
+
+	cols := max(1, int(math.Ceil(math.Sqrt(float64(len(texts))))))
+	rows := max(1, (len(texts)+cols-1)/cols)
+	out := image.NewGray(image.Rect(0, 0, cols*cell.X, rows*cell.Y))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	for i, code := range codes {
+		if code == nil {
+			continue
+		}
+		bounds := code.Bounds()
+		at := image.Pt(i%cols*cell.X+(cell.X-bounds.Dx())/2, i/cols*cell.Y+(cell.Y-bounds.Dy())/2)
+		draw.Draw(out, image.Rectangle{Min: at, Max: at.Add(bounds.Size())}, code, bounds.Min, draw.Src)
+	}
+	return out, nil
+}
+
+// DecodePage decodes all QR codes in an image, e.g. a scanned page of a
+// Backup, into the QRSequence, like DecodeImage does for a single code.
+//
+// Parameters:
+// - img: an image.Image holding one or more QR codes.
+//
+// Returns:
+//   - int: the number of QR codes that were added to the QRSequence.
+//   - error: a *DecodeError if no QR code could be read, or the terminal
+//     error of the QRSequence.
+func (s *QRSequence) DecodePage(img image.Image) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if !s.acceptsFrames() {
+		return 0, nil
+	}
+
+	texts, err := internal.ReadImageAll(img)
+	if err != nil {
+		_, err = s.frameRead(img, nil, 0, err)
+		return 0, err
+	}
+	added := 0
+	for _, text := range texts {
+		frame, err := internal.DecodeText(text)
+		if err != nil {
+			s.countFailure(newDecodeError(err))
+			continue
+		}
+		err = s.receive(frame)
+		if s.err != nil {
+			return added, s.err
+		}
+		if err == nil {
+			added++
+		}
+	}
+	return added, nil
+}
@@ -0,0 +1,272 @@
+package qrseq
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/sha256"
+	"io"
+)
+
+// A pairing frame is a single chunk sequence whose payload is pairingMagic,
+// the pairing version, the kind of the frame and its body: the commitment to
+// the X25519 public key of the device, or the public key itself.
+const (
+	pairingMagic   = "QRPK"
+	pairingVersion = 2
+	pairingInfo    = "qrseq pairing v1"
+	pairingCommit  = "qrseq pairing commit v2"
+)
+
+// pairingChunkSize is the chunk size of pairing frames, the smallest one that
+// holds a pairing frame together with the length and digest in chunk 0.
+const pairingChunkSize = ChunkSize128
+
+// Kinds of pairing frames.
+const (
+	pairingKindCommit = 1
+	pairingKindKey    = 2
+)
+
+// SessionKeySize is the size of the session key derived by a Pairing in bytes.
+const SessionKeySize = 32
+
+// Pairing bootstraps a shared session key between two devices that can only
+// see each other's screens.
+//
+// Each device creates a Pairing, displays its QRCode and scans the QR code of
+// the other device with DecodeImage, until Paired reports true. Both then
+// derive the same session key from an X25519 key agreement. The exchange
+// itself is not authenticated, so users compare the short authentication
+// strings of both devices and Confirm the Pairing if they match. WithPairing
+// then encrypts later sequences with the session key.
+//
+// The QR code first shows a commitment to the public key of the device and
+// switches to the key itself once the commitment or the key of the other
+// device has been scanned. A device accepts the key of the other device only
+// if it matches the commitment scanned before, or if no commitment was
+// scanned and its own key has therefore not been shown yet. Once set, the key
+// of the other device cannot be replaced by a later frame. So the key of the
+// other device is fixed before the own key is revealed, and a machine in the
+// middle cannot search for keys that make the short authentication strings
+// of both devices match.
+type Pairing struct {
+	key        *ecdh.PrivateKey
+	peer       *ecdh.PublicKey
+	peerCommit []byte
+	confirmed  bool
+	rand       io.Reader
+}
+
+// NewPairing creates a new Pairing with a fresh X25519 key pair.
+//
+// Parameters:
+//   - rand: the source of randomness for the key pair and the sequence IDs of
+//     the pairing frames, usually crypto/rand.Reader.
+//
+// Returns:
+// - *Pairing: the new Pairing.
+// - error: an error if the key pair cannot be generated.
+func NewPairing(rand io.Reader) (*Pairing, error) {
+	key, err := ecdh.X25519().GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	return &Pairing{key: key, rand: rand}, nil
+}
+
+// PublicKey returns the public key of this device.
+func (p *Pairing) PublicKey() []byte {
+	return p.key.PublicKey().Bytes()
+}
+
+// Paired reports whether the public key of the other device has been set.
+func (p *Pairing) Paired() bool {
+	return p.peer != nil
+}
+
+// Sequence returns the single chunk sequence of the pairing frame this device
+// displays: the commitment to its public key until the commitment or the key
+// of the other device has been read, and the public key afterwards.
+//
+// Returns:
+// - *QRSequence: the sender of the pairing frame.
+func (p *Pairing) Sequence() *QRSequence {
+	payload := append([]byte(pairingMagic), pairingVersion)
+	if p.peerCommit == nil && p.peer == nil {
+		payload = append(payload, pairingKindCommit)
+		payload = append(payload, commitKey(p.PublicKey())...)
+	} else {
+		payload = append(payload, pairingKindKey)
+		payload = append(payload, p.PublicKey()...)
+	}
+	return newSender(payload, 0, options{chunkSize: pairingChunkSize, rand: p.rand})
+}
+
+// ReadSequence reads the pairing frame of the other device from its completed
+// pairing sequence. A commitment is kept until the key arrives, a key is set
+// as the peer. Once the peer is set, the same key is ignored and any other
+// key is rejected.
+//
+// Parameters:
+// - seq: the completed receiving QRSequence of the pairing frame.
+//
+// Returns:
+//   - error: an error if the sequence is not complete or does not hold a
+//     pairing frame, ErrCommitmentMismatch if it holds a key that does not
+//     match the commitment read before, or ErrPeerChanged if it holds
+//     another key than the peer set before.
+func (p *Pairing) ReadSequence(seq *QRSequence) error {
+	if !seq.IsComplete() {
+		return ErrSequenceIncomplete
+	}
+	payload := seq.Data()
+	if !bytes.HasPrefix(payload, []byte(pairingMagic)) {
+		return ErrNotPairingFrame
+	}
+	payload = payload[len(pairingMagic):]
+	if len(payload) < 2 || payload[0] != pairingVersion {
+		return ErrPairingVersion
+	}
+
+	kind, body := payload[1], payload[2:]
+	switch kind {
+	case pairingKindCommit:
+		if len(body) != sha256.Size {
+			return ErrInvalidCommitment
+		}
+		if bytes.Equal(body, commitKey(p.PublicKey())) {
+			return ErrOwnKey
+		}
+		if p.peer == nil {
+			p.peerCommit = bytes.Clone(body)
+		}
+		return nil
+	case pairingKindKey:
+		if p.peer != nil {
+			// the own key may have been shown by now, so the peer is fixed
+			if !bytes.Equal(body, p.peer.Bytes()) {
+				return ErrPeerChanged
+			}
+			return nil
+		}
+		if p.peerCommit != nil && !hmac.Equal(commitKey(body), p.peerCommit) {
+			return ErrCommitmentMismatch
+		}
+		return p.SetPeer(body)
+	}
+	return ErrUnknownPairingFrame
+}
+
+// commitKey returns the commitment to a public key.
+func commitKey(publicKey []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(pairingCommit))
+	h.Write(publicKey)
+	return h.Sum(nil)
+}
+
+// SetPeer sets the public key of the other device, for keys that were
+// exchanged in another way.
+//
+// Parameters:
+// - publicKey: the X25519 public key of the other device.
+//
+// Returns:
+//   - error: an error if the key is invalid or is the own public key of this
+//     device, which happens when a pairing code is reflected back at it.
+func (p *Pairing) SetPeer(publicKey []byte) error {
+	peer, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	if peer.Equal(p.key.PublicKey()) {
+		return ErrOwnKey
+	}
+	p.peer = peer
+	return nil
+}
+
+// SessionKey derives the session key shared with the peer.
+//
+// The key is derived with HKDF-SHA256 from the X25519 shared secret and both
+// public keys in a fixed order, so both devices derive the same key.
+//
+// Returns:
+// - []byte: the SessionKeySize bytes long session key.
+// - error: an error if no peer is set or the key agreement fails.
+func (p *Pairing) SessionKey() ([]byte, error) {
+	if p.peer == nil {
+		return nil, ErrNoPeer
+	}
+	secret, err := p.key.ECDH(p.peer)
+	if err != nil {
+		return nil, err
+	}
+	return hkdfSHA256(secret, p.transcript(pairingInfo), SessionKeySize), nil
+}
+
+// Confirm records that the user compared the short authentication strings of
+// both devices and found them equal, so the session key can be used.
+//
+// Returns:
+// - error: ErrNoPeer if no peer is set.
+func (p *Pairing) Confirm() error {
+	if p.peer == nil {
+		return ErrNoPeer
+	}
+	p.confirmed = true
+	return nil
+}
+
+// WithPairing encrypts and authenticates the payload with the session key of
+// a confirmed Pairing, like WithEncryption. Senders and receivers on the two
+// devices use it with their own Pairing.
+//
+// Parameters:
+// - p: the Pairing whose short authentication string was confirmed.
+//
+// Returns:
+// - Option: the option, which fails with ErrNotConfirmed before Confirm.
+func WithPairing(p *Pairing) Option {
+	return func(o *options) error {
+		if !p.confirmed {
+			return ErrNotConfirmed
+		}
+		key, err := p.SessionKey()
+		if err != nil {
+			return err
+		}
+		return WithEncryption(key)(o)
+	}
+}
+
+// transcript returns label followed by both public keys, ordered so that both
+// devices produce the same bytes.
+func (p *Pairing) transcript(label string) []byte {
+	own, peer := p.PublicKey(), p.peer.Bytes()
+	if bytes.Compare(own, peer) > 0 {
+		own, peer = peer, own
+	}
+	info := append([]byte(label), own...)
+	return append(info, peer...)
+}
+
+// hkdfSHA256 derives n bytes from secret with HKDF-SHA256 (RFC 5869) and an
+// empty salt.
+func hkdfSHA256(secret, info []byte, n int) []byte {
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	var okm, block []byte
+	for i := byte(1); len(okm) < n; i++ {
+		expand := hmac.New(sha256.New, prk)
+		expand.Write(block)
+		expand.Write(info)
+		expand.Write([]byte{i})
+		block = expand.Sum(nil)
+		okm = append(okm, block...)
+	}
+	return okm[:n]
+}
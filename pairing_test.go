@@ -0,0 +1,188 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	mrand "math/rand"
+	"slices"
+	"testing"
+)
+
+// newTestPairing creates a Pairing with a fixed key and sequence IDs, so tests
+// that render pairing QR codes render the same ones every run. The reader
+// misses a small share of codes, which random frames would turn into flaky
+// tests. The key is not drawn from the seeded source, since key generation
+// reads a random number of bytes on purpose.
+func newTestPairing(t *testing.T, seed byte) *Pairing {
+	t.Helper()
+	key, err := ecdh.X25519().NewPrivateKey(bytes.Repeat([]byte{seed}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Pairing{key: key, rand: mrand.New(mrand.NewSource(int64(seed)))}
+}
+
+func TestPairingRoundTrip(t *testing.T) {
+	a, b := newTestPairing(t, 1), newTestPairing(t, 2)
+
+	// each device shows its code and scans the other one, in turns, until
+	// both are paired: commitments first, keys second
+	opt := RenderOptions{BlockSize: 4}
+	for round := 0; !a.Paired() || !b.Paired(); round++ {
+		if round == 3 {
+			t.Fatal("pairing did not complete")
+		}
+		for _, dir := range [][2]*Pairing{{a, b}, {b, a}} {
+			img, err := dir[0].QRCode(opt)
+			if err != nil {
+				t.Fatalf("round %d: QRCode: %v", round, err)
+			}
+			if err := dir[1].DecodeImage(img); err != nil {
+				t.Fatalf("round %d: DecodeImage: %v", round, err)
+			}
+		}
+	}
+
+	keyA, err := a.SessionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := b.SessionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(keyA, keyB) {
+		t.Error("session keys differ")
+	}
+	sasA, _ := a.SAS()
+	sasB, _ := b.SAS()
+	if !slices.Equal(sasA, sasB) {
+		t.Errorf("SAS differ: %v, %v", sasA, sasB)
+	}
+}
+
+func TestPairingRejectsKeyNotMatchingCommitment(t *testing.T) {
+	a, b, mitm := newTestPairing(t, 1), newTestPairing(t, 2), newTestPairing(t, 3)
+	opt := RenderOptions{BlockSize: 4}
+
+	commit, err := a.QRCode(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.DecodeImage(commit); err != nil {
+		t.Fatal(err)
+	}
+
+	// the machine in the middle reveals a key after seeing the key of b
+	if err := mitm.ReadSequence(receive(t, b.Sequence())); err != nil {
+		t.Fatal(err)
+	}
+	key, err := mitm.QRCode(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.DecodeImage(key); err == nil {
+		t.Error("key not matching the commitment accepted")
+	}
+	if b.Paired() {
+		t.Error("paired with a key not matching the commitment")
+	}
+}
+
+// receive passes the payloads of a sender to a new receiver.
+func receive(t *testing.T, sender *QRSequence) *QRSequence {
+	t.Helper()
+	payloads, err := sender.Payloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := NewEmpty()
+	for _, payload := range payloads {
+		if err := seq.AddPayload(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return seq
+}
+
+func TestPairingRejectsKeyAfterReveal(t *testing.T) {
+	a, _ := NewPairing(rand.Reader)
+	b, _ := NewPairing(rand.Reader)
+	mitm, _ := NewPairing(rand.Reader)
+
+	// b misses the commitment of a and reads its key right away, then
+	// reveals its own key
+	if err := a.ReadSequence(receive(t, b.Sequence())); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ReadSequence(receive(t, a.Sequence())); err != nil {
+		t.Fatal(err)
+	}
+	if err := mitm.ReadSequence(receive(t, b.Sequence())); err != nil {
+		t.Fatal(err)
+	}
+
+	// the machine in the middle now knows the key of b and shows its own
+	if err := b.ReadSequence(receive(t, mitm.Sequence())); !errors.Is(err, ErrPeerChanged) {
+		t.Errorf("got %v, want %v", err, ErrPeerChanged)
+	}
+	if !bytes.Equal(b.peer.Bytes(), a.PublicKey()) {
+		t.Error("peer replaced after the own key was revealed")
+	}
+	if err := b.ReadSequence(receive(t, a.Sequence())); err != nil {
+		t.Errorf("duplicate peer key: %v", err)
+	}
+}
+
+func TestWithPairing(t *testing.T) {
+	a, _ := NewPairing(rand.Reader)
+	b, _ := NewPairing(rand.Reader)
+	for _, step := range [][2]*Pairing{{a, b}, {b, a}, {a, b}} {
+		if err := step[1].ReadSequence(receive(t, step[0].Sequence())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := []byte("sealed with the session key")
+	if _, err := New(data, WithPairing(a)); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("got %v before Confirm, want %v", err, ErrNotConfirmed)
+	}
+	if err := a.Confirm(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Confirm(); err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := New(data, WithPairing(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads, err := sender.Payloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		opt  Option
+		want error
+	}{
+		{name: "peer", opt: WithPairing(b)},
+		{name: "other key", opt: WithEncryption(bytes.Repeat([]byte{1}, keySize)), want: ErrDecryptionFailed},
+	} {
+		receiver := NewEmpty(tc.opt)
+		for _, payload := range payloads {
+			receiver.AddPayload(payload)
+		}
+		if !errors.Is(receiver.Err(), tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, receiver.Err(), tc.want)
+		}
+		if tc.want == nil && !bytes.Equal(receiver.Data(), data) {
+			t.Errorf("%s: received payload differs", tc.name)
+		}
+	}
+}
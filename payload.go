@@ -0,0 +1,72 @@
+package qrseq
+
+import "github.com/airsigner/qrseq/internal"
+
+// Payloads returns the text encoded into the QR code of every chunk of the
+// QRSequence.
+//
+// Servers that leave rendering to their clients send these strings and let
+// the client draw the QR codes. They are available in the core build, which
+// has no image dependencies. With TextRaw the strings hold the binary chunks,
+// which clients have to put into the QR codes in byte mode.
+//
+// Returns:
+// - []string: the QR code text of each chunk in order.
+// - error: an error if the QRSequence is not complete.
+func (s QRSequence) Payloads() ([]string, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+
+	payloads := make([]string, len(s.chunks))
+	for i, chunk := range s.chunks {
+		payloads[i] = chunk.Text(internal.TextEncoding(s.textEncoding))
+	}
+	return payloads, nil
+}
+
+// AddPayload adds the chunk encoded in the text of a QR code scanned by a
+// client to the QRSequence. It is the counterpart of Payloads and the same as
+// DecodeText.
+//
+// Parameters:
+// - text: the text of the scanned QR code.
+//
+// Returns:
+//   - error: a *DecodeError if the text does not hold a valid chunk, or the
+//     terminal error of the QRSequence.
+func (s *QRSequence) AddPayload(text string) error {
+	return s.DecodeText(text)
+}
+
+// DecodeText decodes the text of a QR code into the QRSequence.
+//
+// It is the counterpart of DecodeImage for the core build and for callers
+// that scan QR codes with their own reader, such as a mobile SDK or zbar. The
+// text is decoded from base64 or Base45 and the chunk it carries is added
+// like the chunk of a decoded image, so the outcome is counted in Stats and
+// reported to the callbacks alike. Frames sent with TextRaw only survive
+// readers that pass the bytes of the QR code through unchanged.
+//
+// Parameters:
+// - text: the text of the scanned QR code.
+//
+// Returns:
+//   - error: a *DecodeError if the text does not hold a valid chunk, or the
+//     terminal error of the QRSequence.
+func (s *QRSequence) DecodeText(text string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if !s.acceptsFrames() {
+		return nil
+	}
+
+	frame, err := internal.DecodeText(text)
+	if err != nil {
+		decodeErr := &DecodeError{Failure: FailureInvalid, Err: err}
+		s.countFailure(decodeErr)
+		return decodeErr
+	}
+	return s.receive(frame)
+}
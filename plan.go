@@ -0,0 +1,97 @@
+package qrseq
+
+import "fmt"
+
+// Plan describes the sender created by NewPlanned and the adjustments made to
+// the requested parameters.
+type Plan struct {
+	// RequestedChunkSize is the chunk size asked for with WithChunkSize, or
+	// DefaultChunkSize.
+	RequestedChunkSize ChunkSize
+	// ChunkSize is the chunk size the sender was created with.
+	ChunkSize ChunkSize
+	// ECLevel is the highest error correction level the frames are rendered
+	// at with the RenderOptions passed to NewPlanned, or ECLevelDefault if
+	// no level was asked for.
+	ECLevel ECLevel
+	// Frames is the number of frames of the sender.
+	Frames int
+	// Warnings describe the adjustments made, e.g. a *ChunkSizeWarning if a
+	// smaller chunk size is used. Callers can inspect them with errors.As.
+	Warnings []error
+}
+
+// ChunkSizeWarning is recorded in a Plan if the requested chunk size does not
+// fit in a QR code at the error correction level the frames are rendered at.
+type ChunkSizeWarning struct {
+	Requested ChunkSize // the chunk size asked for
+	Used      ChunkSize // the chunk size the sender was created with
+	ECLevel   ECLevel   // the level the requested chunk size does not fit at
+}
+
+func (w *ChunkSizeWarning) Error() string {
+	return fmt.Sprintf("chunk size %d does not fit in a QR code at error correction level %s, using %d",
+		w.Requested, w.ECLevel, w.Used)
+}
+
+// NewPlanned creates a sender like New, but instead of failing if a chunk of
+// the requested size does not fit in a QR code at the error correction level
+// the frames are rendered at, it falls back to the next smaller chunk size
+// that fits and records a *ChunkSizeWarning in the Plan.
+//
+// The level is the highest one opt renders any frame at: its ECLevel, or the
+// one set with WithECLevel, its EdgeECLevel if it has edge chunks, and
+// ECLevelHigh if it has a logo. Pass the same RenderOptions to the render
+// methods.
+//
+// Parameters:
+// - data: a byte slice containing the data to be split into chunks.
+// - opt: the RenderOptions the frames will be rendered with.
+// - opts: the options to apply.
+//
+// Returns:
+// - *QRSequence: the new QRSequence.
+// - *Plan: the parameters of the new QRSequence.
+// - error: an error if an option is invalid or encoding the payload fails.
+func NewPlanned(data []byte, opt RenderOptions, opts ...Option) (*QRSequence, *Plan, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	level := opt.requiredLevel(o.ecLevel)
+	plan := &Plan{RequestedChunkSize: o.chunkSize, ChunkSize: o.chunkSize, ECLevel: level}
+
+	// New also checks the level set with WithECLevel, even if opt overrides it
+	need := max(level, o.ecLevel)
+	for plan.ChunkSize > ChunkSize32 && plan.ChunkSize.MaxECLevelFor(o.text) < need {
+		plan.ChunkSize /= 2
+	}
+	if plan.ChunkSize != o.chunkSize {
+		plan.Warnings = append(plan.Warnings, &ChunkSizeWarning{Requested: o.chunkSize, Used: plan.ChunkSize, ECLevel: need})
+	}
+
+	s, err := New(data, append(opts[:len(opts):len(opts)], WithChunkSize(plan.ChunkSize))...)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan.Frames = len(s.chunks)
+	return s, plan, nil
+}
+
+// requiredLevel returns the highest error correction level the options render
+// any frame of a sequence with the given level at, or ECLevelDefault if
+// neither asks for one.
+func (opt RenderOptions) requiredLevel(seqLevel ECLevel) ECLevel {
+	level := opt.ECLevel
+	if level == ECLevelDefault {
+		level = seqLevel
+	}
+	if opt.EdgeChunks > 0 {
+		level = max(level, opt.EdgeECLevel)
+	}
+	if opt.Logo != nil {
+		level = ECLevelHigh
+	}
+	return level
+}
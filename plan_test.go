@@ -0,0 +1,112 @@
+package qrseq
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestRequiredLevel(t *testing.T) {
+	logo := image.NewGray(image.Rect(0, 0, 8, 8))
+	for _, tc := range []struct {
+		name     string
+		opt      RenderOptions
+		seqLevel ECLevel
+		want     ECLevel
+	}{
+		{name: "default", want: ECLevelDefault},
+		{name: "sequence level", seqLevel: ECLevelMedium, want: ECLevelMedium},
+		{name: "render level", opt: RenderOptions{ECLevel: ECLevelLow}, seqLevel: ECLevelMedium, want: ECLevelLow},
+		{name: "edge default", opt: RenderOptions{EdgeChunks: 1}, seqLevel: ECLevelLow, want: ECLevelLow},
+		{name: "edge level", opt: RenderOptions{EdgeChunks: 1, EdgeECLevel: ECLevelHigh}, want: ECLevelHigh},
+		{name: "edge below level", opt: RenderOptions{ECLevel: ECLevelHigh, EdgeChunks: 1, EdgeECLevel: ECLevelLow}, want: ECLevelHigh},
+		{name: "edge level unused", opt: RenderOptions{EdgeECLevel: ECLevelHigh}, seqLevel: ECLevelLow, want: ECLevelLow},
+		{name: "logo", opt: RenderOptions{ECLevel: ECLevelLow, Logo: logo}, want: ECLevelHigh},
+		{name: "logo and edge", opt: RenderOptions{Logo: logo, EdgeChunks: 1, EdgeECLevel: ECLevelMedium}, want: ECLevelHigh},
+	} {
+		if got := tc.opt.requiredLevel(tc.seqLevel); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNewPlannedFallsBackForLogo(t *testing.T) {
+	opt := RenderOptions{Logo: image.NewGray(image.Rect(0, 0, 8, 8))}
+	_, plan, err := NewPlanned(make([]byte, 3000), opt, WithChunkSize(ChunkSize1024))
+	if err != nil {
+		t.Fatalf("NewPlanned: %v", err)
+	}
+	if plan.ECLevel != ECLevelHigh {
+		t.Errorf("got level %v, want %v", plan.ECLevel, ECLevelHigh)
+	}
+	if plan.ChunkSize != ChunkSize512 {
+		t.Errorf("got chunk size %d, want %d", plan.ChunkSize, ChunkSize512)
+	}
+	if len(plan.Warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(plan.Warnings))
+	}
+	var warning *ChunkSizeWarning
+	if !errors.As(plan.Warnings[0], &warning) {
+		t.Fatalf("got warning %v, want a *ChunkSizeWarning", plan.Warnings[0])
+	}
+	if warning.Requested != ChunkSize1024 || warning.Used != ChunkSize512 || warning.ECLevel != ECLevelHigh {
+		t.Errorf("got warning %+v", warning)
+	}
+
+	_, plan, err = NewPlanned(make([]byte, 3000), RenderOptions{}, WithChunkSize(ChunkSize1024))
+	if err != nil {
+		t.Fatalf("NewPlanned: %v", err)
+	}
+	if plan.ChunkSize != ChunkSize1024 || len(plan.Warnings) != 0 {
+		t.Errorf("without logo: got chunk size %d and warnings %v", plan.ChunkSize, plan.Warnings)
+	}
+}
+
+func TestRenderOptionsEdgeLevel(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		opt     RenderOptions
+		want    ECLevel
+		wantErr error
+	}{
+		{name: "inherits sequence level", opts: []Option{WithECLevel(ECLevelLow)}, opt: RenderOptions{EdgeChunks: 1}, want: ECLevelLow},
+		{name: "inherits render level", opt: RenderOptions{ECLevel: ECLevelMedium, EdgeChunks: 1}, want: ECLevelMedium},
+		{name: "explicit level", opt: RenderOptions{EdgeChunks: 1, EdgeECLevel: ECLevelHigh}, want: ECLevelHigh},
+		{name: "too high", opts: []Option{WithChunkSize(ChunkSize2048), WithECLevel(ECLevelLow)}, opt: RenderOptions{EdgeChunks: 1, EdgeECLevel: ECLevelHigh}, wantErr: ErrEdgeECLevelTooHigh},
+	} {
+		s, err := New([]byte("edge"), tc.opts...)
+		if err != nil {
+			t.Fatalf("%s: New: %v", tc.name, err)
+		}
+		opt, err := s.renderOptions(tc.opt)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: got error %v, want %v", tc.name, err, tc.wantErr)
+		}
+		if err == nil && opt.EdgeECLevel != tc.want {
+			t.Errorf("%s: got edge level %v, want %v", tc.name, opt.EdgeECLevel, tc.want)
+		}
+	}
+}
+
+func TestRenderOptionsLogoChunkSize(t *testing.T) {
+	opt := RenderOptions{Logo: image.NewGray(image.Rect(0, 0, 8, 8))}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want error
+	}{
+		{name: "fits", opts: []Option{WithChunkSize(ChunkSize512)}},
+		{name: "too large", opts: []Option{WithChunkSize(ChunkSize1024)}, want: ErrLogoChunkSize},
+		{name: "base45", opts: []Option{WithChunkSize(ChunkSize1024), WithTextEncoding(TextBase45)}},
+		{name: "largest", opts: []Option{WithChunkSize(ChunkSize2048), WithTextEncoding(TextBase45)}, want: ErrLogoChunkSize},
+	} {
+		s, err := New([]byte("logo"), tc.opts...)
+		if err != nil {
+			t.Fatalf("%s: New: %v", tc.name, err)
+		}
+		if _, err := s.renderOptions(opt); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package qrseq
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
-	"image"
+	"hash"
+	"io"
+	"time"
 
 	"github.com/airsigner/qrseq/internal"
 )
@@ -17,54 +21,210 @@ const (
 	ChunkSize256     ChunkSize = ChunkSize(internal.ChunkSize256)
 	ChunkSize512     ChunkSize = ChunkSize(internal.ChunkSize512)
 	ChunkSize1024    ChunkSize = ChunkSize(internal.ChunkSize1024)
+	// ChunkSize2048 needs QR codes of version 40 at ECLevelLow, which only
+	// scan reliably when they are shown large and sharp, e.g. on a 4K display
+	// in front of a DSLR.
+	ChunkSize2048 ChunkSize = ChunkSize(internal.ChunkSize2048)
 )
 
+// MaxECLevel returns the highest error correction level at which a QR code
+// holds a full chunk of the size in base64. Senders of that chunk size are
+// rendered at no higher level by default, and rendering them at a higher one
+// fails.
+//
+// Returns:
+// - ECLevel: the highest level, or ECLevelDefault for ChunkSizeUnknown.
+func (cs ChunkSize) MaxECLevel() ECLevel {
+	return cs.MaxECLevelFor(TextBase64)
+}
+
+// MaxECLevelFor returns the highest error correction level at which a QR code
+// holds a full chunk of the size in the given text encoding, see MaxECLevel.
+//
+// Parameters:
+// - enc: the text encoding of the chunks.
+//
+// Returns:
+// - ECLevel: the highest level, or ECLevelDefault for ChunkSizeUnknown.
+func (cs ChunkSize) MaxECLevelFor(enc TextEncoding) ECLevel {
+	if !internal.IsValidChunkSize(uint16(cs)) {
+		return ECLevelDefault
+	}
+	return ECLevel(internal.MaxECLevel(uint16(cs), internal.TextEncoding(enc)))
+}
+
+// QRSequence is a payload split into chunks, either a sender created by New or
+// a receiver created by NewEmpty. It is not safe for concurrent use; wrap a
+// receiver in a SyncSequence to pass frames to it from several goroutines.
 type QRSequence struct {
 	ChunkSize  ChunkSize
 	chunks     []*internal.QRChunk
 	nrReceived int
+	firstSeen  []time.Time
+	expected   [][sha256.Size]byte
+	err        error
+	stats      Stats
+	attempts   []int
+
+	expectedLength int
+	checkLength    bool
+	policies       []Policy
+	trust          *TrustStore
+	signature      []byte
+	finalized      bool
+
+	frameBudget   time.Duration
+	decoding      chan struct{}
+	tearDetection bool
+
+	decoded      []byte
+	key          []byte
+	ecLevel      ECLevel
+	textEncoding TextEncoding
+	decoder      Decoder
+	tee          ChunkStore
+
+	drained      int
+	drainedBytes int
+	drainHash    hash.Hash
+
+	layout    uint8
+	seqID     uint32
+	hasSeqID  bool
+	appTag    uint16
+	fountain  *internal.FountainDecoder
+	candidate *rebaseCandidate
+
+	rand  io.Reader
+	clock Clock
+
+	result          chan Completed
+	resultDelivered bool
+
+	completionMode       CompletionMode
+	next                 *QRSequence
+	newSequence          chan *QRSequence
+	newSequenceDelivered bool
+
+	guidance          chan Guidance
+	guidanceLast      Guidance
+	guidanceCandidate Guidance
+	guidanceCount     int
+
+	onChunk     func(nr, tot int)
+	onDuplicate func(nr, tot int)
+	onComplete  func(data []byte)
+
+	// done is closed once the receiver is complete or failed, it is created by
+	// NewEmpty and never replaced, so WaitComplete can read it concurrently
+	done chan struct{}
 }
 
-// New creates a new QRSequence with the given data and chunk size.
+// New creates a new QRSequence with the given data, configured by the options.
+//
+// The chunks are sent with the v2 chunk header, which carries a CRC of each
+// chunk so corrupted or misread chunks are rejected. Receivers accept up to
+// 65536 chunks per sequence. Without WithChunkSize, the chunks have the
+// DefaultChunkSize.
+//
+// If the payload is compressed, encrypted or padded, Data and Digest of the
+// sequence still refer to the payload as given. Receivers undo the encodings
+// on completion, so Data returns the original payload on their side as well.
 //
 // Parameters:
 // - data: a byte slice containing the data to be split into chunks.
-// - chunkSize: a ChunkSize enum value specifying the size of each chunk.
+// - opts: the options to apply.
 //
 // Returns:
-// - a pointer to a QRSequence object.
-func New(data []byte, chunkSize ChunkSize) *QRSequence {
+//   - *QRSequence: the new QRSequence.
+//   - error: an error if an option is invalid, the error correction level is
+//     too high for the chunk size or encoding the payload fails.
+func New(data []byte, opts ...Option) (*QRSequence, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.ecLevel > o.chunkSize.MaxECLevelFor(o.text) {
+		return nil, ErrECLevelTooHigh
+	}
+
+	s := QRSequence{rand: o.rand}
+	sent, encoding, err := s.encodePayload(data, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.padChunks > 0 {
+		if sent, encoding, err = pad(sent, encoding, o); err != nil {
+			return nil, err
+		}
+	}
+	sender := newSender(sent, encoding, o)
+	sender.ecLevel = o.ecLevel
+	sender.textEncoding = o.text
+	if encoding != 0 {
+		sender.decoded = data
+	}
+	return sender, nil
+}
+
+// newSender creates a sending QRSequence carrying data, which has the given
+// encodings applied, in chunks of the chunk size of the options, tagged with
+// their app tag, if not zero, and with a sequence ID drawn from their source
+// of randomness.
+func newSender(data []byte, encoding uint8, o options) *QRSequence {
 	s := new(QRSequence)
-	s.ChunkSize = ChunkSize(chunkSize)
-	s.chunks = internal.CreateChunks(data, uint16(chunkSize))
+	s.ChunkSize = o.chunkSize
+	s.appTag = o.appTag
+	s.rand = o.rand
+	s.chunks = internal.CreateChunks(data, uint16(o.chunkSize), s.newSeqID(), encoding, o.appTag)
 	s.nrReceived = len(s.chunks)
 	return s
 }
 
 // NewEmpty creates a new QRSequence with an unknown chunk size and an empty
-// slice of QRChunks.
+// slice of QRChunks, configured by the options.
 //
 // An empty sequence should be used to start decoding chunked qr images into the
-// sequence.
+// sequence. Receivers need WithEncryption to decrypt an encrypted payload,
+// options that only concern senders are ignored. An invalid option ends the
+// sequence with a terminal error.
+//
+// Parameters:
+// - opts: the options to apply.
 //
 // Returns:
 // - a pointer to a QRSequence object.
-func NewEmpty() *QRSequence {
-	return &QRSequence{
+func NewEmpty(opts ...Option) *QRSequence {
+	s := &QRSequence{
 		ChunkSize: ChunkSizeUnknown,
 		chunks:    make([]*internal.QRChunk, 0),
+		done:      make(chan struct{}),
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		s.err = err
+		s.markDone()
+		return s
 	}
+	s.ecLevel = o.ecLevel
+	s.textEncoding = o.text
+	s.appTag = o.appTag
+	s.key = o.key
+	s.rand = o.rand
+	s.decoder = o.decoder
+	s.tee = o.tee
+	return s
 }
 
 // IsComplete checks if the QRSequence is complete.
 //
 // It returns true if all chunks of the sequence have been received, false
-// otherwise.
+// otherwise. A sequence that ended with a terminal error is never complete.
 //
 // Returns:
 // - bool: true if the QRSequence is complete, false otherwise.
 func (s QRSequence) IsComplete() bool {
-	if s.ChunkSize == ChunkSizeUnknown {
+	if s.ChunkSize == ChunkSizeUnknown || s.err != nil {
 		return false
 	}
 	return s.nrReceived == len(s.chunks)
@@ -89,93 +249,372 @@ func (s QRSequence) Progress() float32 {
 	return float32(s.nrReceived) / float32(len(s.chunks))
 }
 
+// Missing returns the numbers of the chunks that have not been received yet,
+// so UIs can show which frames still need to be scanned. Chunks of a fountain
+// coded sequence are recovered from any frames, so there the numbers only
+// tell how many chunks are missing.
+//
+// Returns:
+//   - []int: the numbers of the missing chunks in ascending order, empty once
+//     the QRSequence is complete, or nil if no chunk has been received yet
+//     and the total is therefore unknown.
+func (s QRSequence) Missing() []int {
+	return s.chunkNumbers(false)
+}
+
+// Received returns the numbers of the chunks that have been received.
+//
+// Returns:
+// - []int: the numbers of the received chunks in ascending order.
+func (s QRSequence) Received() []int {
+	return s.chunkNumbers(true)
+}
+
+// chunkNumbers returns the numbers of the received or of the missing chunks.
+func (s QRSequence) chunkNumbers(received bool) []int {
+	if s.ChunkSize == ChunkSizeUnknown {
+		return nil
+	}
+	nrs := []int{}
+	for nr, chunk := range s.chunks {
+		if (chunk != nil) == received {
+			nrs = append(nrs, nr)
+		}
+	}
+	return nrs
+}
+
 // Data returns the complete data of the QRSequence if it is complete, otherwise
-// it returns nil.
+// it returns nil. It also returns nil once the payload has been partly drained
+// with WriteTo or DataReader.
 //
 // Returns:
 // - []byte: the data of the QRSequence if it is complete, otherwise nil.
 func (s QRSequence) Data() []byte {
-	if !s.IsComplete() {
+	if !s.IsComplete() || s.drainHash != nil {
 		return nil
 	}
+	if s.decoded != nil {
+		return s.decoded
+	}
 	return internal.GetData(s.chunks)
 }
 
-// QRCodes generates a slice of QR codes for each chunk in the QRSequence.
+// FirstSeen returns the time at which each chunk of the QRSequence was first
+// received.
 //
-// It takes an integer parameter `blockSize` which specifies the size of the QR
-// code blocks.
+// The returned slice is indexed by chunk number. Chunks that have not been
+// received yet have a zero time. Sequences created with New have no receive
+// history and return nil.
 //
 // Returns:
-//   - []image.Image: a slice of QR codes generated for each chunk in the
-//     QRSequence.
-//   - error: an error if the QRSequence is not complete or if there is an error
-//     while generating the QR codes.
-func (s QRSequence) QRCodes(blockSize int) ([]image.Image, error) {
-	if !s.IsComplete() {
-		return nil, errors.New("sequence not complete")
+//   - []time.Time: the first-seen time of every chunk, or nil if the sequence
+//     was not built by receiving chunks.
+func (s QRSequence) FirstSeen() []time.Time {
+	if s.firstSeen == nil {
+		return nil
 	}
+	seen := make([]time.Time, len(s.firstSeen))
+	copy(seen, s.firstSeen)
+	return seen
+}
 
-	images := make([]image.Image, 0, len(s.chunks))
-	for _, chunk := range s.chunks {
-		qr, err := chunk.QRCode(blockSize)
-		if err != nil {
-			return nil, err
-		}
-		images = append(images, qr)
+// AsSender converts a completed receiving QRSequence into a sender that
+// re-displays the same sequence.
+//
+// The returned sequence shares the received chunks but carries none of the
+// receive state, so relay devices can forward a payload across another air gap
+// chunk for chunk, without re-chunking it.
+//
+// Returns:
+// - *QRSequence: a sender for the received sequence.
+//   - error: an error if the QRSequence is not complete or its payload has been
+//     drained.
+func (s QRSequence) AsSender() (*QRSequence, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+	if s.drainHash != nil {
+		return nil, ErrPayloadDrained
 	}
 
-	return images, nil
+	sender := new(QRSequence)
+	sender.ChunkSize = s.ChunkSize
+	sender.chunks = make([]*internal.QRChunk, len(s.chunks))
+	copy(sender.chunks, s.chunks)
+	sender.nrReceived = len(sender.chunks)
+	sender.decoded = s.decoded
+	sender.ecLevel = s.ecLevel
+	sender.textEncoding = s.textEncoding
+	sender.appTag = s.appTag
+	sender.rand = s.rand
+	return sender, nil
 }
 
-// DecodeImage decodes an image into a QRSequence.
+// Rechunk creates a sender that carries the payload of the QRSequence split
+// into new chunks.
 //
-// It takes an image.Image as a parameter and attempts to decode it into a
-// QRChunk.
-// If the QRSequence is already complete, it returns nil.
-// If the decoding is successful, the chunk is added to the QRSequence and nil
-// is returned.
-// If there is an error during decoding, the error is returned.
+// Relay devices use it to forward a payload to a next hop whose display or
+// camera needs other parameters. The payload bytes, and therefore their
+// Digest, are left untouched. Compressed or encrypted payloads are forwarded
+// as they were sent, so WithCompression, WithEncryption and WithPadding are
+// ignored. The chunk size, error correction level, text encoding and app tag
+// of the QRSequence are kept unless an option changes them.
 //
 // Parameters:
-// - img: an image.Image to be decoded into a QRChunk.
+//   - opts: the options to apply, such as WithChunkSize, WithECLevel and
+//     WithTextEncoding.
 //
 // Returns:
-//   - error: an error if there was an issue decoding the image or if the
-//     QRSequence is already complete.
-func (s *QRSequence) DecodeImage(img image.Image) error {
-	if s.IsComplete() {
-		return nil
+//   - *QRSequence: a sender for the payload in the new chunks.
+//   - error: an error if the QRSequence is not complete, its payload has been
+//     drained, an option is invalid or the error correction level is too high
+//     for the chunk size.
+func (s QRSequence) Rechunk(opts ...Option) (*QRSequence, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
 	}
-
-	chunk, err := internal.NewChunkFromImage(img)
+	if s.drainHash != nil {
+		return nil, ErrPayloadDrained
+	}
+	o, err := applyOptions(options{
+		chunkSize: s.ChunkSize,
+		ecLevel:   s.ecLevel,
+		text:      s.textEncoding,
+		appTag:    s.appTag,
+		rand:      s.rand,
+	}, opts)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if o.ecLevel > o.chunkSize.MaxECLevelFor(o.text) {
+		return nil, ErrECLevelTooHigh
 	}
+	sender := newSender(internal.GetData(s.chunks), s.encoding(), o)
+	sender.decoded = s.decoded
+	sender.ecLevel = o.ecLevel
+	sender.textEncoding = o.text
+	return sender, nil
+}
 
-	s.addChunk(chunk)
-	return nil
+// Digest returns the SHA-256 digest of the payload of the QRSequence.
+//
+// Returns:
+// - [sha256.Size]byte: the digest of the payload.
+// - error: an error if the QRSequence is not complete.
+func (s QRSequence) Digest() ([sha256.Size]byte, error) {
+	if !s.IsComplete() {
+		return [sha256.Size]byte{}, ErrSequenceIncomplete
+	}
+	return s.payloadDigest(), nil
+}
+
+// ChunkResult is the outcome of adding a chunk with AddChunk.
+type ChunkResult uint8
+
+const (
+	// ChunkAccepted means the chunk or fountain frame was new and added.
+	ChunkAccepted ChunkResult = iota + 1
+	// ChunkDuplicate means the chunk or fountain frame had been received
+	// before.
+	ChunkDuplicate
+	// ChunkRejected means the data is not a valid chunk or belongs to another
+	// sequence. The error tells why.
+	ChunkRejected
+	// ChunkIgnored means the data was skipped without being checked, because
+	// it is a decoy chunk or an extended frame of an unknown type, the
+	// QRSequence is complete or ended with a terminal error.
+	ChunkIgnored
+)
+
+// String returns a description of the result suitable for users.
+func (r ChunkResult) String() string {
+	switch r {
+	case ChunkAccepted:
+		return "accepted"
+	case ChunkDuplicate:
+		return "duplicate"
+	case ChunkRejected:
+		return "rejected"
+	case ChunkIgnored:
+		return "ignored"
+	}
+	return "unknown result"
 }
 
 // AddChunkFromBytes adds a chunk of data to the QRSequence.
 //
 // It takes a byte slice as a parameter, which represents the data to be added.
-// If the QRSequence is already complete, the function returns immediately.
-// It creates a new chunk from the given data using the internal.NewChunk
-// function.
-// If the chunk is nil, the function returns.
-// Otherwise, it adds the chunk to the QRSequence using the addChunk method.
+// If the QRSequence is already complete, the data is handled according to the
+// CompletionMode.
+// The data is either a chunk or a fountain frame, anything else is ignored.
+// Otherwise, it adds the frame to the QRSequence using the addFrame method.
+// Use AddChunk to learn whether the chunk was accepted.
 func (s *QRSequence) AddChunkFromBytes(data []byte) {
+	_, _ = s.AddChunk(data)
+}
+
+// AddChunk adds a chunk of data to the QRSequence like AddChunkFromBytes, and
+// reports what became of it, so receiver UIs can give feedback.
+//
+// Parameters:
+// - data: the bytes of the chunk or fountain frame.
+//
+// Returns:
+//   - ChunkResult: whether the chunk was accepted, a duplicate, rejected or
+//     ignored.
+//   - error: why the chunk was rejected, e.g. ErrInvalidChunk or a
+//     *ChunkMismatchError naming the field that conflicts with the chunks
+//     received before, or the terminal error of the QRSequence.
+func (s *QRSequence) AddChunk(data []byte) (ChunkResult, error) {
+	if s.err != nil {
+		return ChunkIgnored, s.err
+	}
 	if s.IsComplete() {
-		return
+		return ChunkIgnored, s.receiveAfterComplete(data)
+	}
+
+	result, err := s.addFrame(data)
+	if err != nil {
+		return result, err
+	}
+	return result, s.err
+}
+
+// receive adds a decoded frame to the QRSequence and counts the outcome in
+// Stats. Frames arriving after completion are handled according to the
+// CompletionMode.
+//
+// Parameters:
+// - frame: the bytes of the decoded frame.
+//
+// Returns:
+//   - error: a *DecodeError if the frame is invalid, or the terminal error of
+//     the QRSequence.
+func (s *QRSequence) receive(frame []byte) error {
+	if s.IsComplete() {
+		return s.receiveAfterComplete(frame)
+	}
+	// chunks of another sequence are read correctly, and a failing ChunkStore
+	// ends the session with its own error, so neither counts as a failure
+	result, err := s.addFrame(frame)
+	if err != nil && err != s.err && !errors.Is(err, ErrChunkMismatch) {
+		decodeErr := newFrameError(err)
+		s.stats.count(decodeErr)
+		return decodeErr
+	}
+	s.stats.count(nil)
+	s.stats.countChunk(result)
+	return s.err
+}
+
+// addFrame adds a decoded frame to the QRSequence, dispatching on its type.
+//
+// Extended frames of an unknown type, such as manifest or handshake frames of
+// newer senders, are skipped without fixing the layout of the sequence. If a
+// valid frame with a newer layout than the one the sequence was locked into
+// arrives and belongs to the sequence, see checkRebaseline, the sequence
+// re-baselines: the chunks received so far are dropped and the new frame
+// fixes the layout.
+//
+// Parameters:
+// - frame: the bytes of the decoded frame.
+//
+// Returns:
+//   - ChunkResult: the outcome of adding the frame.
+//   - error: an error if the frame is neither a valid chunk nor a fountain
+//     frame, or a *ChunkMismatchError if it belongs to another sequence.
+func (s *QRSequence) addFrame(frame []byte) (ChunkResult, error) {
+	layout := internal.FrameLayout(frame)
+	if layout == internal.LayoutUnknown {
+		return ChunkIgnored, nil
+	}
+
+	// frames are checked completely before they can re-baseline the sequence
+	var (
+		chunk    *internal.QRChunk
+		header   internal.FountainHeader
+		block    []byte
+		seqID    uint32
+		hasSeqID bool
+		err      error
+	)
+	if layout == internal.LayoutFountain {
+		if header, block, err = internal.ParseFountainFrame(frame); err != nil {
+			return ChunkRejected, err
+		}
+		seqID, hasSeqID = header.SeqID, true
+	} else {
+		if chunk, err = internal.NewChunk(frame); err != nil {
+			return ChunkRejected, err
+		}
+		seqID, hasSeqID = chunk.SeqID()
+	}
+	if err := checkAppTag(s.appTag, chunk, header); err != nil {
+		return ChunkRejected, err
+	}
+	if s.layout != internal.LayoutUnknown && layout > s.layout {
+		if chunk != nil && chunk.IsDecoy() {
+			return ChunkIgnored, nil
+		}
+		if err := s.checkRebaseline(layout, seqID, hasSeqID, frame); err != nil {
+			return ChunkRejected, err
+		}
+		s.rebaseline()
 	}
 
-	chunk := internal.NewChunk(data)
 	if chunk == nil {
-		return
+		return s.addFountainFrame(header, block, frame)
+	}
+	return s.addChunk(chunk, frame)
+}
+
+// checkRebaseline checks that a valid frame of a newer layout than the one the
+// sequence is locked into belongs to the sequence, so a stray frame or a
+// frame of another sequence cannot drop the chunks received so far.
+//
+// If the sequence has a sequence ID, the frame must carry the same one.
+// Legacy sequences carry none, so the newer layout must be confirmed by a
+// second, different frame of the same layout and sequence ID. The first one
+// is rejected and remembered.
+//
+// Parameters:
+// - layout: the layout of the frame.
+// - seqID: the sequence ID of the frame.
+// - hasSeqID: whether the frame carries a sequence ID.
+// - frame: the bytes of the frame.
+//
+// Returns:
+//   - error: a *ChunkMismatchError if the frame does not belong to the
+//     sequence or is not confirmed yet.
+func (s *QRSequence) checkRebaseline(layout uint8, seqID uint32, hasSeqID bool, frame []byte) error {
+	if s.hasSeqID {
+		got := int64(-1)
+		if hasSeqID {
+			got = int64(seqID)
+		}
+		if got != int64(s.seqID) {
+			return &ChunkMismatchError{Field: "sequence ID", Want: int64(s.seqID), Got: got}
+		}
+		return nil
+	}
+
+	c := s.candidate
+	if c != nil && c.layout == layout && c.seqID == seqID && c.hasSeqID == hasSeqID && !bytes.Equal(c.frame, frame) {
+		return nil
 	}
+	s.candidate = &rebaseCandidate{layout: layout, seqID: seqID, hasSeqID: hasSeqID, frame: bytes.Clone(frame)}
+	return &ChunkMismatchError{Field: "layout", Want: int64(s.layout), Got: int64(layout)}
+}
 
-	s.addChunk(chunk)
+// rebaseCandidate is the first frame of a newer layout received by a legacy
+// sequence, which the sequence re-baselines for once a second frame
+// confirms it.
+type rebaseCandidate struct {
+	layout   uint8
+	seqID    uint32
+	hasSeqID bool
+	frame    []byte
 }
 
 // addChunk adds a chunk of data to the QRSequence.
@@ -183,24 +622,171 @@ func (s *QRSequence) AddChunkFromBytes(data []byte) {
 // It takes a pointer to a QRChunk as a parameter, which represents the data to
 // be added.
 // If the QRSequence is already complete, the function returns immediately.
+// Decoy chunks are discarded.
 // If the ChunkSize is unknown, it sets the ChunkSize to the size of the given
 // chunk and creates a slice of QRChunks with the total size.
+// Chunks that do not match the layout or sequence ID of the QRSequence,
+// including any chunk of a fountain coded sequence, belong to another
+// sequence and are rejected with a *ChunkMismatchError.
 // If the chunk with the same number already exists in the QRSequence, the
 // function returns.
-// Otherwise, it adds the chunk to the QRSequence and increments the number of
-// received chunks.
+// Otherwise, it adds the chunk to the QRSequence using the setChunk method.
 //
 // Parameters:
 // - chunk: a pointer to a QRChunk representing the data to be added.
-func (s *QRSequence) addChunk(chunk *internal.QRChunk) {
+// - frame: the bytes the chunk was parsed from, for the ChunkStore.
+//
+// Returns:
+//   - ChunkResult: the outcome of adding the chunk.
+//   - error: a *ChunkMismatchError if the chunk belongs to another sequence,
+//     or the error of the ChunkStore.
+func (s *QRSequence) addChunk(chunk *internal.QRChunk, frame []byte) (ChunkResult, error) {
+	if chunk.IsDecoy() {
+		return ChunkIgnored, nil
+	}
+
 	if s.ChunkSize == ChunkSizeUnknown {
 		s.ChunkSize = ChunkSize(chunk.Size())
 		s.chunks = make([]*internal.QRChunk, chunk.Tot())
+		s.firstSeen = make([]time.Time, chunk.Tot())
+		s.attempts = make([]int, chunk.Tot())
 		s.nrReceived = 0
+		s.layout = chunk.Layout()
+		s.seqID, s.hasSeqID = chunk.SeqID()
+	}
+
+	if err := s.checkChunk(chunk); err != nil {
+		return ChunkRejected, err
+	}
+	if s.attempts != nil {
+		s.attempts[chunk.Nr()]++
 	}
 
 	if s.chunks[chunk.Nr()] == nil {
-		s.chunks[chunk.Nr()] = chunk
-		s.nrReceived++
+		if err := s.teeFrame(frame); err != nil {
+			return ChunkIgnored, err
+		}
+		s.setChunk(chunk)
+		return ChunkAccepted, nil
+	}
+	if s.onDuplicate != nil {
+		s.onDuplicate(chunk.Nr(), len(s.chunks))
+	}
+	return ChunkDuplicate, nil
+}
+
+// checkChunk checks that a chunk belongs to the sequence established by the
+// chunks received before, before its number is used as an index.
+//
+// Parameters:
+// - chunk: the chunk to check.
+//
+// Returns:
+//   - error: a *ChunkMismatchError naming the first conflicting field, or
+//     ErrInvalidChunk if the chunk number is out of range.
+func (s *QRSequence) checkChunk(chunk *internal.QRChunk) error {
+	if s.fountain != nil {
+		return &ChunkMismatchError{Field: "layout", Want: int64(s.layout), Got: int64(chunk.Layout())}
+	}
+	if ChunkSize(chunk.Size()) != s.ChunkSize {
+		return &ChunkMismatchError{Field: "chunk size", Want: int64(s.ChunkSize), Got: int64(chunk.Size())}
+	}
+	if chunk.Tot() != len(s.chunks) {
+		return &ChunkMismatchError{Field: "total", Want: int64(len(s.chunks)), Got: int64(chunk.Tot())}
+	}
+	want, got := int64(-1), int64(-1)
+	if s.hasSeqID {
+		want = int64(s.seqID)
+	}
+	if seqID, ok := chunk.SeqID(); ok {
+		got = int64(seqID)
+	}
+	if want != got {
+		return &ChunkMismatchError{Field: "sequence ID", Want: want, Got: got}
+	}
+	if chunk.Nr() < 0 || chunk.Nr() >= len(s.chunks) {
+		return ErrInvalidChunk
+	}
+	return nil
+}
+
+// checkAppTag checks that a frame carries the application tag want, if it is
+// not zero.
+//
+// Parameters:
+//   - want: the application tag of the receiver, or zero to accept frames of
+//     any application.
+//   - chunk: the chunk of the frame, or nil for a fountain frame.
+//   - h: the header of a fountain frame.
+//
+// Returns:
+// - error: a *ChunkMismatchError if the frame carries another tag or none.
+func checkAppTag(want uint16, chunk *internal.QRChunk, h internal.FountainHeader) error {
+	if want == 0 {
+		return nil
+	}
+	got := int64(-1)
+	if chunk != nil {
+		if tag, ok := chunk.AppTag(); ok {
+			got = int64(tag)
+		}
+	} else if h.AppTag != 0 {
+		got = int64(h.AppTag)
+	}
+	if got != int64(want) {
+		return &ChunkMismatchError{Field: "app tag", Want: int64(want), Got: got}
+	}
+	return nil
+}
+
+// rebaseline drops the receive state of the QRSequence, so the next frame fixes
+// its layout anew. Once part of the payload has been drained, the sequence
+// stays with its layout.
+func (s *QRSequence) rebaseline() {
+	if s.drainHash != nil {
+		return
+	}
+	s.ChunkSize = ChunkSizeUnknown
+	s.chunks = make([]*internal.QRChunk, 0)
+	s.firstSeen = nil
+	s.attempts = nil
+	s.nrReceived = 0
+	s.layout = internal.LayoutUnknown
+	s.fountain = nil
+	s.candidate = nil
+}
+
+// setChunk stores a newly received chunk, records the time it was first seen
+// and increments the number of received chunks. Once the last missing chunk
+// arrives, the payload is checked and decoded, and the outcome is delivered to
+// the Result channel.
+//
+// Parameters:
+// - chunk: a pointer to the QRChunk to store.
+func (s *QRSequence) setChunk(chunk *internal.QRChunk) {
+	s.chunks[chunk.Nr()] = chunk
+	s.firstSeen[chunk.Nr()] = s.timeSource().Now()
+	s.nrReceived++
+	if s.onChunk != nil {
+		s.onChunk(chunk.Nr(), len(s.chunks))
+	}
+
+	if s.IsComplete() {
+		s.fountain = nil
+		s.err = s.checkSentLength()
+		if s.err == nil {
+			s.err = s.checkSentDigest()
+		}
+		if s.err == nil {
+			s.err = s.decodePayload()
+		}
+		if s.err == nil {
+			s.err = s.checkDigest()
+		}
+		s.deliverResult()
+		if s.err == nil && s.onComplete != nil {
+			s.onComplete(s.Data())
+		}
+		s.markDone()
 	}
 }
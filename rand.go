@@ -0,0 +1,65 @@
+package qrseq
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+// SetRand sets the source of randomness of the QRSequence, which is used for
+// sequence IDs, decoy frames and encryption nonces.
+//
+// Signing devices can route it to a hardware RNG and tests can make it
+// deterministic. Fountain seeds are not random: they are derived from the
+// frame counter, so the first frames carry the plain blocks.
+//
+// A sender gets its sequence ID and encryption nonce when it is created, from
+// the source set with WithRand or crypto/rand.Reader. SetRand draws a new
+// sequence ID from r, but cannot draw a new nonce for a payload that has
+// already been encrypted, so reproducible encrypted senders need WithRand.
+// SetRand must be called before the first frame is sent.
+//
+// Parameters:
+//   - r: the source of randomness, or nil for the default crypto/rand.Reader.
+func (s *QRSequence) SetRand(r io.Reader) {
+	s.rand = r
+
+	if s.firstSeen != nil || len(s.chunks) == 0 {
+		return
+	}
+	seqID := s.newSeqID()
+	for i, chunk := range s.chunks {
+		if _, ok := chunk.SeqID(); ok {
+			s.chunks[i] = chunk.WithSeqID(seqID)
+		}
+	}
+}
+
+// newSeqID draws a random sequence ID from the source of randomness of the
+// QRSequence.
+func (s QRSequence) newSeqID() uint32 {
+	return readSeqID(s.random())
+}
+
+// readSeqID draws a random sequence ID from r. If r fails, the ID is zero,
+// which still works but no longer tells transfers apart.
+func readSeqID(r io.Reader) uint32 {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b[:])
+}
+
+// random returns the source of randomness of the QRSequence.
+func (s QRSequence) random() io.Reader {
+	return orRand(s.rand)
+}
+
+// orRand returns r, or crypto/rand.Reader if r is nil.
+func orRand(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+	return r
+}
@@ -0,0 +1,82 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io"
+)
+
+// FrameReader delivers the frames of a camera, stream or video. It is
+// implemented by Camera, MJPEGStream, Libcamera and Video.
+type FrameReader interface {
+	// ReadFrame waits for the next frame. A *FrameError reports a frame that
+	// cannot be decoded, which is skipped.
+	ReadFrame() (image.Image, error)
+}
+
+// ReceiveFrames decodes frames of r into the receiving QRSequence until it is
+// complete. Frames that cannot be decoded or hold no readable QR code are
+// skipped.
+//
+// If ctx is done while r waits for a frame, stop is called to make the
+// pending ReadFrame return, e.g. by stopping the capture. ReceiveFrames
+// returns after stop has returned. A nil stop leaves the frame being waited
+// for to be read first.
+//
+// Parameters:
+// - ctx: the context to stop receiving with.
+// - r: the source of the frames.
+// - seq: the receiving QRSequence.
+// - stop: the function interrupting a pending ReadFrame, or nil.
+//
+// Returns:
+//   - error: the error of ctx if it is done first, ErrSourceEnded if r ends
+//     first, an error if a frame cannot be read, or the terminal error of the
+//     QRSequence.
+func ReceiveFrames(ctx context.Context, r FrameReader, seq *QRSequence, stop func()) error {
+	if stop != nil {
+		stopped := make(chan struct{})
+		cancel := context.AfterFunc(ctx, func() {
+			stop()
+			close(stopped)
+		})
+		defer func() {
+			if !cancel() {
+				<-stopped
+			}
+		}()
+	}
+
+	for !seq.IsComplete() {
+		if err := seq.Err(); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		img, err := r.ReadFrame()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// the frame may have been cut short by stop
+			return ctxErr
+		}
+		var frameErr *FrameError
+		if errors.As(err, &frameErr) {
+			continue
+		}
+		if err == io.EOF {
+			return ErrSourceEnded
+		}
+		if err != nil {
+			return err
+		}
+		var decodeErr *DecodeError
+		if err := seq.DecodeImage(img); err != nil && !errors.As(err, &decodeErr) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package qrseq
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRechunk(t *testing.T) {
+	data := bytes.Repeat([]byte("rechunk"), 100)
+	sender, err := New(data, WithChunkSize(ChunkSize128), WithECLevel(ECLevelHigh), WithAppTag(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		opts      []Option
+		chunkSize ChunkSize
+		level     ECLevel
+		text      TextEncoding
+		want      error
+	}{
+		{name: "unchanged", chunkSize: ChunkSize128, level: ECLevelHigh},
+		{name: "chunk size", opts: []Option{WithChunkSize(ChunkSize512)}, chunkSize: ChunkSize512, level: ECLevelHigh},
+		{name: "text encoding", opts: []Option{WithTextEncoding(TextBase45)}, chunkSize: ChunkSize128, level: ECLevelHigh, text: TextBase45},
+		{name: "level", opts: []Option{WithChunkSize(ChunkSize2048), WithECLevel(ECLevelLow)}, chunkSize: ChunkSize2048, level: ECLevelLow},
+		{name: "level too high", opts: []Option{WithChunkSize(ChunkSize2048)}, want: ErrECLevelTooHigh},
+		{name: "invalid chunk size", opts: []Option{WithChunkSize(100)}, want: ErrInvalidChunkSize},
+	} {
+		rechunked, err := sender.Rechunk(tc.opts...)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rechunked.ChunkSize != tc.chunkSize || rechunked.ecLevel != tc.level || rechunked.textEncoding != tc.text || rechunked.appTag != 9 {
+			t.Errorf("%s: got chunk size %d, level %v, text %v and app tag %d", tc.name,
+				rechunked.ChunkSize, rechunked.ecLevel, rechunked.textEncoding, rechunked.appTag)
+		}
+
+		payloads, err := rechunked.Payloads()
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver := NewEmpty(WithAppTag(9))
+		for _, payload := range payloads {
+			receiver.AddPayload(payload)
+		}
+		if !bytes.Equal(receiver.Data(), data) {
+			t.Errorf("%s: received payload differs", tc.name)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"time"
+)
+
+// A session recording starts with recordingMagic followed by the format
+// version. Every frame is stored as its offset from the start of the session
+// in nanoseconds (uint64), the length of the encoded image (uint32), both
+// little endian, and the PNG encoded image.
+const (
+	recordingMagic   = "QRSR"
+	recordingVersion = 1
+)
+
+// Frame is a single frame of a recorded receive session.
+type Frame struct {
+	Offset time.Duration // time since the start of the session
+	Image  image.Image
+}
+
+// Recorder records every frame of a receive session while passing it on to
+// the receiving QRSequence, so field failures can be replayed exactly later.
+type Recorder struct {
+	w     io.Writer
+	seq   *QRSequence
+	start time.Time
+}
+
+// NewRecorder creates a new Recorder that writes the session to w and decodes
+// the frames into seq.
+//
+// Parameters:
+// - w: the io.Writer the recording is written to.
+// - seq: the receiving QRSequence to decode the frames into.
+//
+// Returns:
+// - *Recorder: the new Recorder.
+// - error: an error if the recording header cannot be written.
+func NewRecorder(w io.Writer, seq *QRSequence) (*Recorder, error) {
+	if _, err := io.WriteString(w, recordingMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write([]byte{recordingVersion}); err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		w:     w,
+		seq:   seq,
+		start: seq.timeSource().Now(),
+	}, nil
+}
+
+// DecodeImage records the frame with its arrival time and decodes it into the
+// QRSequence of the Recorder.
+//
+// Frames are recorded even if they cannot be decoded or the sequence is
+// already complete, since those are exactly the frames needed to reproduce a
+// failure.
+//
+// Parameters:
+// - img: an image.Image to be recorded and decoded.
+//
+// Returns:
+//   - error: an error if the frame cannot be recorded, or the error returned
+//     by QRSequence.DecodeImage.
+func (r *Recorder) DecodeImage(img image.Image) error {
+	if err := r.record(img); err != nil {
+		return err
+	}
+	return r.seq.DecodeImage(img)
+}
+
+func (r *Recorder) record(img image.Image) error {
+	offset := r.seq.timeSource().Now().Sub(r.start)
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		return err
+	}
+
+	header := make([]byte, 12)
+	binary.LittleEndian.PutUint64(header[0:8], uint64(offset))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(buf.Len()))
+	if _, err := r.w.Write(header); err != nil {
+		return err
+	}
+	_, err := r.w.Write(buf.Bytes())
+	return err
+}
+
+// SessionReader reads the frames of a recorded receive session.
+type SessionReader struct {
+	r *bufio.Reader
+}
+
+// NewSessionReader creates a new SessionReader reading a recording written by
+// a Recorder.
+//
+// Parameters:
+// - r: the io.Reader to read the recording from.
+//
+// Returns:
+// - *SessionReader: the new SessionReader.
+// - error: an error if r does not start with a valid recording header.
+func NewSessionReader(r io.Reader) (*SessionReader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(recordingMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(recordingMagic)]) != recordingMagic {
+		return nil, ErrNotRecording
+	}
+	if header[len(recordingMagic)] != recordingVersion {
+		return nil, ErrRecordingVersion
+	}
+
+	return &SessionReader{r: br}, nil
+}
+
+// Next returns the next frame of the recording.
+//
+// Returns:
+//   - Frame: the next recorded frame.
+//   - error: io.EOF at the end of the recording, or an error if the recording
+//     is truncated or a frame cannot be decoded.
+func (sr *SessionReader) Next() (Frame, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(sr.r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Frame{}, ErrRecordingTruncated
+		}
+		return Frame{}, err
+	}
+	offset := time.Duration(binary.LittleEndian.Uint64(header[0:8]))
+	length := binary.LittleEndian.Uint32(header[8:12])
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(sr.r, data); err != nil {
+		return Frame{}, ErrRecordingTruncated
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Frame{}, err
+	}
+	return Frame{Offset: offset, Image: img}, nil
+}
+
+// Replay feeds all frames of a recorded session into the receiving QRSequence.
+//
+// If realtime is true, the frames are fed with the same timing as they were
+// recorded, otherwise as fast as possible. Decode errors of single frames are
+// part of the recorded session and are ignored.
+//
+// Parameters:
+// - r: the io.Reader to read the recording from.
+// - seq: the receiving QRSequence to decode the frames into.
+// - realtime: whether to reproduce the original frame timing.
+//
+// Returns:
+// - error: an error if the recording cannot be read.
+func Replay(r io.Reader, seq *QRSequence, realtime bool) error {
+	sr, err := NewSessionReader(r)
+	if err != nil {
+		return err
+	}
+
+	clock := seq.timeSource()
+	start := clock.Now()
+	for {
+		frame, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if realtime {
+			sleepUntil(clock, start.Add(frame.Offset))
+		}
+		_ = seq.DecodeImage(frame.Image)
+	}
+}
@@ -0,0 +1,211 @@
+package qrseq
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// Palette selects the gray levels used to render QR codes.
+type Palette uint8
+
+const (
+	// PaletteMono renders pure black modules on a pure white background.
+	PaletteMono Palette = Palette(internal.PaletteMono)
+	// PaletteGray4 keeps black and white module cores but draws the edges
+	// between dark and light modules in two intermediate gray levels. It is
+	// meant for displays where hard black/white transitions smear, such as
+	// some OLED panels at low PWM brightness. It needs a block size of at
+	// least 3 pixels, smaller blocks are rendered as with PaletteMono.
+	PaletteGray4 Palette = Palette(internal.PaletteGray4)
+)
+
+// ECLevel is the error correction level of the rendered QR codes. Higher levels
+// survive more damage, but need larger QR codes for the same chunk.
+type ECLevel uint8
+
+const (
+	// ECLevelDefault selects the default level, ECLevelQuartile.
+	ECLevelDefault ECLevel = ECLevel(internal.ECLevelDefault)
+	// ECLevelLow recovers about 7% of the code.
+	ECLevelLow ECLevel = ECLevel(internal.ECLevelLow)
+	// ECLevelMedium recovers about 15% of the code.
+	ECLevelMedium ECLevel = ECLevel(internal.ECLevelMedium)
+	// ECLevelQuartile recovers about 25% of the code.
+	ECLevelQuartile ECLevel = ECLevel(internal.ECLevelQuartile)
+	// ECLevelHigh recovers about 30% of the code.
+	ECLevelHigh ECLevel = ECLevel(internal.ECLevelHigh)
+)
+
+// String returns the name of the level.
+func (l ECLevel) String() string {
+	switch l {
+	case ECLevelDefault:
+		return "default"
+	case ECLevelLow:
+		return "low"
+	case ECLevelMedium:
+		return "medium"
+	case ECLevelQuartile:
+		return "quartile"
+	case ECLevelHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+// RenderOptions configures how the QR codes of a QRSequence are rendered.
+type RenderOptions struct {
+	// BlockSize is the size of a QR code module in pixels. It is also used
+	// as the width of the quiet zone around the code.
+	BlockSize int
+	// Palette selects the gray levels of the rendered image.
+	Palette Palette
+	// Profile describes the output device the QR codes are rendered for.
+	Profile Profile
+	// Watermark stamps every frame with a watermark strip holding its index
+	// in the rendered sequence and its render time, see AddWatermark.
+	Watermark bool
+	// SyncMarker draws a one block marker in the bottom right corner of the
+	// quiet zone, in the foreground color on frames with an odd index in the
+	// rendered sequence. It toggles with every frame shown, so receivers can
+	// tell consecutive frames apart and drop captures whose exposure
+	// straddles two of them, see ReadSyncMarker. Players render sequences
+	// with an odd number of frames twice, so the marker also toggles where
+	// the loop starts over.
+	SyncMarker bool
+	// ECLevel is the error correction level of the QR codes.
+	ECLevel ECLevel
+	// EdgeChunks is the number of chunks at the start and at the end of the
+	// sequence that are rendered with EdgeECLevel instead. Chunk 0 carries
+	// the length and digest of the payload, and the chunks at the loop
+	// boundary are the ones most often missed, so they are worth the larger
+	// QR codes of a higher level.
+	EdgeChunks int
+	// EdgeECLevel is the error correction level of the edge chunks, or
+	// ECLevelDefault for the level of the other chunks. Rendering fails with
+	// ErrEdgeECLevelTooHigh if the chunks do not fit in a QR code at it.
+	EdgeECLevel ECLevel
+	// Background and Foreground are the colors of the light and dark
+	// modules, white and black if nil. PaletteGray4 draws its edges in
+	// shades between the two. The colors apply to the render call only, so
+	// concurrent renders with different color schemes do not interfere. Most
+	// readers need a dark foreground on a light background with a large
+	// difference in brightness. Terminal output ignores the colors.
+	Background color.Color `json:"-"`
+	Foreground color.Color `json:"-"`
+	// Logo is drawn in the middle of every QR code, on a light square
+	// covering a fifth of the width of the code. Since it hides modules, QR
+	// codes with a logo are always rendered at ECLevelHigh, which holds
+	// chunks of up to ChunkSize512 in base64 and up to ChunkSize1024 in
+	// Base45 or raw, see ChunkSize.MaxECLevelFor. Rendering larger chunks
+	// with a logo fails with ErrLogoChunkSize before any frame is rendered.
+	// NewPlanned picks a chunk size that fits.
+	Logo image.Image `json:"-"`
+
+	// clock is the Clock of the QRSequence the frames are rendered for
+	clock Clock
+	// textEncoding is the TextEncoding of the QRSequence
+	textEncoding TextEncoding
+}
+
+// ecLevel returns the error correction level of chunk nr of tot chunks.
+func (opt RenderOptions) ecLevel(nr, tot int) internal.ECLevel {
+	if nr < opt.EdgeChunks || nr >= tot-opt.EdgeChunks {
+		return opt.level(opt.EdgeECLevel)
+	}
+	return opt.level(opt.ECLevel)
+}
+
+// level returns the error correction level QR codes are rendered at instead of
+// the given one, which is raised to ECLevelHigh if there is a logo.
+func (opt RenderOptions) level(l ECLevel) internal.ECLevel {
+	if opt.Logo != nil {
+		return internal.ECLevelHigh
+	}
+	return internal.ECLevel(l)
+}
+
+// checkLogo checks that chunks of the given size and text encoding fit in a QR
+// code at ECLevelHigh if the options have a logo.
+func (opt RenderOptions) checkLogo(cs ChunkSize, enc TextEncoding) error {
+	if opt.Logo != nil && cs.MaxECLevelFor(enc) < ECLevelHigh {
+		return ErrLogoChunkSize
+	}
+	return nil
+}
+
+// renderOptions returns opt with the error correction level of the sequence
+// filled in if opt does not set one. If neither sets one, the default level is
+// lowered to the highest one that holds the chunks of the sequence. The edge
+// chunks are rendered at that level too, unless opt sets EdgeECLevel.
+//
+// Returns:
+//   - RenderOptions: the options to render the sequence with.
+//   - error: ErrEdgeECLevelTooHigh if the edge chunks do not fit their level,
+//     or ErrLogoChunkSize if the chunks do not fit the level of a logo.
+func (s QRSequence) renderOptions(opt RenderOptions) (RenderOptions, error) {
+	if err := opt.checkLogo(s.ChunkSize, s.textEncoding); err != nil {
+		return RenderOptions{}, err
+	}
+	if opt.ECLevel == ECLevelDefault {
+		opt.ECLevel = s.ecLevel
+	}
+	highest := s.ChunkSize.MaxECLevelFor(s.textEncoding)
+	if opt.ECLevel == ECLevelDefault && highest < ECLevelQuartile {
+		opt.ECLevel = highest
+	}
+	if opt.EdgeECLevel == ECLevelDefault {
+		opt.EdgeECLevel = opt.ECLevel
+	} else if opt.EdgeChunks > 0 && opt.EdgeECLevel > highest {
+		return RenderOptions{}, ErrEdgeECLevelTooHigh
+	}
+	opt.clock = s.timeSource()
+	opt.textEncoding = s.textEncoding
+	return opt, nil
+}
+
+// Profile describes the rendering characteristics of a display or printer, so
+// integrators can ship one with their hardware instead of tuning every render
+// call. The zero value applies no compensation.
+type Profile struct {
+	// Gamma is the gamma of the output device. The gray levels of the
+	// palette are pre-compensated with its inverse. Only intermediate gray
+	// levels are affected, pure black and white stay as they are.
+	Gamma float64
+	// Contrast scales the distance of all gray levels to mid gray, for
+	// devices that bloom at full contrast. It is a value between 0 and 1,
+	// where 0 and 1 both mean full contrast.
+	Contrast float64
+	// MinBlockSize is the smallest module size in pixels the device can
+	// reproduce reliably. Smaller block sizes are raised to it.
+	MinBlockSize int
+	// DotGain is the number of pixels by which ink spreads around dark
+	// modules when printing. Dark modules are shrunk by that amount where
+	// they border light modules. The rendered image is therefore only meant
+	// to be scanned once printed. DotGain is meant for the mono palette.
+	DotGain int
+}
+
+// internal converts the RenderOptions into the options of the image writer,
+// applying the minimum block size of the profile.
+func (opt RenderOptions) internal() *internal.Option {
+	blockSize := opt.BlockSize
+	if blockSize > 0 {
+		blockSize = max(blockSize, opt.Profile.MinBlockSize)
+	}
+
+	return &internal.Option{
+		Padding:      blockSize,
+		BlockSize:    blockSize,
+		Palette:      internal.Palette(opt.Palette),
+		Background:   opt.Background,
+		Foreground:   opt.Foreground,
+		Gamma:        opt.Profile.Gamma,
+		Contrast:     opt.Profile.Contrast,
+		DotGain:      opt.Profile.DotGain,
+		ECLevel:      opt.level(opt.ECLevel),
+		TextEncoding: internal.TextEncoding(opt.textEncoding),
+	}
+}
@@ -0,0 +1,118 @@
+package qrseq
+
+import (
+	"context"
+	"time"
+)
+
+// Completed is the outcome of a receive session as delivered by Result.
+//
+// On success Data holds the reassembled payload and Err is nil. If the session
+// ended with a terminal error, Err is set and Data is nil.
+type Completed struct {
+	Data      []byte
+	ChunkSize ChunkSize
+	Chunks    int
+	Duration  time.Duration // time between the first and the last new chunk
+	Err       error
+}
+
+// Result returns a channel on which the outcome of the QRSequence is delivered
+// once it is complete.
+//
+// The channel delivers exactly one Completed value and is closed afterwards,
+// so it can be used directly in a select statement instead of polling
+// IsComplete and Data. Calling Result more than once returns the same channel.
+// If the sequence is already complete or failed, the value is available
+// immediately.
+//
+// Returns:
+// - <-chan Completed: the channel delivering the outcome of the sequence.
+func (s *QRSequence) Result() <-chan Completed {
+	if s.result == nil {
+		s.result = make(chan Completed, 1)
+		if s.IsComplete() || s.err != nil {
+			s.deliverResult()
+		}
+	}
+	return s.result
+}
+
+// WaitComplete blocks until the receiving QRSequence is complete or ended with
+// a terminal error, or until ctx is done.
+//
+// Unlike the other methods, it may be called while another goroutine passes
+// frames to the QRSequence, e.g. from a camera, so a long-running scan can be
+// waited for with a timeout. Once it returns nil, the payload can be read
+// with Data.
+//
+// Parameters:
+// - ctx: the context bounding the wait.
+//
+// Returns:
+//   - error: nil once the QRSequence is complete, its terminal error, the
+//     error of ctx if it is done first, or an error if the QRSequence was not
+//     created by NewEmpty.
+func (s *QRSequence) WaitComplete(ctx context.Context) error {
+	if s.done == nil {
+		return ErrNotReceiver
+	}
+	// a QRSequence that is already done wins over a context that is done too
+	select {
+	case <-s.done:
+		return s.err
+	default:
+	}
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// markDone wakes up WaitComplete once the QRSequence is complete or ended
+// with a terminal error.
+func (s *QRSequence) markDone() {
+	if s.done == nil {
+		return
+	}
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+// deliverResult sends the outcome of the completed or failed QRSequence on the
+// result channel and closes it. It does nothing if nobody asked for the result or if
+// it has already been delivered.
+func (s *QRSequence) deliverResult() {
+	if s.result == nil || s.resultDelivered {
+		return
+	}
+
+	s.result <- Completed{
+		Data:      s.Data(),
+		ChunkSize: s.ChunkSize,
+		Chunks:    len(s.chunks),
+		Duration:  s.duration(),
+		Err:       s.err,
+	}
+	close(s.result)
+	s.resultDelivered = true
+}
+
+// duration returns the time between the first and the last new chunk.
+func (s QRSequence) duration() time.Duration {
+	var first, last time.Time
+	for _, t := range s.firstSeen {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last.Sub(first)
+}
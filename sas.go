@@ -0,0 +1,52 @@
+package qrseq
+
+const sasInfo = "qrseq sas v1"
+
+// sasWords is the word list of the short authentication string. It has 64
+// short, distinct words, so every word encodes six bits.
+var sasWords = [64]string{
+	"acid", "apple", "arrow", "baker", "bell", "bird", "boat", "bread",
+	"brick", "cable", "candy", "chair", "cloud", "coral", "crown", "dance",
+	"delta", "diver", "dragon", "eagle", "earth", "fence", "flame", "frog",
+	"ghost", "giant", "glass", "grape", "horse", "house", "island", "jelly",
+	"jungle", "kettle", "knife", "lemon", "lion", "magnet", "maple", "metal",
+	"mirror", "moon", "night", "ocean", "olive", "orbit", "panda", "pepper",
+	"piano", "pilot", "radio", "river", "robot", "salt", "shadow", "silver",
+	"snake", "spider", "storm", "tiger", "tomato", "violin", "wagon", "zebra",
+}
+
+// SASWords is the number of words in a short authentication string.
+const SASWords = 4
+
+// SAS returns the short authentication string of the pairing.
+//
+// Both devices derive the same words from the shared secret and both public
+// keys. If the words shown on the two devices match, no machine in the middle
+// replaced the pairing QR codes between the screens and cameras. The string
+// has 24 bits. Since each device fixes the key of the other device before it
+// reveals its own, see Pairing, a machine in the middle has to pick its keys
+// without knowing the strings they lead to, and goes unnoticed with a chance
+// of one in about 16 million per pairing. Without the commitment, as for keys
+// set with SetPeer, it could search for colliding strings in seconds.
+//
+// Returns:
+// - []string: the SASWords words to compare.
+// - error: an error if no peer is set or the key agreement fails.
+func (p *Pairing) SAS() ([]string, error) {
+	if p.peer == nil {
+		return nil, ErrNoPeer
+	}
+	secret, err := p.key.ECDH(p.peer)
+	if err != nil {
+		return nil, err
+	}
+
+	b := hkdfSHA256(secret, p.transcript(sasInfo), 3)
+	bits := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+
+	words := make([]string, SASWords)
+	for i := range words {
+		words[i] = sasWords[bits>>(6*(SASWords-1-i))&0x3f]
+	}
+	return words, nil
+}
@@ -0,0 +1,174 @@
+package qrseq
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// SchemaEnvelope is the manifest a JSON payload is wrapped in by WrapJSON. It
+// declares the schema of the payload, so receivers can check signing requests
+// and other structured payloads before the application sees them.
+type SchemaEnvelope struct {
+	Schema  string          `json:"schema"`  // identifier of the schema, e.g. "airsigner/sign-request/v1"
+	Payload json.RawMessage `json:"payload"` // the wrapped JSON value
+}
+
+// Schema validates the JSON payloads of one schema. A non-nil error rejects
+// the payload.
+type Schema func(payload json.RawMessage) error
+
+// SchemaError is the error returned if a payload is not a valid
+// SchemaEnvelope or does not match its declared schema. It wraps the error of
+// parsing or validating the payload.
+type SchemaError struct {
+	Schema string // the declared schema, empty if the envelope is invalid
+	Err    error
+}
+
+func (e *SchemaError) Error() string {
+	if e.Schema == "" {
+		return "invalid schema envelope: " + e.Err.Error()
+	}
+	return "payload does not match schema " + e.Schema + ": " + e.Err.Error()
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// StrictSchema returns a Schema that decodes payloads into a new value of the
+// expected type, rejecting unknown fields and trailing data. If the value has
+// a Validate() error method, it is called after decoding to check the
+// contents.
+//
+// Parameters:
+//   - newValue: a function returning a pointer to a new value of the type,
+//     e.g. func() any { return new(SignRequest) }.
+//
+// Returns:
+// - Schema: the new Schema.
+func StrictSchema(newValue func() any) Schema {
+	return func(payload json.RawMessage) error {
+		v := newValue()
+		if err := decodeStrict(payload, v); err != nil {
+			return err
+		}
+		if validator, ok := v.(interface{ Validate() error }); ok {
+			return validator.Validate()
+		}
+		return nil
+	}
+}
+
+// WrapJSON marshals a value and wraps it in a SchemaEnvelope declaring its
+// schema, ready to be sent with New.
+//
+// Parameters:
+// - schema: the identifier of the schema of the value.
+// - v: the value to send.
+//
+// Returns:
+// - []byte: the JSON encoded SchemaEnvelope.
+// - error: an error if the schema is empty or the value cannot be marshaled.
+func WrapJSON(schema string, v any) ([]byte, error) {
+	if schema == "" {
+		return nil, ErrEmptySchemaID
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SchemaEnvelope{Schema: schema, Payload: payload})
+}
+
+// SchemaRegistry holds the schemas a receiver accepts, by identifier.
+type SchemaRegistry struct {
+	schemas map[string]Schema
+}
+
+// NewSchemaRegistry creates an empty SchemaRegistry.
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]Schema)}
+}
+
+// Register adds a schema under its identifier.
+//
+// Parameters:
+// - id: the identifier of the schema.
+// - schema: the Schema validating its payloads.
+//
+// Returns:
+//   - error: an error if the identifier is empty or already registered or the
+//     schema is nil.
+func (r *SchemaRegistry) Register(id string, schema Schema) error {
+	if id == "" {
+		return ErrEmptySchemaID
+	}
+	if schema == nil {
+		return ErrNilSchema
+	}
+	if _, ok := r.schemas[id]; ok {
+		return ErrSchemaRegistered
+	}
+	r.schemas[id] = schema
+	return nil
+}
+
+// Unwrap parses a SchemaEnvelope and validates its payload against the
+// registered schema it declares.
+//
+// Parameters:
+// - data: the JSON encoded SchemaEnvelope, e.g. the payload of a QRSequence.
+//
+// Returns:
+//   - string: the identifier of the schema.
+//   - json.RawMessage: the validated payload.
+//   - error: a *SchemaError if the envelope is invalid, its schema is not
+//     registered or the payload does not match it.
+func (r *SchemaRegistry) Unwrap(data []byte) (string, json.RawMessage, error) {
+	var env SchemaEnvelope
+	if err := decodeStrict(data, &env); err != nil {
+		return "", nil, &SchemaError{Err: err}
+	}
+	if env.Schema == "" {
+		return "", nil, &SchemaError{Err: ErrNoSchema}
+	}
+	if len(env.Payload) == 0 || string(env.Payload) == "null" {
+		return "", nil, &SchemaError{Schema: env.Schema, Err: ErrNoSchemaPayload}
+	}
+	schema, ok := r.schemas[env.Schema]
+	if !ok {
+		return "", nil, &SchemaError{Schema: env.Schema, Err: ErrSchemaNotRegistered}
+	}
+	if err := schema(env.Payload); err != nil {
+		return "", nil, &SchemaError{Schema: env.Schema, Err: err}
+	}
+	return env.Schema, env.Payload, nil
+}
+
+// Policy returns a Policy that makes Finalize reject payloads Unwrap rejects,
+// so malformed requests never reach the application.
+//
+// Returns:
+// - Policy: the Policy to add to a receiving QRSequence with AddPolicy.
+func (r *SchemaRegistry) Policy() Policy {
+	return func(data []byte, md Metadata) error {
+		_, _, err := r.Unwrap(data)
+		return err
+	}
+}
+
+// decodeStrict decodes a single JSON value into v, rejecting unknown fields
+// and trailing data.
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrTrailingData
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// degradation is one step of the simulated capture quality ladder used to
+// score frames, from a sharp capture to a small and blurred one. The size is
+// the number of camera pixels a QR code module covers.
+type degradation struct {
+	moduleSize float64
+	radius     int
+}
+
+var degradations = []degradation{
+	{moduleSize: 4, radius: 0},
+	{moduleSize: 4, radius: 1},
+	{moduleSize: 3.5, radius: 1},
+	{moduleSize: 3, radius: 1},
+	{moduleSize: 2.75, radius: 1},
+	{moduleSize: 2.5, radius: 1},
+	{moduleSize: 2.25, radius: 1},
+	{moduleSize: 2, radius: 1},
+}
+
+// moderateDegradation is the step of the ladder that corresponds to a typical
+// phone camera capturing a screen at arm's length.
+const moderateDegradation = 3
+
+// ScanScore is the predicted scannability of a rendered frame.
+type ScanScore struct {
+	// Pass reports whether the frame still decodes under moderate blur and
+	// downscaling.
+	Pass bool
+	// Margin is the number of degradation steps beyond the moderate one the
+	// frame survived. It is negative if the frame failed before reaching the
+	// moderate step.
+	Margin int
+}
+
+// RenderReport holds the predicted scannability of every frame of a rendered
+// QRSequence.
+type RenderReport struct {
+	Scores []ScanScore
+}
+
+// Failing returns the chunk numbers of all frames that are predicted to fail
+// on typical phone cameras.
+//
+// Returns:
+// - []int: the chunk numbers of the failing frames.
+func (r RenderReport) Failing() []int {
+	var failing []int
+	for i, score := range r.Scores {
+		if !score.Pass {
+			failing = append(failing, i)
+		}
+	}
+	return failing
+}
+
+// RenderReport renders every chunk of the QRSequence with the given options
+// and scores its scannability with ScoreFrame.
+//
+// Parameters:
+// - opt: the RenderOptions to render the frames with.
+//
+// Returns:
+//   - *RenderReport: the predicted scannability of every frame.
+//   - error: an error if the QRSequence is not complete or if there is an error
+//     while generating the QR codes.
+func (s QRSequence) RenderReport(opt RenderOptions) (*RenderReport, error) {
+	images, err := s.QRCodesWithOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSize := opt.internal().BlockSize
+	report := &RenderReport{Scores: make([]ScanScore, len(images))}
+	for i, img := range images {
+		report.Scores[i] = scoreFrame(img, blockSize, s.chunks[i])
+	}
+	return report, nil
+}
+
+// ScoreFrame predicts how well a rendered frame scans on a typical phone
+// camera.
+//
+// It resamples the frame so that a module covers fewer and fewer camera pixels,
+// blurs it and tries to decode it after every step. The frame passes if it
+// still decodes at the moderate step of three pixels per module with a slight
+// blur. The margin tells how many steps beyond that it survived.
+//
+// Parameters:
+// - img: the rendered frame to score.
+// - blockSize: the size of a QR code module in the frame in pixels.
+//
+// Returns:
+// - ScanScore: the predicted scannability of the frame.
+func ScoreFrame(img image.Image, blockSize int) ScanScore {
+	return scoreFrame(img, blockSize, nil)
+}
+
+// scoreFrame scores img as ScoreFrame does. If want is not nil, a decode only
+// counts if it reproduces that chunk.
+func scoreFrame(img image.Image, blockSize int, want *internal.QRChunk) ScanScore {
+	survived := -1
+	for i, d := range degradations {
+		factor := float64(blockSize) / d.moduleSize
+		chunk, err := internal.NewChunkFromImage(internal.Degrade(img, factor, d.radius))
+		if err != nil {
+			break
+		}
+		if want != nil && (chunk.Nr() != want.Nr() || !bytes.Equal(chunk.Data(), want.Data())) {
+			break
+		}
+		survived = i
+	}
+
+	return ScanScore{
+		Pass:   survived >= moderateDegradation,
+		Margin: survived - moderateDegradation,
+	}
+}
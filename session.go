@@ -0,0 +1,295 @@
+package qrseq
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// SessionManager routes frames to a separate receiving QRSequence per sequence
+// ID, for scanners that may see frames of several senders at once.
+//
+// Legacy chunks, which carry no sequence ID, share one session that is listed
+// with HasID false and reached with LegacySession and RemoveLegacy.
+//
+// At most DefaultMaxSessions sessions are kept, see SetMaxSessions, so a
+// stream of frames with random sequence IDs cannot grow its memory without
+// bound.
+type SessionManager struct {
+	sessions    map[sessionKey]*session
+	opts        []Option
+	onSession   func(seq *QRSequence)
+	maxSessions int
+	clock       Clock
+	appTag      uint16
+}
+
+// DefaultMaxSessions is the number of sessions a SessionManager keeps unless
+// SetMaxSessions sets another one.
+const DefaultMaxSessions = 16
+
+type sessionKey struct {
+	id    uint32
+	hasID bool
+}
+
+type session struct {
+	seq      *QRSequence
+	started  time.Time
+	lastSeen time.Time
+}
+
+// SessionInfo describes a session of a SessionManager.
+type SessionInfo struct {
+	ID       uint32    // sequence ID of the session
+	HasID    bool      // false for the session of frames without sequence ID
+	Progress float32   // progress of the sequence, between 0 and 1
+	Complete bool      // whether the sequence is complete
+	Started  time.Time // arrival of the first frame
+	LastSeen time.Time // arrival of the latest frame
+}
+
+// NewSessionManager creates a SessionManager without sessions, whose sessions
+// are receivers configured by the options, see NewEmpty. An invalid option
+// ends every session with a terminal error.
+//
+// Parameters:
+//   - opts: the options to apply to the sequence of every session, e.g.
+//     WithEncryption.
+//
+// Returns:
+// - *SessionManager: the new SessionManager.
+func NewSessionManager(opts ...Option) *SessionManager {
+	m := &SessionManager{
+		sessions:    make(map[sessionKey]*session),
+		opts:        opts,
+		maxSessions: DefaultMaxSessions,
+	}
+	if o, err := newOptions(opts); err == nil {
+		m.appTag = o.appTag
+	}
+	return m
+}
+
+// OnSession sets a function that is called with the sequence of every new
+// session before the first frame is added to it, to configure what options
+// do not cover, such as ExpectDigest, AddPolicy or a ReceiverConfig.
+//
+// Parameters:
+// - f: the function to call, or nil to remove it.
+func (m *SessionManager) OnSession(f func(seq *QRSequence)) {
+	m.onSession = f
+}
+
+// SetMaxSessions sets the maximum number of sessions. A frame that would start
+// a session beyond it evicts the incomplete session that has not seen a frame
+// for the longest time. If all sessions are complete, the frame is not routed
+// and ErrTooManySessions is returned until a session is removed.
+//
+// Parameters:
+// - n: the maximum number of sessions, at least 1.
+func (m *SessionManager) SetMaxSessions(n int) {
+	m.maxSessions = max(n, 1)
+}
+
+// SetClock sets the Clock the sessions are timestamped and evicted with. It
+// is passed on to the sequences of the sessions started afterwards.
+//
+// Parameters:
+// - clock: the Clock, or nil for the system clock.
+func (m *SessionManager) SetClock(clock Clock) {
+	m.clock = clock
+}
+
+// SetAppTag sets the application tag frames must carry to be routed, see
+// WithAppTag, replacing the one of the options. Frames of other applications
+// start no session. The tag is passed on to the sequences of the sessions
+// started afterwards.
+//
+// Parameters:
+// - tag: the application tag, or zero to route frames of any application.
+func (m *SessionManager) SetAppTag(tag uint16) {
+	m.appTag = tag
+}
+
+// timeSource returns the Clock of the SessionManager.
+func (m *SessionManager) timeSource() Clock {
+	if m.clock == nil {
+		return systemClock{}
+	}
+	return m.clock
+}
+
+// AddFrame routes a decoded frame to the session of its sequence ID, starting
+// a new session for an ID not seen before.
+//
+// Parameters:
+// - frame: the bytes of the decoded frame.
+//
+// Returns:
+//   - *QRSequence: the sequence of the session the frame was routed to, or nil
+//     if it was not routed.
+//   - error: an error if the frame is invalid, a *ChunkMismatchError if it
+//     carries another application tag, ErrTooManySessions if it would start a
+//     session beyond the maximum and no session can be evicted, or the error
+//     of adding it to the sequence.
+func (m *SessionManager) AddFrame(frame []byte) (*QRSequence, error) {
+	var (
+		key    sessionKey
+		chunk  *internal.QRChunk
+		header internal.FountainHeader
+		err    error
+	)
+	switch internal.FrameLayout(frame) {
+	case internal.LayoutUnknown:
+		return nil, nil
+	case internal.LayoutFountain:
+		if header, _, err = internal.ParseFountainFrame(frame); err != nil {
+			return nil, newFrameError(err)
+		}
+		key.id, key.hasID = header.SeqID, true
+	default:
+		if chunk, err = internal.NewChunk(frame); err != nil {
+			return nil, newFrameError(err)
+		}
+		key.id, key.hasID = chunk.SeqID()
+	}
+	if err := checkAppTag(m.appTag, chunk, header); err != nil {
+		return nil, err
+	}
+
+	now := m.timeSource().Now()
+	sess, ok := m.sessions[key]
+	if !ok {
+		if len(m.sessions) >= m.maxSessions && !m.evictStalest() {
+			return nil, ErrTooManySessions
+		}
+		opts := append(slices.Clip(m.opts), WithAppTag(m.appTag))
+		sess = &session{seq: NewEmpty(opts...), started: now}
+		sess.seq.SetClock(m.clock)
+		if m.onSession != nil {
+			m.onSession(sess.seq)
+		}
+		m.sessions[key] = sess
+	}
+	sess.lastSeen = now
+	return sess.seq, sess.seq.receive(frame)
+}
+
+// evictStalest drops the incomplete session that has not seen a frame for the
+// longest time, and reports whether there was one.
+func (m *SessionManager) evictStalest() bool {
+	var (
+		stalest sessionKey
+		found   bool
+	)
+	for key, sess := range m.sessions {
+		if sess.seq.IsComplete() {
+			continue
+		}
+		if !found || sess.lastSeen.Before(m.sessions[stalest].lastSeen) {
+			stalest, found = key, true
+		}
+	}
+	if found {
+		delete(m.sessions, stalest)
+	}
+	return found
+}
+
+// AddPayload decodes the text of a QR code and routes the frame it carries
+// like AddFrame.
+//
+// Parameters:
+// - text: the text of the QR code.
+//
+// Returns:
+//   - *QRSequence: the sequence of the session the frame was routed to, or nil
+//     if it was not routed.
+//   - error: an error if the text does not hold a valid frame, or the error of
+//     adding it to the sequence.
+func (m *SessionManager) AddPayload(text string) (*QRSequence, error) {
+	frame, err := internal.DecodeText(text)
+	if err != nil {
+		return nil, &DecodeError{Failure: FailureInvalid, Err: err}
+	}
+	return m.AddFrame(frame)
+}
+
+// Sessions lists the sessions of the SessionManager, ordered by sequence ID.
+func (m *SessionManager) Sessions() []SessionInfo {
+	infos := make([]SessionInfo, 0, len(m.sessions))
+	for key, sess := range m.sessions {
+		infos = append(infos, SessionInfo{
+			ID:       key.id,
+			HasID:    key.hasID,
+			Progress: sess.seq.Progress(),
+			Complete: sess.seq.IsComplete(),
+			Started:  sess.started,
+			LastSeen: sess.lastSeen,
+		})
+	}
+	slices.SortFunc(infos, func(a, b SessionInfo) int {
+		if a.HasID != b.HasID {
+			if a.HasID {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return infos
+}
+
+// Session returns the sequence of the session with the given sequence ID, or
+// nil if there is none. The session of frames without sequence ID is
+// returned by LegacySession.
+func (m *SessionManager) Session(id uint32) *QRSequence {
+	if sess, ok := m.sessions[sessionKey{id: id, hasID: true}]; ok {
+		return sess.seq
+	}
+	return nil
+}
+
+// Remove drops the session with the given sequence ID, typically once its
+// payload has been taken.
+func (m *SessionManager) Remove(id uint32) {
+	delete(m.sessions, sessionKey{id: id, hasID: true})
+}
+
+// LegacySession returns the sequence of the session of the frames without
+// sequence ID, or nil if there is none.
+func (m *SessionManager) LegacySession() *QRSequence {
+	if sess, ok := m.sessions[sessionKey{}]; ok {
+		return sess.seq
+	}
+	return nil
+}
+
+// RemoveLegacy drops the session of the frames without sequence ID, like
+// Remove.
+func (m *SessionManager) RemoveLegacy() {
+	delete(m.sessions, sessionKey{})
+}
+
+// Evict drops the incomplete sessions that have not seen a frame for the given
+// duration, such as transfers the scanner only caught a glimpse of.
+//
+// Parameters:
+// - idle: the time without frames after which a session is stale.
+//
+// Returns:
+// - int: the number of evicted sessions.
+func (m *SessionManager) Evict(idle time.Duration) int {
+	now := m.timeSource().Now()
+	evicted := 0
+	for key, sess := range m.sessions {
+		if !sess.seq.IsComplete() && now.Sub(sess.lastSeen) > idle {
+			delete(m.sessions, key)
+			evicted++
+		}
+	}
+	return evicted
+}
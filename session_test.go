@@ -0,0 +1,124 @@
+package qrseq
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testClock is a Clock that only advances when told to.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time        { return c.now }
+func (c *testClock) Sleep(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestSessionManagerLimit(t *testing.T) {
+	senders := make([]*QRSequence, 3)
+	for i := range senders {
+		var err error
+		if senders[i], err = New(bytes.Repeat([]byte{byte(i)}, 40), WithChunkSize(ChunkSize32)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id := func(i int) uint32 {
+		seqID, _ := senders[i].chunks[0].SeqID()
+		return seqID
+	}
+
+	clock := &testClock{now: time.Unix(0, 0)}
+	m := NewSessionManager()
+	m.SetClock(clock)
+	m.SetMaxSessions(2)
+
+	// every step adds the first or all chunks of a sender to m, a second
+	// after the step before
+	for _, step := range []struct {
+		name   string
+		sender int
+		all    bool
+		remove int // sender to remove before adding the chunks, or -1
+		want   error
+		ids    []int // senders that have a session afterwards
+	}{
+		{name: "first", sender: 0, remove: -1, ids: []int{0}},
+		{name: "second", sender: 1, remove: -1, ids: []int{0, 1}},
+		{name: "evicts stalest", sender: 2, remove: -1, ids: []int{1, 2}},
+		{name: "completes", sender: 1, all: true, remove: -1, ids: []int{1, 2}},
+		{name: "completes other", sender: 2, all: true, remove: -1, ids: []int{1, 2}},
+		{name: "all complete", sender: 0, remove: -1, want: ErrTooManySessions, ids: []int{1, 2}},
+		{name: "after remove", sender: 0, remove: 1, ids: []int{0, 2}},
+	} {
+		clock.Sleep(time.Second)
+		if step.remove >= 0 {
+			m.Remove(id(step.remove))
+		}
+		chunks := senders[step.sender].chunks
+		if !step.all {
+			chunks = chunks[:1]
+		}
+		var err error
+		for _, chunk := range chunks {
+			if _, err = m.AddFrame(chunk.Bytes()); err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, step.want) {
+			t.Errorf("%s: got %v, want %v", step.name, err, step.want)
+		}
+
+		var got, want []uint32
+		for _, info := range m.Sessions() {
+			got = append(got, info.ID)
+		}
+		for _, i := range step.ids {
+			want = append(want, id(i))
+		}
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got sessions %v, want %v", step.name, got, want)
+		}
+	}
+
+	// the session of sender 0 is incomplete and idle, the one of sender 2
+	// complete
+	clock.Sleep(time.Minute)
+	if n := m.Evict(30 * time.Second); n != 1 {
+		t.Errorf("Evict: got %d evicted sessions, want 1", n)
+	}
+	if m.Session(id(0)) != nil || m.Session(id(2)) == nil {
+		t.Errorf("Evict: got sessions %v", m.Sessions())
+	}
+}
+
+func TestSessionManagerLegacySession(t *testing.T) {
+	// a legacy chunk 0 of 1 of size 32, and a chunk with a sequence ID
+	legacy := append([]byte{0, 1, byte(ChunkSize32), 0}, "legacy"...)
+	sender, err := New([]byte("v2"), WithChunkSize(ChunkSize32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqID, _ := sender.chunks[0].SeqID()
+
+	m := NewSessionManager()
+	for _, frame := range [][]byte{legacy, sender.chunks[0].Bytes()} {
+		if _, err := m.AddFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seq := m.LegacySession()
+	if seq == nil || !bytes.Equal(seq.Data(), []byte("legacy")) {
+		t.Fatalf("got legacy session %v", seq)
+	}
+
+	m.RemoveLegacy()
+	if m.LegacySession() != nil {
+		t.Error("legacy session not removed")
+	}
+	if m.Session(seqID) == nil {
+		t.Error("session with sequence ID removed")
+	}
+}
@@ -0,0 +1,226 @@
+//go:build linux && !core
+
+package qrseq
+
+import (
+	"encoding/binary"
+	"image"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// A frame ring starts with a header of frameRingHeaderSize bytes: the magic,
+// the version, the number of slots, the frame width and height (uint32 each)
+// and, at offset 24, the number of the last published frame (uint64). It is
+// followed by the slots, each holding the number of the frame it contains
+// (uint64), 8 reserved bytes and the 8 bit gray pixels of the frame. All
+// integers are in native byte order, as the ring is only shared between
+// processes on the same machine.
+const (
+	frameRingMagic      = "QRSM"
+	frameRingVersion    = 1
+	frameRingHeaderSize = 64
+	frameRingSlotHeader = 16
+	frameRingPublished  = 24
+)
+
+// FrameRing is a ring buffer of camera frames in shared memory, written by a
+// separate capture process such as a privileged camera daemon and read by the
+// receiver without copying frames through pipes.
+//
+// The writer fills a slot and then publishes its frame number. The reader
+// decodes frames directly from the shared memory and checks the frame number
+// of the slot again afterwards, so frames overwritten while being decoded are
+// discarded instead of yielding torn data.
+type FrameRing struct {
+	mem    []byte
+	slots  int
+	width  int
+	height int
+	read   uint64 // number of the last frame read
+}
+
+// CreateFrameRing creates a frame ring file at path, usually below /dev/shm,
+// and maps it into memory.
+//
+// Parameters:
+// - path: the path of the ring file.
+// - slots: the number of frames the ring holds.
+// - width: the width of the frames in pixels.
+// - height: the height of the frames in pixels.
+//
+// Returns:
+// - *FrameRing: the mapped ring.
+// - error: an error if the parameters are invalid or the file cannot be mapped.
+func CreateFrameRing(path string, slots, width, height int) (*FrameRing, error) {
+	if slots < 1 || width < 1 || height < 1 {
+		return nil, ErrInvalidFrameRingSize
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	size := frameRingHeaderSize + slots*frameRingSlotSize(width, height)
+	if err := f.Truncate(int64(size)); err != nil {
+		return nil, err
+	}
+	mem, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(mem, frameRingMagic)
+	binary.NativeEndian.PutUint32(mem[4:8], frameRingVersion)
+	binary.NativeEndian.PutUint32(mem[8:12], uint32(slots))
+	binary.NativeEndian.PutUint32(mem[12:16], uint32(width))
+	binary.NativeEndian.PutUint32(mem[16:20], uint32(height))
+	return &FrameRing{mem: mem, slots: slots, width: width, height: height}, nil
+}
+
+// OpenFrameRing maps an existing frame ring, e.g. a file below /dev/shm or a
+// memfd passed as /proc/self/fd/N.
+//
+// Parameters:
+// - path: the path of the ring file.
+//
+// Returns:
+// - *FrameRing: the mapped ring.
+// - error: an error if the file cannot be mapped or is not a frame ring.
+func OpenFrameRing(path string) (*FrameRing, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < frameRingHeaderSize {
+		return nil, ErrNotFrameRing
+	}
+	mem, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FrameRing{
+		mem:    mem,
+		slots:  int(binary.NativeEndian.Uint32(mem[8:12])),
+		width:  int(binary.NativeEndian.Uint32(mem[12:16])),
+		height: int(binary.NativeEndian.Uint32(mem[16:20])),
+	}
+	switch {
+	case string(mem[:4]) != frameRingMagic:
+		err = ErrNotFrameRing
+	case binary.NativeEndian.Uint32(mem[4:8]) != frameRingVersion:
+		err = ErrFrameRingVersion
+	case r.slots < 1 || r.width < 1 || r.height < 1 ||
+		int64(frameRingHeaderSize+r.slots*frameRingSlotSize(r.width, r.height)) > info.Size():
+		err = ErrInvalidFrameRingSize
+	}
+	if err != nil {
+		syscall.Munmap(mem)
+		return nil, err
+	}
+	r.read = r.published()
+	return r, nil
+}
+
+// frameRingSlotSize returns the size of a slot, padded to keep the frame
+// numbers 8 byte aligned.
+func frameRingSlotSize(width, height int) int {
+	return (frameRingSlotHeader + width*height + 7) &^ 7
+}
+
+func (r *FrameRing) counter(offset int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&r.mem[offset]))
+}
+
+func (r *FrameRing) published() uint64 {
+	return atomic.LoadUint64(r.counter(frameRingPublished))
+}
+
+func (r *FrameRing) slot(nr uint64) (int, []byte) {
+	offset := frameRingHeaderSize + int(nr%uint64(r.slots))*frameRingSlotSize(r.width, r.height)
+	pixels := offset + frameRingSlotHeader
+	return offset, r.mem[pixels : pixels+r.width*r.height]
+}
+
+// WriteFrame publishes a frame to the ring, overwriting the oldest one.
+//
+// Parameters:
+// - img: the frame, which must have the size of the ring.
+//
+// Returns:
+// - error: an error if the frame has the wrong size.
+func (r *FrameRing) WriteFrame(img *image.Gray) error {
+	if img.Rect.Dx() != r.width || img.Rect.Dy() != r.height {
+		return ErrFrameSizeMismatch
+	}
+
+	nr := r.published() + 1
+	offset, pixels := r.slot(nr)
+	atomic.StoreUint64(r.counter(offset), 0)
+	for y := 0; y < r.height; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+r.width]
+		copy(pixels[y*r.width:], row)
+	}
+	atomic.StoreUint64(r.counter(offset), nr)
+	atomic.StoreUint64(r.counter(frameRingPublished), nr)
+	return nil
+}
+
+// Ingest decodes all frames published since the last call into the receiving
+// QRSequence, reading them directly from shared memory.
+//
+// Frames the writer overwrote before or while they were decoded are skipped.
+//
+// Parameters:
+// - seq: the receiving QRSequence.
+//
+// Returns:
+//   - int: the number of frames decoded.
+//   - error: the terminal error of the QRSequence, if it failed.
+func (r *FrameRing) Ingest(seq *QRSequence) (int, error) {
+	published := r.published()
+	if published-r.read > uint64(r.slots) {
+		r.read = published - uint64(r.slots)
+	}
+
+	decoded := 0
+	for ; r.read < published; r.read++ {
+		if seq.Err() != nil {
+			return decoded, seq.Err()
+		}
+		if seq.IsComplete() {
+			r.read = published
+			break
+		}
+
+		nr := r.read + 1
+		offset, pixels := r.slot(nr)
+		if atomic.LoadUint64(r.counter(offset)) != nr {
+			continue
+		}
+		img := &image.Gray{Pix: pixels, Stride: r.width, Rect: image.Rect(0, 0, r.width, r.height)}
+		frame, err := seq.readFrame(img)
+		if atomic.LoadUint64(r.counter(offset)) != nr || err != nil {
+			continue
+		}
+		decoded++
+		seq.receive(frame)
+	}
+	return decoded, seq.Err()
+}
+
+// Close unmaps the ring.
+func (r *FrameRing) Close() error {
+	return syscall.Munmap(r.mem)
+}
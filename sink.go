@@ -0,0 +1,130 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FrameSink writes frames as numbered PNG files into a directory at a fixed
+// frame rate, so slideshow tools such as feh or fbi watching the directory can
+// act as the display surface.
+//
+// Every file is written under a temporary name and renamed into place, so the
+// tools never load a partly written frame. The files are named like those of
+// QRSequence.FS, numbered by the order they were written in.
+type FrameSink struct {
+	dir      string
+	interval time.Duration
+	keep     int
+	next     int
+	due      time.Time
+	clock    Clock
+}
+
+// NewFrameSink creates a FrameSink writing into dir, which must exist.
+//
+// Parameters:
+// - dir: the directory the frames are written to.
+// - fps: the number of frames written per second.
+// - keep: the number of most recent frames kept, or 0 to keep all of them.
+//
+// Returns:
+// - *FrameSink: the new FrameSink.
+// - error: an error if fps or keep is invalid or dir is not a directory.
+func NewFrameSink(dir string, fps float64, keep int) (*FrameSink, error) {
+	if fps <= 0 {
+		return nil, ErrInvalidFrameRate
+	}
+	if keep < 0 {
+		return nil, ErrInvalidKeep
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, ErrNotDirectory
+	}
+
+	return &FrameSink{
+		dir:      dir,
+		interval: time.Duration(float64(time.Second) / fps),
+		keep:     keep,
+	}, nil
+}
+
+// WriteFrame waits until the next frame is due and writes img as the next
+// numbered file, removing the oldest file beyond the number of kept frames.
+//
+// Parameters:
+// - img: the frame to write.
+//
+// Returns:
+// - error: an error if the frame cannot be written.
+func (k *FrameSink) WriteFrame(img image.Image) error {
+	clock := k.timeSource()
+	if k.due.IsZero() {
+		k.due = clock.Now()
+	}
+	sleepUntil(clock, k.due)
+
+	if err := writeImageFile(filepath.Join(k.dir, frameName(k.next)), img, time.Now()); err != nil {
+		return err
+	}
+	if k.keep > 0 && k.next >= k.keep {
+		err := os.Remove(filepath.Join(k.dir, frameName(k.next-k.keep)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	k.next++
+	k.due = k.due.Add(k.interval)
+	return nil
+}
+
+// SetClock sets the Clock the frames are scheduled with.
+//
+// Parameters:
+// - clock: the Clock, or nil for the system clock.
+func (k *FrameSink) SetClock(clock Clock) {
+	k.clock = clock
+}
+
+// timeSource returns the Clock of the FrameSink.
+func (k *FrameSink) timeSource() Clock {
+	if k.clock == nil {
+		return systemClock{}
+	}
+	return k.clock
+}
+
+// Play writes the frames of a complete QRSequence in a loop.
+//
+// Parameters:
+// - seq: the sending QRSequence.
+// - opt: the RenderOptions to render the frames with.
+// - loops: the number of times the sequence is written.
+//
+// Returns:
+//   - error: an error if the sequence is not complete or a frame cannot be
+//     rendered or written.
+func (k *FrameSink) Play(seq *QRSequence, opt RenderOptions, loops int) error {
+	images, err := seq.QRCodesWithOptions(opt)
+	if err != nil {
+		return err
+	}
+
+	for loop := 0; loop < loops; loop++ {
+		for _, img := range images {
+			if err := k.WriteFrame(img); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
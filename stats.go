@@ -0,0 +1,252 @@
+package qrseq
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// DecodeFailure classifies why a frame could not be decoded.
+type DecodeFailure uint8
+
+const (
+	// FailureNotFound means no QR code was found in the frame.
+	FailureNotFound DecodeFailure = iota + 1
+	// FailureChecksum means a QR code was found, but it had more errors than
+	// its error correction could repair.
+	FailureChecksum
+	// FailureFormat means a QR code was found, but its format information or
+	// structure could not be read.
+	FailureFormat
+	// FailureInvalid means a QR code was read, but it does not hold a valid
+	// chunk.
+	FailureInvalid
+	// FailureAborted means decoding the frame exceeded the frame budget.
+	FailureAborted
+	// FailureCorrupt means a chunk was read, but its CRC does not match, so
+	// the QR code was misread or the chunk corrupted.
+	FailureCorrupt
+	// FailureTorn means the frame was dropped without decoding it, because
+	// the camera exposure straddled two displayed QR codes.
+	FailureTorn
+)
+
+// String returns a description of the failure suitable for users.
+func (f DecodeFailure) String() string {
+	switch f {
+	case FailureNotFound:
+		return "no code in view"
+	case FailureChecksum, FailureFormat, FailureCorrupt:
+		return "code in view but unreadable"
+	case FailureInvalid:
+		return "code is not part of a sequence"
+	case FailureAborted:
+		return "decoding too slow"
+	case FailureTorn:
+		return "code changed during capture"
+	}
+	return "unknown failure"
+}
+
+// DecodeError is the error returned by QRSequence.DecodeImage if a frame
+// cannot be decoded. It wraps the error of the QR code reader, and matches
+// the error of its failure with errors.Is, e.g. ErrNoQRFound.
+type DecodeError struct {
+	Failure DecodeFailure
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Failure.String() + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the error of the failure, so errors.Is
+// matches it without depending on the QR code reader.
+func (e *DecodeError) Is(target error) bool {
+	return target != nil && failureErrors[e.Failure] == target
+}
+
+// CRCError is the error wrapped by a DecodeError of FailureCorrupt. It holds
+// the chunk number as read and the mismatching CRCs.
+type CRCError = internal.CRCError
+
+// Stats holds the decode counters of a receive session.
+type Stats struct {
+	Frames  int `json:"frames"`  // frames passed to DecodeImage before the sequence ended
+	Decoded int `json:"decoded"` // frames that decoded into a chunk
+
+	NotFound int `json:"not_found"` // frames without a QR code
+	Checksum int `json:"checksum"`  // frames with a QR code that could not be repaired
+	Format   int `json:"format"`    // frames with a QR code whose format could not be read
+	Invalid  int `json:"invalid"`   // frames with a QR code that is not a chunk
+	Aborted  int `json:"aborted"`   // frames that exceeded the frame budget
+	Corrupt  int `json:"corrupt"`   // frames with a chunk whose CRC does not match
+	Torn     int `json:"torn"`      // frames dropped as blends of two QR codes
+
+	// Accepted, Duplicates, Foreign and Ignored break the decoded frames
+	// down by what became of their chunk, see ChunkResult. A transfer that
+	// stalls with many duplicates lacks the missing chunks in its loop, one
+	// with many foreign chunks sees another sender.
+	Accepted   int `json:"accepted"`   // frames with a new chunk or fountain frame
+	Duplicates int `json:"duplicates"` // frames with a chunk received before
+	Foreign    int `json:"foreign"`    // frames with a chunk of another sequence
+	Ignored    int `json:"ignored"`    // frames with a decoy or an unknown frame type
+
+	// AfterComplete counts the frames decoded after the sequence was
+	// complete, if the CompletionMode asks for them.
+	AfterComplete int `json:"after_complete"`
+
+	// Duration is the time between the first and the last new chunk.
+	Duration time.Duration `json:"duration"`
+	// Attempts holds how often each chunk was received, indexed by chunk
+	// number. It is nil for fountain coded sequences, whose frames do not
+	// map to chunks.
+	Attempts []int `json:"attempts"`
+}
+
+// ExportFormat selects the format of Stats.Export.
+type ExportFormat uint8
+
+const (
+	// ExportJSON writes the statistics as a JSON object.
+	ExportJSON ExportFormat = iota
+	// ExportCSV writes the statistics as a CSV header and one record, with
+	// the duration in milliseconds and the attempts separated by spaces.
+	ExportCSV
+)
+
+// Unreadable returns the number of frames in which a QR code was seen but
+// could not be read.
+func (st Stats) Unreadable() int {
+	return st.Checksum + st.Format + st.Corrupt
+}
+
+// Stats returns the decode counters of the QRSequence.
+//
+// They tell apart a camera that sees no code at all from one that sees a code
+// it cannot read, which call for different fixes: aiming the camera versus
+// changing distance, focus or block size.
+//
+// Returns:
+// - Stats: a snapshot of the decode counters.
+func (s QRSequence) Stats() Stats {
+	st := s.stats
+	st.Duration = s.duration()
+	st.Attempts = slices.Clone(s.attempts)
+	return st
+}
+
+// Export writes a machine-readable summary of the statistics, for monitoring
+// the receive quality of a fleet of devices.
+//
+// Parameters:
+// - w: the io.Writer the summary is written to.
+// - format: the format of the summary.
+//
+// Returns:
+// - error: an error if the format is unknown or writing fails.
+func (st Stats) Export(w io.Writer, format ExportFormat) error {
+	switch format {
+	case ExportJSON:
+		return json.NewEncoder(w).Encode(st)
+	case ExportCSV:
+		attempts := make([]string, len(st.Attempts))
+		for i, n := range st.Attempts {
+			attempts[i] = strconv.Itoa(n)
+		}
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"duration_ms", "frames", "decoded", "not_found", "checksum", "format", "invalid", "aborted", "corrupt", "after_complete", "attempts",
+			"accepted", "duplicates", "foreign", "ignored", "torn"})
+		cw.Write([]string{
+			strconv.FormatInt(st.Duration.Milliseconds(), 10),
+			strconv.Itoa(st.Frames),
+			strconv.Itoa(st.Decoded),
+			strconv.Itoa(st.NotFound),
+			strconv.Itoa(st.Checksum),
+			strconv.Itoa(st.Format),
+			strconv.Itoa(st.Invalid),
+			strconv.Itoa(st.Aborted),
+			strconv.Itoa(st.Corrupt),
+			strconv.Itoa(st.AfterComplete),
+			strings.Join(attempts, " "),
+			strconv.Itoa(st.Accepted),
+			strconv.Itoa(st.Duplicates),
+			strconv.Itoa(st.Foreign),
+			strconv.Itoa(st.Ignored),
+			strconv.Itoa(st.Torn),
+		})
+		cw.Flush()
+		return cw.Error()
+	}
+	return ErrUnknownExportFormat
+}
+
+// newFrameError classifies an error returned while adding a decoded frame.
+func newFrameError(err error) *DecodeError {
+	var crcErr *CRCError
+	if errors.As(err, &crcErr) {
+		return &DecodeError{Failure: FailureCorrupt, Err: err}
+	}
+	return &DecodeError{Failure: FailureInvalid, Err: err}
+}
+
+// countFailure counts a frame that could not be decoded, unless it arrived
+// after the sequence was complete and is not part of its receive session.
+func (s *QRSequence) countFailure(err *DecodeError) {
+	if !s.IsComplete() {
+		s.stats.count(err)
+	}
+}
+
+// count updates the decode counters with the outcome of a frame.
+func (st *Stats) count(err *DecodeError) {
+	st.Frames++
+	if err == nil {
+		st.Decoded++
+		return
+	}
+
+	switch err.Failure {
+	case FailureNotFound:
+		st.NotFound++
+	case FailureChecksum:
+		st.Checksum++
+	case FailureFormat:
+		st.Format++
+	case FailureInvalid:
+		st.Invalid++
+	case FailureAborted:
+		st.Aborted++
+	case FailureCorrupt:
+		st.Corrupt++
+	case FailureTorn:
+		st.Torn++
+	}
+}
+
+// countChunk updates the counters of the decoded frames with what became of
+// the chunk of a frame.
+func (st *Stats) countChunk(result ChunkResult) {
+	switch result {
+	case ChunkAccepted:
+		st.Accepted++
+	case ChunkDuplicate:
+		st.Duplicates++
+	case ChunkRejected:
+		st.Foreign++
+	case ChunkIgnored:
+		st.Ignored++
+	}
+}
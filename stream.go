@@ -0,0 +1,143 @@
+package qrseq
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"io"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// WriteTo writes the payload of a receiving QRSequence to w as far as it has
+// been received in order, and releases the written chunks.
+//
+// Calling it after every new chunk drains the payload incrementally to disk or
+// a pipe, so memory stays bounded for large transfers. Once draining started,
+// Data returns nil, while Digest and the expected digest check still cover the
+// whole payload. Compressed or encrypted payloads cannot be drained and are
+// taken with Data.
+//
+// Parameters:
+// - w: the io.Writer to write the payload to.
+//
+// Returns:
+// - int64: the number of bytes written.
+//   - error: the error returned by w, if any, or an error if the payload is
+//     compressed or encrypted.
+func (s *QRSequence) WriteTo(w io.Writer) (int64, error) {
+	if s.encoding() != 0 {
+		return 0, ErrEncodedStream
+	}
+
+	var n int64
+	for chunk := s.nextChunk(); chunk != nil; chunk = s.nextChunk() {
+		m, err := w.Write(chunk.Data())
+		s.consume(chunk, m)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// DataReader returns a reader over the payload of a receiving QRSequence.
+//
+// Like WriteTo, the reader releases the chunks it has returned. It returns
+// ErrChunkPending while the next chunk is missing, io.EOF after the whole
+// payload of a complete sequence has been read and the terminal error of a
+// failed sequence.
+//
+// Returns:
+// - io.Reader: the reader over the payload.
+func (s *QRSequence) DataReader() io.Reader {
+	return &dataReader{s: s}
+}
+
+type dataReader struct {
+	s *QRSequence
+}
+
+func (r *dataReader) Read(p []byte) (int, error) {
+	if r.s.encoding() != 0 {
+		return 0, ErrEncodedStream
+	}
+
+	n := 0
+	for n < len(p) {
+		chunk := r.s.nextChunk()
+		if chunk == nil {
+			break
+		}
+		m := copy(p[n:], chunk.Data())
+		r.s.consume(chunk, m)
+		n += m
+	}
+
+	switch {
+	case n > 0:
+		return n, nil
+	case r.s.err != nil:
+		return 0, r.s.err
+	case r.s.IsComplete():
+		return 0, io.EOF
+	}
+	return 0, ErrChunkPending
+}
+
+// WriteTo lets io.Copy drain the sequence without an intermediate buffer.
+func (r *dataReader) WriteTo(w io.Writer) (int64, error) {
+	return r.s.WriteTo(w)
+}
+
+// nextChunk returns the chunk the payload continues with, or nil if it has not
+// been received yet or the payload has been drained completely.
+func (s *QRSequence) nextChunk() *internal.QRChunk {
+	for s.drained < len(s.chunks) && s.chunks[s.drained] != nil {
+		chunk := s.chunks[s.drained]
+		if len(chunk.Data()) > 0 {
+			return chunk
+		}
+		s.drained++
+	}
+	return nil
+}
+
+// consume marks the first n bytes of chunk as drained.
+func (s *QRSequence) consume(chunk *internal.QRChunk, n int) {
+	if s.drainHash == nil {
+		s.drainHash = sha256.New()
+	}
+	s.drainHash.Write(chunk.Data()[:n])
+	s.drainedBytes += n
+	chunk.Consume(n)
+}
+
+// payloadDigest returns the SHA-256 digest of the payload of a sequence whose
+// chunks have all arrived, after its encodings have been undone.
+func (s QRSequence) payloadDigest() [sha256.Size]byte {
+	if s.decoded != nil {
+		return sha256.Sum256(s.decoded)
+	}
+	return s.sentDigest()
+}
+
+// sentDigest returns the SHA-256 digest of the bytes carried by the chunks of a
+// sequence whose chunks have all arrived, including the part that has already
+// been drained.
+func (s QRSequence) sentDigest() [sha256.Size]byte {
+	if s.drainHash == nil {
+		return sha256.Sum256(internal.GetData(s.chunks))
+	}
+
+	h := sha256.New()
+	state, _ := s.drainHash.(encoding.BinaryMarshaler).MarshalBinary()
+	_ = h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+	for _, chunk := range s.chunks[s.drained:] {
+		h.Write(chunk.Data())
+	}
+
+	var digest [sha256.Size]byte
+	h.Sum(digest[:0])
+	return digest
+}
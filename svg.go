@@ -0,0 +1,46 @@
+//go:build !core
+
+package qrseq
+
+import "bytes"
+
+// QRCodesSVG generates an SVG document for the QR code of each chunk in the
+// QRSequence, for web frontends and print pipelines that need output
+// independent of resolution.
+//
+// The documents have the dimensions of the images of QRCodesWithOptions, with
+// the block size as unit. The error correction level, palette contrast and
+// gamma of the options are applied. The logo, the sync marker, the watermark
+// strip, the dot gain compensation and the intermediate gray levels of
+// PaletteGray4 are raster features and are left out.
+//
+// Parameters:
+// - opt: the RenderOptions to render the QR codes with.
+//
+// Returns:
+//   - [][]byte: an SVG document for each chunk in the QRSequence.
+//   - error: an error if the QRSequence is not complete or if there is an error
+//     while generating the QR codes.
+func (s QRSequence) QRCodesSVG(opt RenderOptions) ([][]byte, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+
+	opt.Logo = nil
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([][]byte, 0, len(s.chunks))
+	for _, chunk := range s.chunks {
+		o := opt.internal()
+		o.ECLevel = opt.ecLevel(chunk.Nr(), chunk.Tot())
+
+		buf := new(bytes.Buffer)
+		if err := chunk.RenderSVG(buf, o); err != nil {
+			return nil, err
+		}
+		docs = append(docs, buf.Bytes())
+	}
+	return docs, nil
+}
@@ -0,0 +1,80 @@
+package qrseq
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// The sync marker is a single block in the bottom right corner of the quiet
+// zone, the only corner of a QR code without a finder pattern next to it. It
+// is dark on frames with an odd index and light on the others, so it toggles
+// with every frame shown.
+
+// addSyncMarker returns a copy of the rendered QR code img with a dark sync
+// marker of the given color. Paletted frames stay paletted, with the color
+// added to the palette if needed, and gray frames stay gray if the color is.
+func addSyncMarker(img image.Image, dark color.Color, blockSize int) image.Image {
+	b := img.Bounds()
+	bs := max(blockSize, 1)
+
+	var out draw.Image = image.NewRGBA(b)
+	switch src := img.(type) {
+	case *image.Paletted:
+		palette := append(color.Palette(nil), src.Palette...)
+		if !sameColor(palette[palette.Index(dark)], dark) && len(palette) < 256 {
+			palette = append(palette, dark)
+		}
+		if sameColor(palette[palette.Index(dark)], dark) {
+			out = image.NewPaletted(b, palette)
+		}
+	case *image.Gray:
+		if _, ok := dark.(color.Gray); ok {
+			out = image.NewGray(b)
+		}
+	}
+	draw.Draw(out, b, img, b.Min, draw.Src)
+	marker := image.Rect(b.Max.X-bs, b.Max.Y-bs, b.Max.X, b.Max.Y)
+	draw.Draw(out, marker, image.NewUniform(dark), image.Point{}, draw.Src)
+	return out
+}
+
+// ReadSyncMarker reads the sync marker of a frame rendered with
+// RenderOptions.SyncMarker.
+//
+// It samples the middle of the bottom right block of the QR code, skipping the
+// watermark strip of stamped frames, so it reads rendered frames and screen
+// recordings, not camera footage. Consecutive frames with the same state show
+// the same displayed frame, frames with different states two different ones.
+//
+// Parameters:
+// - img: the rendered frame.
+// - blockSize: the block size the frame was rendered with.
+//
+// Returns:
+//   - bool: whether the marker is dark, which it is on frames with an odd
+//     index.
+//   - error: an error if the frame is too small to hold a marker.
+func ReadSyncMarker(img image.Image, blockSize int) (bool, error) {
+	bs := max(blockSize, 1)
+	qr := img.Bounds()
+	if _, err := ReadWatermark(img); err == nil {
+		// the QR code is square and centered above the strip
+		side := qr.Dy() - 3*bs
+		qr.Min.X += (qr.Dx() - side) / 2
+		qr.Max = image.Pt(qr.Min.X+side, qr.Max.Y-3*bs)
+	}
+	if qr.Dx() < 2*bs || qr.Dy() < 2*bs {
+		return false, ErrNoSyncMarker
+	}
+
+	gray := color.GrayModel.Convert(img.At(qr.Max.X-1-bs/2, qr.Max.Y-1-bs/2)).(color.Gray)
+	return gray.Y < 128, nil
+}
+
+// sameColor reports whether two colors are the same.
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
@@ -0,0 +1,123 @@
+package qrseq
+
+import "sync"
+
+// SyncSequence wraps a receiving QRSequence so several goroutines can pass
+// frames to it at once, e.g. one per camera or the workers of a decoding
+// pool. The QRSequence itself is not safe for concurrent use.
+//
+// Every call holds a mutex, except for reading the QR code in DecodeImage,
+// which runs in parallel. The Decoder of the QRSequence must therefore be
+// safe for concurrent use, as the built-in one and DecodeCache are, and its
+// frame budget does not apply. Callbacks such as OnChunk run with the mutex
+// held and must not call the SyncSequence.
+//
+// Once the SyncSequence is shared, the QRSequence must only be accessed
+// through its methods and Do. WaitComplete of the QRSequence may be called
+// at any time, channels like Result and Guidance must be obtained before.
+type SyncSequence struct {
+	mu  sync.Mutex
+	seq *QRSequence
+}
+
+// NewSyncSequence wraps a receiving QRSequence for concurrent use.
+//
+// Parameters:
+// - seq: the receiving QRSequence.
+//
+// Returns:
+// - *SyncSequence: the new SyncSequence.
+func NewSyncSequence(seq *QRSequence) *SyncSequence {
+	return &SyncSequence{seq: seq}
+}
+
+// AddChunkFromBytes adds a chunk to the wrapped QRSequence like
+// QRSequence.AddChunkFromBytes.
+//
+// Parameters:
+// - data: the bytes of the chunk or fountain frame.
+func (q *SyncSequence) AddChunkFromBytes(data []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq.AddChunkFromBytes(data)
+}
+
+// AddChunk adds a chunk to the wrapped QRSequence like QRSequence.AddChunk.
+//
+// Parameters:
+// - data: the bytes of the chunk or fountain frame.
+//
+// Returns:
+// - ChunkResult: the outcome of adding the chunk.
+// - error: why the chunk was rejected, or the terminal error.
+func (q *SyncSequence) AddChunk(data []byte) (ChunkResult, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seq.AddChunk(data)
+}
+
+// DecodeText decodes the text of a QR code into the wrapped QRSequence like
+// QRSequence.DecodeText.
+//
+// Parameters:
+// - text: the text of the scanned QR code.
+//
+// Returns:
+//   - error: a *DecodeError if the text does not hold a valid chunk, or the
+//     terminal error of the QRSequence.
+func (q *SyncSequence) DecodeText(text string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seq.DecodeText(text)
+}
+
+// IsComplete reports whether the wrapped QRSequence is complete.
+//
+// Returns:
+// - bool: true if all chunks have been received.
+func (q *SyncSequence) IsComplete() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seq.IsComplete()
+}
+
+// Progress returns the progress of the wrapped QRSequence.
+//
+// Returns:
+// - float32: the fraction of chunks received, between 0 and 1.
+func (q *SyncSequence) Progress() float32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seq.Progress()
+}
+
+// Err returns the terminal error of the wrapped QRSequence.
+//
+// Returns:
+// - error: the error that ended the receive session, or nil.
+func (q *SyncSequence) Err() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seq.Err()
+}
+
+// Data returns the payload of the wrapped QRSequence.
+//
+// Returns:
+// - []byte: the payload, or nil if the QRSequence is not complete.
+func (q *SyncSequence) Data() []byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seq.Data()
+}
+
+// Do calls fn with the wrapped QRSequence while holding the mutex, for
+// methods the SyncSequence does not wrap, like Stats or Missing.
+//
+// Parameters:
+// - fn: the function accessing the QRSequence, which must not keep it.
+func (q *SyncSequence) Do(fn func(seq *QRSequence)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fn(q.seq)
+}
@@ -0,0 +1,73 @@
+package qrseq
+
+import "image"
+
+// Thresholds of the tear detection, for 8 bit luma.
+const (
+	// tearPeak is the share of the non-light pixels of a frame that, gathered
+	// in a single narrow band of intermediate gray, marks the frame as a blend
+	// of two QR codes. Blurred frames spread their intermediate pixels over
+	// all gray levels and stay well below it, as do blends dominated by one
+	// of the codes, which often still decode.
+	tearPeak = 0.35
+	// tearBand is the width of the gray band, as a fraction of the contrast.
+	tearBand = 0.1
+)
+
+// SetTearDetection enables dropping frames whose exposure straddled two
+// displayed QR codes.
+//
+// A camera frame exposed while the display switches codes shows the modules
+// on which both codes agree in black and white, and the others, about half
+// of them, in the same intermediate gray. Such frames seldom decode, yet
+// cost a full decode attempt. With tear detection the luma histogram of each
+// frame is checked first, and frames with a narrow gray peak fail right away
+// with a DecodeError of FailureTorn, which raises the effective frame rate at
+// high sender FPS. The check samples at most 512 rows and columns of the
+// frame.
+//
+// Parameters:
+// - enabled: whether to drop blended frames.
+func (s *QRSequence) SetTearDetection(enabled bool) {
+	s.tearDetection = enabled
+}
+
+// isTorn reports whether img blends two QR codes, judging by the share of its
+// non-light pixels in the fullest gray band between the darkest and
+// brightest parts of its luma histogram.
+func isTorn(img image.Image) bool {
+	b := img.Bounds()
+	if b.Empty() {
+		return false
+	}
+
+	var levels [256]int
+	xStep, yStep := max(1, b.Dx()/analysisLength), max(1, b.Dy()/analysisLength)
+	samples := 0
+	for y := b.Min.Y; y < b.Max.Y; y += yStep {
+		for x := b.Min.X; x < b.Max.X; x += xStep {
+			levels[luma(img, x, y)]++
+			samples++
+		}
+	}
+
+	low, high := percentile(levels, samples, 0.02), percentile(levels, samples, 0.98)
+	if high-low < minContrast {
+		return false
+	}
+	margin, band := (high-low)/8, max(int(tearBand*float64(high-low)), 2)
+	lo, hi := low+margin, high-margin
+
+	nonLight, inBand, peak := 0, 0, 0
+	for v := 0; v <= hi; v++ {
+		nonLight += levels[v]
+		if v >= lo {
+			inBand += levels[v]
+			if v >= lo+band {
+				inBand -= levels[v-band]
+			}
+			peak = max(peak, inBand)
+		}
+	}
+	return float64(peak) > tearPeak*float64(nonLight)
+}
@@ -0,0 +1,53 @@
+package qrseq
+
+// ChunkStore archives the frames a receiver accepts. A FeedWriter is a
+// ChunkStore writing a chunk feed, which ReadFeed replays.
+type ChunkStore interface {
+	// WriteFrame stores the bytes of an accepted frame as received.
+	WriteFrame(frame []byte) error
+}
+
+// WithTee makes a receiver write every chunk or fountain frame it accepts to
+// store while reassembly proceeds, so archiving a transfer does not take a
+// second decode pass. Frames are written as received, before they are added,
+// and neither duplicates nor frames of other sequences are written. To
+// archive to an io.Writer, pass a FeedWriter created by NewFeedWriter.
+//
+// If store fails, its error becomes the terminal error of the QRSequence, so
+// a payload is never accepted with an incomplete archive. The store is kept
+// by the sequences delivered by NewSequence and ignored by senders.
+//
+// Parameters:
+// - store: the ChunkStore to write the frames to.
+//
+// Returns:
+// - Option: the option.
+func WithTee(store ChunkStore) Option {
+	return func(o *options) error {
+		if store == nil {
+			return ErrNilChunkStore
+		}
+		o.tee = store
+		return nil
+	}
+}
+
+// teeFrame writes an accepted frame to the ChunkStore of the QRSequence.
+//
+// Parameters:
+// - frame: the bytes of the frame.
+//
+// Returns:
+// - error: the terminal error set if the ChunkStore fails.
+func (s *QRSequence) teeFrame(frame []byte) error {
+	if s.tee == nil {
+		return nil
+	}
+	if err := s.tee.WriteFrame(frame); err != nil {
+		s.err = err
+		s.deliverResult()
+		s.markDone()
+		return err
+	}
+	return nil
+}
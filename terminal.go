@@ -0,0 +1,149 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// TerminalMode selects how QR codes drawn with half block characters are
+// colored.
+type TerminalMode uint8
+
+const (
+	// TerminalANSI sets black on white with ANSI escape sequences, so the
+	// codes show the same in any color terminal. This is the default.
+	TerminalANSI TerminalMode = iota
+	// TerminalLight draws the dark modules without escape sequences, for
+	// terminals showing dark text on a light background.
+	TerminalLight
+	// TerminalDark draws the light modules without escape sequences, for
+	// terminals showing light text on a dark background.
+	TerminalDark
+)
+
+// terminalQuietZone is the width of the quiet zone around codes drawn in a
+// terminal in modules, less than the four of the standard to save space,
+// which scanners tolerate in front of the plain background of a terminal.
+const terminalQuietZone = 2
+
+// Terminal clears and redraws the frames of a sender in place, so headless
+// servers and SSH sessions can show a sequence without any graphical
+// environment.
+//
+// Every module takes one character cell in width and half of one in height,
+// so a code of version 10 needs 61 columns and 31 lines.
+type Terminal struct {
+	w    io.Writer
+	mode TerminalMode
+}
+
+// NewTerminal creates a Terminal drawing to w, usually os.Stdout.
+//
+// Parameters:
+// - w: the io.Writer connected to the terminal.
+// - mode: how the codes are colored.
+//
+// Returns:
+// - *Terminal: the new Terminal.
+func NewTerminal(w io.Writer, mode TerminalMode) *Terminal {
+	return &Terminal{w: w, mode: mode}
+}
+
+// Play draws the frames of a complete QRSequence in a loop.
+//
+// Parameters:
+// - seq: the sending QRSequence.
+// - opt: the RenderOptions selecting the error correction levels.
+// - fps: the number of frames drawn per second.
+// - loops: the number of times the sequence is drawn.
+//
+// Returns:
+//   - error: an error if the sequence is not complete, fps is not positive or
+//     a frame cannot be rendered or written.
+func (t *Terminal) Play(seq *QRSequence, opt RenderOptions, fps float64, loops int) error {
+	if fps <= 0 {
+		return ErrInvalidFrameRate
+	}
+	frames, err := seq.terminalFrames(opt, t.mode)
+	if err != nil {
+		return err
+	}
+
+	// the screen is only cleared when the frame size changes, frames of the
+	// same size cover each other completely
+	interval := time.Duration(float64(time.Second) / fps)
+	clock := seq.timeSource()
+	next := clock.Now()
+	size := -1
+	for loop := 0; loop < loops; loop++ {
+		for _, frame := range frames {
+			sleepUntil(clock, next)
+			home := "\x1b[H"
+			if len(frame) != size {
+				home, size = "\x1b[H\x1b[2J", len(frame)
+			}
+			if _, err := io.WriteString(t.w, home); err != nil {
+				return err
+			}
+			if _, err := t.w.Write(frame); err != nil {
+				return err
+			}
+			next = next.Add(interval)
+		}
+	}
+	return nil
+}
+
+// WriteTerminal writes the QR codes of all chunks of the QRSequence one after
+// another as lines of half block characters, e.g. to print a sequence into a
+// log or a terminal that cannot be redrawn. Only the error correction levels
+// of the options are used.
+//
+// Parameters:
+// - w: the io.Writer the lines are written to.
+// - opt: the RenderOptions selecting the error correction levels.
+// - mode: how the codes are colored.
+//
+// Returns:
+//   - error: an error if the QRSequence is not complete or a code cannot be
+//     rendered or written.
+func (s QRSequence) WriteTerminal(w io.Writer, opt RenderOptions, mode TerminalMode) error {
+	frames, err := s.terminalFrames(opt, mode)
+	if err != nil {
+		return err
+	}
+	for _, frame := range frames {
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// terminalFrames renders the QR codes of all chunks as half block characters.
+func (s QRSequence) terminalFrames(opt RenderOptions, mode TerminalMode) ([][]byte, error) {
+	if !s.IsComplete() {
+		return nil, ErrSequenceIncomplete
+	}
+
+	opt.Logo = nil
+	opt, err := s.renderOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	frames := make([][]byte, 0, len(s.chunks))
+	for _, chunk := range s.chunks {
+		o := opt.internal()
+		o.ECLevel = opt.ecLevel(chunk.Nr(), chunk.Tot())
+
+		buf := new(bytes.Buffer)
+		if err := chunk.RenderTerminal(buf, o, terminalQuietZone, mode == TerminalDark, mode == TerminalANSI); err != nil {
+			return nil, err
+		}
+		frames = append(frames, buf.Bytes())
+	}
+	return frames, nil
+}
@@ -0,0 +1,172 @@
+package qrseq
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+)
+
+// TrustBackend persists the keys of a TrustStore.
+type TrustBackend interface {
+	// Load returns the stored keys by name.
+	Load() (map[string][]byte, error)
+	// Save replaces the stored keys.
+	Save(keys map[string][]byte) error
+}
+
+// TrustedKey is a sender public key pinned in a TrustStore.
+type TrustedKey struct {
+	Name string            `json:"name"`
+	Key  ed25519.PublicKey `json:"key"`
+}
+
+// TrustStore holds the pinned Ed25519 public keys of trusted senders.
+//
+// Every change is written through to its TrustBackend, so applications share
+// one way of managing sender keys instead of each building their own.
+type TrustStore struct {
+	backend TrustBackend
+	keys    map[string]ed25519.PublicKey
+}
+
+// NewTrustStore creates a TrustStore and loads the keys from the backend.
+//
+// Parameters:
+// - backend: the TrustBackend persisting the keys.
+//
+// Returns:
+// - *TrustStore: the new TrustStore.
+// - error: an error if the keys cannot be loaded or one of them is invalid.
+func NewTrustStore(backend TrustBackend) (*TrustStore, error) {
+	stored, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(stored))
+	for name, key := range stored {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, ErrInvalidPublicKey
+		}
+		keys[name] = ed25519.PublicKey(key)
+	}
+	return &TrustStore{backend: backend, keys: keys}, nil
+}
+
+// Add pins the public key of a sender under the given name, replacing any key
+// stored under that name.
+//
+// Parameters:
+// - name: the name identifying the sender.
+// - key: the Ed25519 public key of the sender.
+//
+// Returns:
+// - error: an error if the key is invalid or cannot be saved.
+func (t *TrustStore) Add(name string, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return ErrInvalidPublicKey
+	}
+	t.keys[name] = append(ed25519.PublicKey(nil), key...)
+	return t.save()
+}
+
+// Remove unpins the key stored under the given name. Removing an unknown name
+// is not an error.
+//
+// Parameters:
+// - name: the name identifying the sender.
+//
+// Returns:
+// - error: an error if the keys cannot be saved.
+func (t *TrustStore) Remove(name string) error {
+	if _, ok := t.keys[name]; !ok {
+		return nil
+	}
+	delete(t.keys, name)
+	return t.save()
+}
+
+// List returns all pinned keys sorted by name.
+func (t *TrustStore) List() []TrustedKey {
+	list := make([]TrustedKey, 0, len(t.keys))
+	for name, key := range t.keys {
+		list = append(list, TrustedKey{Name: name, Key: key})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Verify checks a signature over message against all pinned keys.
+//
+// Parameters:
+// - message: the signed message.
+// - sig: the Ed25519 signature.
+//
+// Returns:
+// - string: the name of the sender whose key made the signature.
+// - error: an error if no pinned key verifies the signature.
+func (t *TrustStore) Verify(message, sig []byte) (string, error) {
+	for _, trusted := range t.List() {
+		if ed25519.Verify(trusted.Key, message, sig) {
+			return trusted.Name, nil
+		}
+	}
+	return "", ErrUntrustedKey
+}
+
+func (t *TrustStore) save() error {
+	keys := make(map[string][]byte, len(t.keys))
+	for name, key := range t.keys {
+		keys[name] = key
+	}
+	return t.backend.Save(keys)
+}
+
+// MemoryTrustBackend is a TrustBackend that keeps the keys in memory only.
+type MemoryTrustBackend struct {
+	keys map[string][]byte
+}
+
+func (m *MemoryTrustBackend) Load() (map[string][]byte, error) {
+	return m.keys, nil
+}
+
+func (m *MemoryTrustBackend) Save(keys map[string][]byte) error {
+	m.keys = keys
+	return nil
+}
+
+// FileTrustBackend is a TrustBackend that stores the keys as a JSON file at
+// the given path. A missing file holds no keys.
+type FileTrustBackend string
+
+func (f FileTrustBackend) Load() (map[string][]byte, error) {
+	data, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string][]byte
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (f FileTrustBackend) Save(keys map[string][]byte) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := string(f) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(f))
+}
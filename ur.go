@@ -0,0 +1,89 @@
+package qrseq
+
+import (
+	"strings"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// DefaultURFragmentLen is the maximum fragment length used by common wallet
+// apps for animated UR QR codes.
+const DefaultURFragmentLen = 200
+
+// UREncoder sends a payload as a BC-UR (Blockchain Commons Uniform Resource)
+// of type bytes, so it can be read by hardware wallets and wallet apps that
+// speak the UR standard instead of the qrseq framing.
+//
+// Payloads that fit into one fragment are sent as a single-part UR, larger
+// ones as an endless stream of fountain coded parts (ur:bytes/1-9/...).
+type UREncoder struct {
+	enc *internal.UREncoder
+}
+
+// NewUREncoder creates a UREncoder for the given payload.
+//
+// Parameters:
+//   - data: the payload to send.
+//   - maxFragmentLen: the maximum number of message bytes per part, e.g.
+//     DefaultURFragmentLen.
+//
+// Returns:
+// - *UREncoder: the new UREncoder.
+// - error: an error if the fragment length is below 10 bytes.
+func NewUREncoder(data []byte, maxFragmentLen int) (*UREncoder, error) {
+	enc, err := internal.NewUREncoder(data, maxFragmentLen)
+	if err != nil {
+		return nil, err
+	}
+	return &UREncoder{enc: enc}, nil
+}
+
+// SeqLen returns the number of fragments of the UR, which is the minimum
+// number of parts a receiver needs.
+func (e *UREncoder) SeqLen() int {
+	return e.enc.SeqLen()
+}
+
+// NextPart returns the text of the next part in upper case, which QR codes
+// encode compactly in alphanumeric mode.
+func (e *UREncoder) NextPart() string {
+	return strings.ToUpper(e.enc.NextPart())
+}
+
+// URDecoder receives a payload sent as a BC-UR of type bytes, by a UREncoder
+// or by any other UR implementation.
+type URDecoder struct {
+	dec *internal.URDecoder
+}
+
+// NewURDecoder creates an empty URDecoder.
+func NewURDecoder() *URDecoder {
+	return &URDecoder{dec: internal.NewURDecoder()}
+}
+
+// AddPart adds the text of a scanned UR part.
+//
+// Parameters:
+// - text: the text of the part, in upper or lower case.
+//
+// Returns:
+//   - error: an error wrapping ErrInvalidUR if the text is not a valid part of
+//     a UR of type bytes or belongs to another UR than the parts added before.
+func (d *URDecoder) AddPart(text string) error {
+	return d.dec.AddPart(text)
+}
+
+// IsComplete reports whether the payload has been reassembled.
+func (d *URDecoder) IsComplete() bool {
+	return d.dec.Data() != nil
+}
+
+// Progress returns the fraction of fragments received, between 0 and 1.
+func (d *URDecoder) Progress() float32 {
+	return d.dec.Progress()
+}
+
+// Data returns the payload if the UR is complete, otherwise nil.
+func (d *URDecoder) Data() []byte {
+	return d.dec.Data()
+}
@@ -0,0 +1,342 @@
+//go:build linux && !core && !(mips || mipsle || mips64 || mips64le || ppc64 || ppc64le)
+
+package qrseq
+
+import (
+	"context"
+	"image"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// PixelFormat is the format of the frames a Camera captures.
+type PixelFormat uint32
+
+// The pixel formats a Camera can capture, as V4L2 fourcc codes.
+const (
+	// PixelFormatYUYV captures uncompressed YUV 4:2:2 frames, of which only
+	// the luma is used. It needs the most bandwidth but no decoding.
+	PixelFormatYUYV PixelFormat = 'Y' | 'U'<<8 | 'Y'<<16 | 'V'<<24
+	// PixelFormatMJPEG captures JPEG compressed frames, which most USB
+	// cameras offer at higher resolutions and frame rates than YUYV.
+	PixelFormatMJPEG PixelFormat = 'M' | 'J'<<8 | 'P'<<16 | 'G'<<24
+)
+
+// ioctl requests and constants of the V4L2 API, see linux/videodev2.h.
+const (
+	v4l2BufTypeVideoCapture = 1
+	v4l2MemoryMMAP          = 1
+	v4l2CapVideoCapture     = 0x00000001
+	v4l2CapStreaming        = 0x04000000
+	v4l2CapDeviceCaps       = 0x80000000
+
+	// cameraBuffers is the number of buffers requested from the driver.
+	cameraBuffers = 4
+)
+
+var (
+	vidiocQueryCap  = v4l2Ioc(2, 0, unsafe.Sizeof(v4l2Capability{}))
+	vidiocSetFormat = v4l2Ioc(3, 5, unsafe.Sizeof(v4l2Format{}))
+	vidiocReqBufs   = v4l2Ioc(3, 8, unsafe.Sizeof(v4l2RequestBuffers{}))
+	vidiocQueryBuf  = v4l2Ioc(3, 9, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocQBuf      = v4l2Ioc(3, 15, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocDQBuf     = v4l2Ioc(3, 17, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocStreamOn  = v4l2Ioc(1, 18, unsafe.Sizeof(int32(0)))
+	vidiocStreamOff = v4l2Ioc(1, 19, unsafe.Sizeof(int32(0)))
+)
+
+// v4l2Ioc encodes an ioctl request of the V4L2 API with the direction bits
+// (1 write, 2 read) of the generic encoding. MIPS and POWER encode requests
+// differently and are left out by the build constraint.
+func v4l2Ioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'V'<<8 | nr
+}
+
+// v4l2Capability is struct v4l2_capability.
+type v4l2Capability struct {
+	driver       [16]byte
+	card         [32]byte
+	busInfo      [32]byte
+	version      uint32
+	capabilities uint32
+	deviceCaps   uint32
+	reserved     [3]uint32
+}
+
+// v4l2Format is struct v4l2_format. The union holds pointers in some of its
+// variants, which aligns it to the size of a pointer.
+type v4l2Format struct {
+	typ uint32
+	fmt [200 / unsafe.Sizeof(uintptr(0))]uintptr
+}
+
+// v4l2PixFormat is the leading part of struct v4l2_pix_format, the variant of
+// the v4l2Format union used for video capture.
+type v4l2PixFormat struct {
+	width        uint32
+	height       uint32
+	pixelFormat  uint32
+	field        uint32
+	bytesPerLine uint32
+	sizeImage    uint32
+}
+
+// v4l2RequestBuffers is struct v4l2_requestbuffers.
+type v4l2RequestBuffers struct {
+	count    uint32
+	typ      uint32
+	memory   uint32
+	reserved [2]uint32
+}
+
+// v4l2Buffer is struct v4l2_buffer.
+type v4l2Buffer struct {
+	index     uint32
+	typ       uint32
+	bytesUsed uint32
+	flags     uint32
+	field     uint32
+	timestamp syscall.Timeval
+	timecode  [16]byte
+	sequence  uint32
+	memory    uint32
+	offset    uintptr // union of the offset, user pointer, planes and fd
+	length    uint32
+	reserved2 uint32
+	requestFD uint32
+}
+
+// Camera captures frames from a V4L2 video device such as /dev/video0
+// directly through the kernel API, without OpenCV or cgo, keeping the
+// dependency footprint of embedded receivers small.
+//
+// Frames are streamed through buffers mapped from the driver. YUYV frames are
+// read as their luma plane, MJPEG frames are decoded with image/jpeg, adding
+// the standard Huffman tables many cameras leave out.
+type Camera struct {
+	f       *os.File
+	width   int
+	height  int
+	stride  int
+	format  PixelFormat
+	buffers [][]byte
+}
+
+// OpenCamera opens a video device and starts streaming frames.
+//
+// The driver may pick a different frame size than requested, see Bounds.
+//
+// Parameters:
+// - path: the path of the device, e.g. /dev/video0.
+// - width: the requested width of the frames in pixels.
+// - height: the requested height of the frames in pixels.
+// - format: the pixel format to capture.
+//
+// Returns:
+//   - *Camera: the streaming camera.
+//   - error: an error if the device is no streaming capture device, does not
+//     support the pixel format or cannot be set up.
+func OpenCamera(path string, width, height int, format PixelFormat) (*Camera, error) {
+	if format != PixelFormatYUYV && format != PixelFormatMJPEG {
+		return nil, ErrUnsupportedCameraFormat
+	}
+	if width < 1 || height < 1 {
+		return nil, ErrInvalidFrameSize
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	c := &Camera{f: f, format: format}
+	if err := c.setup(width, height); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// setup checks the capabilities of the device, sets the format, maps the
+// buffers and starts streaming.
+func (c *Camera) setup(width, height int) error {
+	var capability v4l2Capability
+	if err := c.ioctl(vidiocQueryCap, unsafe.Pointer(&capability)); err != nil {
+		return err
+	}
+	caps := capability.capabilities
+	if caps&v4l2CapDeviceCaps != 0 {
+		caps = capability.deviceCaps
+	}
+	if caps&v4l2CapVideoCapture == 0 || caps&v4l2CapStreaming == 0 {
+		return ErrNotCaptureDevice
+	}
+
+	format := v4l2Format{typ: v4l2BufTypeVideoCapture}
+	pix := (*v4l2PixFormat)(unsafe.Pointer(&format.fmt))
+	pix.width = uint32(width)
+	pix.height = uint32(height)
+	pix.pixelFormat = uint32(c.format)
+	if err := c.ioctl(vidiocSetFormat, unsafe.Pointer(&format)); err != nil {
+		return err
+	}
+	if PixelFormat(pix.pixelFormat) != c.format {
+		return ErrUnsupportedCameraFormat
+	}
+	c.width, c.height, c.stride = int(pix.width), int(pix.height), int(pix.bytesPerLine)
+	if c.format == PixelFormatYUYV && c.stride < 2*c.width {
+		c.stride = 2 * c.width
+	}
+
+	request := v4l2RequestBuffers{count: cameraBuffers, typ: v4l2BufTypeVideoCapture, memory: v4l2MemoryMMAP}
+	if err := c.ioctl(vidiocReqBufs, unsafe.Pointer(&request)); err != nil {
+		return err
+	}
+	if request.count == 0 {
+		return ErrNoCameraBuffers
+	}
+	for i := uint32(0); i < request.count; i++ {
+		buf := v4l2Buffer{index: i, typ: v4l2BufTypeVideoCapture, memory: v4l2MemoryMMAP}
+		if err := c.ioctl(vidiocQueryBuf, unsafe.Pointer(&buf)); err != nil {
+			return err
+		}
+		mem, err := syscall.Mmap(int(c.f.Fd()), int64(*(*uint32)(unsafe.Pointer(&buf.offset))), int(buf.length), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+		if err != nil {
+			return err
+		}
+		c.buffers = append(c.buffers, mem)
+		if err := c.ioctl(vidiocQBuf, unsafe.Pointer(&buf)); err != nil {
+			return err
+		}
+	}
+
+	typ := int32(v4l2BufTypeVideoCapture)
+	return c.ioctl(vidiocStreamOn, unsafe.Pointer(&typ))
+}
+
+func (c *Camera) ioctl(req uintptr, arg unsafe.Pointer) error {
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, c.f.Fd(), req, uintptr(arg))
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			continue
+		default:
+			return errno
+		}
+	}
+}
+
+// Bounds returns the size of the frames the driver captures.
+func (c *Camera) Bounds() image.Rectangle {
+	return image.Rect(0, 0, c.width, c.height)
+}
+
+// ReadFrame waits for the next frame of the camera.
+//
+// Returns:
+//   - image.Image: the frame, an *image.Gray for YUYV frames and the decoded
+//     JPEG image for MJPEG frames.
+//   - error: an error if the frame cannot be captured, or a *FrameError if
+//     it cannot be decoded.
+func (c *Camera) ReadFrame() (image.Image, error) {
+	img, corrupt, err := c.readFrame()
+	if corrupt {
+		return nil, &FrameError{Err: err}
+	}
+	return img, err
+}
+
+// readFrame captures the next frame. It reports whether an error comes from
+// decoding a corrupted frame rather than from the device.
+func (c *Camera) readFrame() (image.Image, bool, error) {
+	buf := v4l2Buffer{typ: v4l2BufTypeVideoCapture, memory: v4l2MemoryMMAP}
+	if err := c.ioctl(vidiocDQBuf, unsafe.Pointer(&buf)); err != nil {
+		return nil, false, err
+	}
+	if int(buf.index) >= len(c.buffers) {
+		return nil, false, ErrInvalidCameraBuffer
+	}
+	data := c.buffers[buf.index][:min(int(buf.bytesUsed), len(c.buffers[buf.index]))]
+
+	// the frame is copied or decoded before the buffer is handed back
+	var img image.Image
+	var decodeErr error
+	if c.format == PixelFormatYUYV {
+		img, decodeErr = c.luma(data)
+	} else {
+		img, decodeErr = decodeMJPEG(data)
+	}
+	if err := c.ioctl(vidiocQBuf, unsafe.Pointer(&buf)); err != nil {
+		return nil, false, err
+	}
+	if decodeErr != nil {
+		return nil, true, decodeErr
+	}
+	return img, false, nil
+}
+
+// streamOff stops streaming, which also makes a pending VIDIOC_DQBUF return.
+func (c *Camera) streamOff() error {
+	typ := int32(v4l2BufTypeVideoCapture)
+	return c.ioctl(vidiocStreamOff, unsafe.Pointer(&typ))
+}
+
+// luma extracts the luma plane of a YUYV frame, in which every second byte is
+// the luma of a pixel.
+func (c *Camera) luma(data []byte) (*image.Gray, error) {
+	if len(data) < (c.height-1)*c.stride+2*c.width {
+		return nil, ErrShortCameraFrame
+	}
+	img := image.NewGray(c.Bounds())
+	for y := 0; y < c.height; y++ {
+		row := data[y*c.stride:]
+		pix := img.Pix[y*img.Stride : y*img.Stride+c.width]
+		for x := range pix {
+			pix[x] = row[2*x]
+		}
+	}
+	return img, nil
+}
+
+// Receive is ReceiveContext with a context that is never done.
+//
+// Parameters:
+// - seq: the receiving QRSequence.
+//
+// Returns:
+// - error: the error of ReceiveContext.
+func (c *Camera) Receive(seq *QRSequence) error {
+	return c.ReceiveContext(context.Background(), seq)
+}
+
+// ReceiveContext decodes frames of the camera into the receiving QRSequence
+// until it is complete. Corrupted frames and frames without a readable QR code
+// are skipped.
+//
+// Cancelling ctx also interrupts the frame being waited for by stopping the
+// capture, after which the camera has to be closed.
+//
+// Parameters:
+// - ctx: the context to stop receiving with.
+// - seq: the receiving QRSequence.
+//
+// Returns:
+//   - error: the error of ctx if it is done first, an error if a frame cannot
+//     be captured or the QRSequence ended with a terminal error.
+func (c *Camera) ReceiveContext(ctx context.Context, seq *QRSequence) error {
+	return ReceiveFrames(ctx, c, seq, func() { c.streamOff() })
+}
+
+// Close stops streaming, unmaps the buffers and closes the device.
+func (c *Camera) Close() error {
+	if len(c.buffers) > 0 {
+		c.streamOff()
+	}
+	for _, mem := range c.buffers {
+		syscall.Munmap(mem)
+	}
+	c.buffers = nil
+	return c.f.Close()
+}
@@ -0,0 +1,63 @@
+package qrseq
+
+import "bytes"
+
+// Verify checks the reassembled payload against the SHA-256 digest the sender
+// embedded in chunk 0, proving the bytes match what was encoded before they
+// are acted on. For compressed or encrypted payloads the digest covers the
+// bytes as sent.
+//
+// The digest is sent by all senders producing v2 chunks, except for
+// sequences of ChunkSize32, whose chunks are too small to carry it, and for
+// fountain coded sequences. Receivers already check it when the sequence
+// completes, which ends with ErrDigestMismatch on a mismatch, and Finalize
+// checks it again. Verify additionally fails if no digest was sent.
+//
+// Returns:
+//   - error: an error if the sequence is not complete, no digest was received
+//     or the payload does not match it.
+func (s QRSequence) Verify() error {
+	if !s.IsComplete() {
+		return ErrSequenceIncomplete
+	}
+	if len(s.chunks) == 0 || s.chunks[0].Digest() == nil {
+		return ErrNoDigest
+	}
+	return s.checkSentDigest()
+}
+
+// checkSentDigest verifies the reassembled payload of a sequence whose chunks
+// have all arrived against the digest the sender put into chunk 0, if it did.
+func (s QRSequence) checkSentDigest() error {
+	if len(s.chunks) == 0 || s.chunks[0].Digest() == nil {
+		return nil
+	}
+
+	digest := s.sentDigest()
+	if !bytes.Equal(digest[:], s.chunks[0].Digest()) {
+		return ErrDigestMismatch
+	}
+	return nil
+}
+
+// checkSentLength verifies the length of the reassembled payload of a sequence
+// whose chunks have all arrived against the length the sender put into chunk
+// 0, if it did.
+func (s *QRSequence) checkSentLength() error {
+	if len(s.chunks) == 0 {
+		return nil
+	}
+	want, ok := s.chunks[0].Length()
+	if !ok {
+		return nil
+	}
+
+	length := s.drainedBytes
+	for _, chunk := range s.chunks[s.drained:] {
+		length += len(chunk.Data())
+	}
+	if length != want {
+		return ErrLengthMismatch
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package qrseq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+func TestDigestMismatchEndsSequence(t *testing.T) {
+	data := make([]byte, 500)
+	tampered := make([]byte, len(data))
+	copy(tampered, data)
+	tampered[len(tampered)-1] = 1
+
+	// every chunk is valid on its own, only the digest in chunk 0 tells
+	// that the payload was tampered with
+	original := internal.CreateChunks(data, uint16(ChunkSize128), 7, 0, 0)
+	chunks := internal.CreateChunks(tampered, uint16(ChunkSize128), 7, 0, 0)
+	chunks[0] = original[0]
+
+	seq := NewEmpty()
+	for _, chunk := range chunks {
+		_, _ = seq.AddChunk(chunk.Bytes())
+	}
+	if !errors.Is(seq.Err(), ErrDigestMismatch) {
+		t.Fatalf("got error %v, want ErrDigestMismatch", seq.Err())
+	}
+	if seq.Data() != nil {
+		t.Error("tampered payload returned")
+	}
+	if _, _, err := seq.Finalize(); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Finalize: got error %v, want ErrDigestMismatch", err)
+	}
+}
@@ -0,0 +1,261 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ffmpegCommand is the program a Video runs to decode the frames.
+const ffmpegCommand = "ffmpeg"
+
+// maxVideoFrame bounds the number of pixels of a video frame.
+const maxVideoFrame = 64 << 20
+
+// Video reads the frames of a recorded video, e.g. of a screen recording of
+// a sender, so a sequence can be received offline.
+//
+// It runs ffmpeg, which must be installed, to decode the frames of any
+// container and codec ffmpeg supports into gray PGM images on a pipe.
+type Video struct {
+	cmd    *exec.Cmd
+	out    io.ReadCloser
+	r      *bufio.Reader
+	stderr bytes.Buffer
+	done   bool
+	err    error
+}
+
+// OpenVideo starts decoding the frames of a video file.
+//
+// Parameters:
+// - path: the path of the video file.
+//
+// Returns:
+// - *Video: the video.
+// - error: an error if ffmpeg cannot start.
+func OpenVideo(path string) (*Video, error) {
+	return startVideo(nil, "-nostdin", "-i", path)
+}
+
+// OpenVideoReader starts decoding the frames of a video read from r. Formats
+// that need seeking, like MP4 files with the index at the end, cannot be read
+// this way and must be opened with OpenVideo.
+//
+// Parameters:
+// - r: the io.Reader the video is read from.
+//
+// Returns:
+// - *Video: the video.
+// - error: an error if ffmpeg cannot start.
+func OpenVideoReader(r io.Reader) (*Video, error) {
+	return startVideo(r, "-i", "pipe:0")
+}
+
+func startVideo(stdin io.Reader, input ...string) (*Video, error) {
+	args := append([]string{"-hide_banner", "-loglevel", "error"}, input...)
+	args = append(args, "-map", "0:v:0", "-f", "image2pipe", "-c:v", "pgm", "-pix_fmt", "gray", "pipe:1")
+
+	v := new(Video)
+	v.cmd = exec.Command(ffmpegCommand, args...)
+	v.cmd.Stdin = stdin
+	v.cmd.Stderr = &v.stderr
+	out, err := v.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.cmd.Start(); err != nil {
+		return nil, err
+	}
+	v.out = out
+	v.r = bufio.NewReaderSize(out, 1<<16)
+	return v, nil
+}
+
+// ReadFrame reads the next frame of the video.
+//
+// Returns:
+//   - image.Image: the frame as an *image.Gray.
+//   - error: io.EOF at the end of the video, or an error if ffmpeg failed.
+func (v *Video) ReadFrame() (image.Image, error) {
+	if v.done {
+		return nil, v.err
+	}
+	img, err := readPGM(v.r)
+	if err == io.EOF {
+		v.done, v.err = true, io.EOF
+		if err := v.wait(); err != nil {
+			v.err = err
+		}
+		return nil, v.err
+	}
+	return img, err
+}
+
+// Receive is ReceiveContext with a context that is never done.
+//
+// Parameters:
+// - seq: the receiving QRSequence.
+//
+// Returns:
+// - error: the error of ReceiveContext.
+func (v *Video) Receive(seq *QRSequence) error {
+	return v.ReceiveContext(context.Background(), seq)
+}
+
+// ReceiveContext decodes frames of the video into the receiving QRSequence
+// until it is complete. Frames without a readable QR code are skipped.
+//
+// Cancelling ctx also interrupts the frame being waited for by stopping
+// ffmpeg, after which the video cannot be read any further.
+//
+// Parameters:
+// - ctx: the context to stop receiving with.
+// - seq: the receiving QRSequence.
+//
+// Returns:
+//   - error: the error of ctx if it is done first, ErrSourceEnded if the video
+//     ends before the QRSequence is complete, an error if ffmpeg failed or
+//     the terminal error of the QRSequence.
+func (v *Video) ReceiveContext(ctx context.Context, seq *QRSequence) error {
+	return ReceiveFrames(ctx, v, seq, func() { v.cmd.Process.Kill() })
+}
+
+// Close stops ffmpeg.
+func (v *Video) Close() error {
+	if !v.done {
+		v.done, v.err = true, ErrVideoClosed
+		v.cmd.Process.Kill()
+		v.out.Close()
+		v.cmd.Wait()
+	}
+	return nil
+}
+
+// wait waits for ffmpeg to exit and returns its error message if it failed.
+func (v *Video) wait() error {
+	if err := v.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(v.stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", ErrFFmpeg, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// DecodeVideo is DecodeVideoContext with a context that is never done.
+//
+// Parameters:
+// - path: the path of the video file.
+// - opts: the options of the receiving QRSequence, see NewEmpty.
+//
+// Returns:
+// - *QRSequence: the receiving QRSequence of DecodeVideoContext.
+// - error: the error of DecodeVideoContext.
+func DecodeVideo(path string, opts ...Option) (*QRSequence, error) {
+	return DecodeVideoContext(context.Background(), path, opts...)
+}
+
+// DecodeVideoContext receives a sequence from the frames of a video file,
+// which is decoded with ffmpeg, see Video. ffmpeg is stopped when ctx is done.
+//
+// Parameters:
+// - ctx: the context to stop decoding with.
+// - path: the path of the video file.
+// - opts: the options of the receiving QRSequence, see NewEmpty.
+//
+// Returns:
+//   - *QRSequence: the receiving QRSequence, also if it could not be
+//     completed, so its progress can be inspected.
+//   - error: the error of ctx if it is done first, an error if ffmpeg cannot
+//     start or failed, the video ends before the QRSequence is complete or
+//     the QRSequence ended with a terminal error.
+func DecodeVideoContext(ctx context.Context, path string, opts ...Option) (*QRSequence, error) {
+	v, err := OpenVideo(path)
+	if err != nil {
+		return nil, err
+	}
+	defer v.Close()
+
+	seq := NewEmpty(opts...)
+	return seq, v.ReceiveContext(ctx, seq)
+}
+
+// readPGM reads a binary PGM image with 8 bit samples. It returns io.EOF if
+// r ends before the image.
+func readPGM(r *bufio.Reader) (*image.Gray, error) {
+	magic, err := pgmToken(r)
+	if err != nil {
+		return nil, err
+	}
+	if magic != "P5" {
+		return nil, ErrInvalidPGM
+	}
+	var header [3]int
+	for i := range header {
+		token, err := pgmToken(r)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if header[i], err = strconv.Atoi(token); err != nil {
+			return nil, ErrInvalidPGM
+		}
+	}
+
+	width, height, maxVal := header[0], header[1], header[2]
+	if width < 1 || height < 1 || width > maxVideoFrame/height {
+		return nil, ErrInvalidPGMSize
+	}
+	if maxVal != 255 {
+		return nil, ErrPGMDepth
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	if _, err := io.ReadFull(r, img.Pix); err != nil {
+		return nil, noEOF(err)
+	}
+	return img, nil
+}
+
+// pgmToken reads a token of a PGM header, skipping whitespace and comments
+// before it and the single whitespace character after it.
+func pgmToken(r *bufio.Reader) (string, error) {
+	var token []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(token) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		switch {
+		case b == '#' && len(token) == 0:
+			if _, err := r.ReadString('\n'); err != nil {
+				return "", err
+			}
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			if len(token) > 0 {
+				return string(token), nil
+			}
+		default:
+			token = append(token, b)
+		}
+	}
+}
+
+// noEOF turns io.EOF into io.ErrUnexpectedEOF, for data that ends inside a
+// frame.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// DefaultMTimeResolution is the modification time granularity a Viewer
+// assumes if ViewerConfig.MTimeResolution is not set.
+const DefaultMTimeResolution = 10 * time.Millisecond
+
+// ViewerConfig describes the external image viewer a Viewer drives.
+type ViewerConfig struct {
+	// Path is the image file the viewer shows. Every frame is written to a
+	// temporary file next to it and renamed into place, so the viewer never
+	// reads a partly written frame.
+	Path string
+	// Command starts the viewer, with Path appended as the last argument,
+	// e.g. []string{"feh", "--reload", "0.05"}. If it is empty, the viewer
+	// is expected to be running already.
+	Command []string
+	// Reload is run after every frame for viewers that do not watch their
+	// file, e.g. []string{"xdotool", "search", "--name", "feh", "key", "r"}.
+	// It is optional.
+	Reload []string
+	// MTimeResolution is the granularity of the file modification times the
+	// viewer detects changes by. Consecutive frames get modification times
+	// at least this far apart, and Play does not show frames faster. Zero
+	// means DefaultMTimeResolution.
+	MTimeResolution time.Duration
+}
+
+// Viewer shows the frames of a sender in an external image viewer, for
+// air-gapped hosts that can only display images with an existing viewer.
+type Viewer struct {
+	config ViewerConfig
+	cmd    *exec.Cmd
+	mtime  time.Time
+}
+
+// OpenViewer shows the first frame and starts the viewer on it.
+//
+// Parameters:
+// - config: the viewer to drive.
+// - first: the frame shown when the viewer opens.
+//
+// Returns:
+//   - *Viewer: the new Viewer.
+//   - error: an error if the frame cannot be written or the viewer cannot be
+//     started.
+func OpenViewer(config ViewerConfig, first image.Image) (*Viewer, error) {
+	if config.Path == "" {
+		return nil, ErrNoViewer
+	}
+	if config.MTimeResolution <= 0 {
+		config.MTimeResolution = DefaultMTimeResolution
+	}
+
+	v := &Viewer{config: config}
+	if err := v.write(first); err != nil {
+		return nil, err
+	}
+	if len(config.Command) > 0 {
+		args := append(append([]string{}, config.Command[1:]...), config.Path)
+		v.cmd = exec.Command(config.Command[0], args...)
+		if err := v.cmd.Start(); err != nil {
+			os.Remove(config.Path)
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Show replaces the frame shown by the viewer.
+//
+// Parameters:
+// - img: the frame to show.
+//
+// Returns:
+//   - error: an error if the frame cannot be written or the reload command
+//     fails.
+func (v *Viewer) Show(img image.Image) error {
+	if err := v.write(img); err != nil {
+		return err
+	}
+	if len(v.config.Reload) > 0 {
+		return exec.Command(v.config.Reload[0], v.config.Reload[1:]...).Run()
+	}
+	return nil
+}
+
+// Play shows the frames of a complete QRSequence in a loop.
+//
+// The interval between frames is the larger of 1/fps and the modification
+// time resolution of the viewer, since faster frames would not be noticed.
+//
+// Parameters:
+// - seq: the sending QRSequence.
+// - opt: the RenderOptions to render the frames with.
+// - fps: the number of frames shown per second.
+// - loops: the number of times the sequence is shown.
+//
+// Returns:
+//   - error: an error if the sequence is not complete, fps is not positive or
+//     a frame cannot be rendered or shown.
+func (v *Viewer) Play(seq *QRSequence, opt RenderOptions, fps float64, loops int) error {
+	if fps <= 0 {
+		return ErrInvalidFrameRate
+	}
+	images, err := seq.playFrames(opt)
+	if err != nil {
+		return err
+	}
+
+	interval := max(time.Duration(float64(time.Second)/fps), v.config.MTimeResolution)
+	clock := seq.timeSource()
+	next := clock.Now()
+	for loop := 0; loop < loops; loop++ {
+		for _, img := range images {
+			sleepUntil(clock, next)
+			if err := v.Show(img); err != nil {
+				return err
+			}
+			next = next.Add(interval)
+		}
+	}
+	return nil
+}
+
+// Close stops the viewer it started and removes the image file.
+func (v *Viewer) Close() error {
+	var err error
+	if v.cmd != nil {
+		v.cmd.Process.Kill()
+		v.cmd.Wait()
+	}
+	if rmErr := os.Remove(v.config.Path); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+		err = rmErr
+	}
+	return err
+}
+
+// write writes img to the file of the viewer with a modification time at
+// least the resolution later than that of the previous frame.
+func (v *Viewer) write(img image.Image) error {
+	mtime := time.Now()
+	if earliest := v.mtime.Add(v.config.MTimeResolution); mtime.Before(earliest) {
+		mtime = earliest
+	}
+	if err := writeImageFile(v.config.Path, img, mtime); err != nil {
+		return err
+	}
+	v.mtime = mtime
+	return nil
+}
+
+// writeImageFile writes img PNG encoded to a temporary file in the directory
+// of path, sets its modification time and renames it to path, so readers see
+// either the previous or the new image, never a partly written one.
+func writeImageFile(path string, img image.Image, mtime time.Time) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	err = png.Encode(f, img)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, mtime, mtime)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+//go:build !core
+
+package qrseq
+
+import "math"
+
+// Default camera assumptions of ViewingConditions, matching a typical phone
+// camera recording video.
+const (
+	DefaultCameraResolution = 1920
+	DefaultCameraFOV        = 70
+)
+
+// ViewingConditions describes how the receiving camera sees the screen of the
+// sender.
+type ViewingConditions struct {
+	// ScreenPPI is the pixel density of the sending display in pixels per
+	// inch.
+	ScreenPPI float64
+	// Distance is the distance between the camera and the display in
+	// millimetres.
+	Distance float64
+	// CameraResolution is the horizontal resolution of the camera frames in
+	// pixels. Zero means DefaultCameraResolution.
+	CameraResolution int
+	// CameraFOV is the horizontal field of view of the camera in degrees.
+	// Zero means DefaultCameraFOV.
+	CameraFOV float64
+}
+
+// BlockSize computes the block size that makes a QR code module cover enough
+// camera pixels to scan reliably under the given viewing conditions.
+//
+// The camera resolution and field of view give the width one camera pixel
+// covers at the viewing distance. A module has to span as many camera pixels
+// as the moderate step used by ScoreFrame, and the block size is the number of
+// display pixels needed for that.
+//
+// Returns:
+//   - int: the block size in display pixels, at least 1, or 0 if the screen
+//     PPI or the distance is not set.
+func (vc ViewingConditions) BlockSize() int {
+	if vc.ScreenPPI <= 0 || vc.Distance <= 0 {
+		return 0
+	}
+
+	resolution := vc.CameraResolution
+	if resolution <= 0 {
+		resolution = DefaultCameraResolution
+	}
+	fov := vc.CameraFOV
+	if fov <= 0 {
+		fov = DefaultCameraFOV
+	}
+
+	// width of the scene covered by one camera pixel at the display, in mm
+	cameraPixel := 2 * vc.Distance * math.Tan(fov/2*math.Pi/180) / float64(resolution)
+	module := degradations[moderateDegradation].moduleSize * cameraPixel
+	displayPixel := 25.4 / vc.ScreenPPI
+
+	return max(1, int(math.Ceil(module/displayPixel)))
+}
@@ -0,0 +1,87 @@
+//go:build js && wasm
+
+package wasm
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// Register installs the global JavaScript object qrseq with the functions
+// below, which bind to a Receivers. A function that fails returns a
+// JavaScript Error in place of its result, since Go cannot throw.
+//
+//	qrseq.open(key?: Uint8Array): number
+//	qrseq.addPayload(id: number, text: string): {progress, complete, missing, failure?, error?}
+//	qrseq.data(id: number): Uint8Array
+//	qrseq.close(id: number)
+//
+// The functions are available once the Go program has started, and until it
+// exits, so the program must keep running after calling Register.
+func Register() {
+	r := NewReceivers()
+	api := js.Global().Get("Object").New()
+	api.Set("open", js.FuncOf(func(this js.Value, args []js.Value) any {
+		var key []byte
+		if len(args) > 0 && !args[0].IsUndefined() && !args[0].IsNull() {
+			key = make([]byte, args[0].Get("length").Int())
+			js.CopyBytesToGo(key, args[0])
+		}
+		id, err := r.Open(key)
+		if err != nil {
+			return jsError(err)
+		}
+		return id
+	}))
+	api.Set("addPayload", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) < 2 {
+			return jsError(errArgs)
+		}
+		st, err := r.AddPayload(args[0].Int(), args[1].String())
+		if err != nil {
+			return jsError(err)
+		}
+		missing := make([]any, len(st.Missing))
+		for i, nr := range st.Missing {
+			missing[i] = nr
+		}
+		obj := map[string]any{
+			"progress": st.Progress,
+			"complete": st.Complete,
+			"missing":  missing,
+		}
+		if st.Failure != "" {
+			obj["failure"] = st.Failure
+		}
+		if st.Err != "" {
+			obj["error"] = st.Err
+		}
+		return obj
+	}))
+	api.Set("data", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return jsError(errArgs)
+		}
+		data, err := r.Data(args[0].Int())
+		if err != nil {
+			return jsError(err)
+		}
+		arr := js.Global().Get("Uint8Array").New(len(data))
+		js.CopyBytesToJS(arr, data)
+		return arr
+	}))
+	api.Set("close", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) > 0 {
+			r.Close(args[0].Int())
+		}
+		return nil
+	}))
+	js.Global().Set("qrseq", api)
+}
+
+var errArgs = errors.New("missing arguments")
+
+// jsError converts err to a JavaScript Error.
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
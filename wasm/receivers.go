@@ -0,0 +1,127 @@
+// Package wasm exposes the receiving side of qrseq to JavaScript when it is
+// compiled to WebAssembly, so browser receivers parse and validate frames with
+// exactly the code Go receivers run.
+//
+// Browsers scan the QR codes themselves, e.g. with BarcodeDetector, and pass
+// the texts on. Receivers is the API the JavaScript functions bind to. It is
+// plain Go, so embedders can drive it natively as well, and it builds with the
+// core tag, which keeps the module free of image dependencies:
+//
+//	GOOS=js GOARCH=wasm go build -tags core -o qrseq.wasm ./cmd/qrseq-wasm
+package wasm
+
+import (
+	"errors"
+
+	"github.com/airsigner/qrseq"
+)
+
+// ErrUnknownReceiver means a handle does not belong to an open receiver.
+var ErrUnknownReceiver = errors.New("unknown receiver")
+
+// Status is the state of a receiver after a payload was added.
+type Status struct {
+	Progress float32 `json:"progress"`          // fraction of chunks received
+	Complete bool    `json:"complete"`          // whether the payload is complete
+	Missing  []int   `json:"missing"`           // numbers of the chunks still missing
+	Failure  string  `json:"failure,omitempty"` // why the payload was rejected
+	Err      string  `json:"error,omitempty"`   // terminal error of the receiver
+}
+
+// Receivers holds receiving QRSequences by handle, since JavaScript cannot
+// hold Go pointers. It is not safe for concurrent use, which JavaScript does
+// not need.
+type Receivers struct {
+	seqs map[int]*qrseq.QRSequence
+	next int
+}
+
+// NewReceivers creates an empty Receivers.
+//
+// Returns:
+// - *Receivers: the new Receivers.
+func NewReceivers() *Receivers {
+	return &Receivers{seqs: make(map[int]*qrseq.QRSequence)}
+}
+
+// Open creates a receiver.
+//
+// Parameters:
+// - key: the 32 byte key of an encrypted payload, or nil.
+//
+// Returns:
+// - int: the handle of the receiver.
+// - error: an error if the key is invalid.
+func (r *Receivers) Open(key []byte) (int, error) {
+	var opts []qrseq.Option
+	if key != nil {
+		opts = append(opts, qrseq.WithEncryption(key))
+	}
+	seq := qrseq.NewEmpty(opts...)
+	if err := seq.Err(); err != nil {
+		return 0, err
+	}
+
+	r.next++
+	r.seqs[r.next] = seq
+	return r.next, nil
+}
+
+// AddPayload adds the text of a scanned QR code to a receiver, see
+// QRSequence.AddPayload.
+//
+// Parameters:
+// - id: the handle of the receiver.
+// - text: the text of the QR code.
+//
+// Returns:
+//   - Status: the state of the receiver. A rejected payload is reported in
+//     Failure, a receiver that failed for good in Err.
+//   - error: an error if there is no receiver with the handle.
+func (r *Receivers) AddPayload(id int, text string) (Status, error) {
+	seq, ok := r.seqs[id]
+	if !ok {
+		return Status{}, ErrUnknownReceiver
+	}
+
+	st := Status{}
+	var decodeErr *qrseq.DecodeError
+	if err := seq.AddPayload(text); errors.As(err, &decodeErr) {
+		st.Failure = decodeErr.Error()
+	}
+	if err := seq.Err(); err != nil {
+		st.Err = err.Error()
+	}
+	st.Progress = seq.Progress()
+	st.Complete = seq.IsComplete()
+	st.Missing = seq.Missing()
+	return st, nil
+}
+
+// Data returns the payload of a complete receiver.
+//
+// Parameters:
+// - id: the handle of the receiver.
+//
+// Returns:
+//   - []byte: the payload.
+//   - error: an error if there is no receiver with the handle or it is not
+//     complete.
+func (r *Receivers) Data(id int) ([]byte, error) {
+	seq, ok := r.seqs[id]
+	if !ok {
+		return nil, ErrUnknownReceiver
+	}
+	if !seq.IsComplete() {
+		return nil, qrseq.ErrSequenceIncomplete
+	}
+	return seq.Data(), nil
+}
+
+// Close releases a receiver. Closing an unknown handle does nothing.
+//
+// Parameters:
+// - id: the handle of the receiver.
+func (r *Receivers) Close(id int) {
+	delete(r.seqs, id)
+}
@@ -0,0 +1,124 @@
+package qrseq
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+)
+
+// A watermark strip is a row of watermarkBits bars spanning the bottom of the
+// frame, dark for a one bit and light for a zero bit. Bar i covers the columns
+// from i*width/watermarkBits up to (i+1)*width/watermarkBits, both rounded
+// down. The bars are a start pattern (1, 0), the frame index (32 bits), the
+// timestamp in Unix milliseconds (48 bits) and the low byte of the CRC-32
+// (IEEE) of the big endian index and timestamp, all most significant bit
+// first, followed by a stop bit (1).
+const watermarkBits = 2 + 32 + 48 + 8 + 1
+
+// Watermark is the frame index and timestamp stamped onto a frame, so
+// analysis tools can align recorded video with the logical frame sequence
+// without decoding the QR code.
+type Watermark struct {
+	Index int       // position of the frame in the displayed sequence
+	Time  time.Time // time the frame was rendered or displayed
+}
+
+// AddWatermark returns a copy of the frame with a watermark strip below its
+// quiet zone.
+//
+// The strip is separated from the QR code by a gap of one block and is two
+// blocks high. The frame is widened if it is narrower than the strip needs,
+// one pixel per bar. Players that show frames in real time can stamp the
+// display time instead of the render time of RenderOptions.Watermark.
+//
+// Parameters:
+// - img: the rendered frame.
+// - w: the frame index and timestamp to stamp.
+// - blockSize: the block size the frame was rendered with.
+//
+// Returns:
+// - *image.Gray: the frame with the watermark strip.
+func AddWatermark(img image.Image, w Watermark, blockSize int) *image.Gray {
+	bs := max(blockSize, 1)
+	b := img.Bounds()
+	width := max(b.Dx(), watermarkBits)
+	height := b.Dy() + 3*bs
+
+	out := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	offset := (width - b.Dx()) / 2
+	draw.Draw(out, image.Rect(offset, 0, offset+b.Dx(), b.Dy()), img, b.Min, draw.Src)
+
+	bits := w.bits()
+	for i, bit := range bits {
+		if !bit {
+			continue
+		}
+		bar := image.Rect(i*width/len(bits), height-2*bs, (i+1)*width/len(bits), height)
+		draw.Draw(out, bar, image.Black, image.Point{}, draw.Src)
+	}
+	return out
+}
+
+// ReadWatermark reads the watermark strip of a frame stamped by AddWatermark.
+//
+// It samples the bottom row of the image, so it reads rendered frames and
+// screen recordings, not camera footage.
+//
+// Parameters:
+// - img: the stamped frame.
+//
+// Returns:
+// - Watermark: the frame index and timestamp of the frame.
+// - error: an error if the frame has no valid watermark strip.
+func ReadWatermark(img image.Image) (Watermark, error) {
+	b := img.Bounds()
+	if b.Dx() < watermarkBits || b.Dy() < 1 {
+		return Watermark{}, ErrNoWatermark
+	}
+
+	bits := make([]bool, watermarkBits)
+	for i := range bits {
+		x := b.Min.X + i*b.Dx()/watermarkBits
+		gray := color.GrayModel.Convert(img.At(x, b.Max.Y-1)).(color.Gray)
+		bits[i] = gray.Y < 128
+	}
+	if !bits[0] || bits[1] || !bits[watermarkBits-1] {
+		return Watermark{}, ErrNoWatermark
+	}
+
+	var buf [11]byte
+	for i, bit := range bits[2 : watermarkBits-1] {
+		if bit {
+			buf[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	if byte(crc32.ChecksumIEEE(buf[:10])) != buf[10] {
+		return Watermark{}, ErrWatermarkChecksum
+	}
+
+	index := binary.BigEndian.Uint32(buf[0:4])
+	ms := binary.BigEndian.Uint64(append([]byte{0, 0}, buf[4:10]...))
+	return Watermark{Index: int(index), Time: time.UnixMilli(int64(ms))}, nil
+}
+
+// bits returns the bars of the watermark strip.
+func (w Watermark) bits() []bool {
+	var buf [11]byte
+	binary.BigEndian.PutUint32(buf[0:4], uint32(w.Index))
+	ms := uint64(w.Time.UnixMilli())
+	for i := 0; i < 6; i++ {
+		buf[4+i] = byte(ms >> (40 - 8*i))
+	}
+	buf[10] = byte(crc32.ChecksumIEEE(buf[:10]))
+
+	bits := make([]bool, 0, watermarkBits)
+	bits = append(bits, true, false)
+	for i := 0; i < 8*len(buf); i++ {
+		bits = append(bits, buf[i/8]&(0x80>>(i%8)) != 0)
+	}
+	return append(bits, true)
+}
@@ -0,0 +1,255 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/airsigner/qrseq/internal"
+)
+
+// decodeWorkerEnv marks the child process started by StartDecodeWorker.
+const decodeWorkerEnv = "QRSEQ_DECODE_WORKER"
+
+// maxFeedImage bounds the body of an image message sent to a DecodeWorker.
+const maxFeedImage = 1 << 26
+
+// DecodeWorker decodes frames in a child process, so a vulnerability in the
+// QR code parser is contained away from the process holding keys and the
+// received payload.
+//
+// The child is the running executable started again. It talks to the parent
+// over a chunk feed on its standard input and output, receiving gray images
+// and answering with decoded frames or the reason decoding failed. Since it
+// needs nothing but reading and writing these two descriptors, it can be
+// locked down with a strict seccomp filter or dropped privileges.
+type DecodeWorker struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	w     *FeedWriter
+	r     *bufio.Reader
+}
+
+// RunDecodeWorker turns the process into a decode worker if it was started by
+// StartDecodeWorker and returns otherwise. It must be called at the start of
+// main, before the process opens any files or acquires secrets.
+//
+// The worker calls sandbox, if not nil, before it reads the first image, so
+// the caller can drop privileges or install a seccomp filter. The worker
+// exits when its input is closed or sandbox fails.
+//
+// Parameters:
+// - sandbox: a function confining the worker process, or nil.
+func RunDecodeWorker(sandbox func() error) {
+	if os.Getenv(decodeWorkerEnv) != "1" {
+		return
+	}
+	if sandbox != nil {
+		if err := sandbox(); err != nil {
+			os.Exit(2)
+		}
+	}
+	if err := runDecodeWorker(os.Stdin, os.Stdout); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func runDecodeWorker(r io.Reader, w io.Writer) error {
+	br, err := readFeedHeader(r)
+	if err != nil {
+		return err
+	}
+	fw, err := NewFeedWriter(w)
+	if err != nil {
+		return err
+	}
+
+	for {
+		kind, body, err := readFeedMessage(br, maxFeedImage)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if kind != feedImage {
+			return ErrUnknownFeedMessage
+		}
+
+		img, err := decodeFeedImage(body)
+		if err != nil {
+			return err
+		}
+		frame, err := readWorkerFrame(img)
+		if err != nil {
+			decodeErr := newDecodeError(err)
+			err = fw.write(feedFailure, append([]byte{byte(decodeErr.Failure)}, decodeErr.Err.Error()...))
+		} else {
+			err = fw.write(feedFrame, frame)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readWorkerFrame(img image.Image) ([]byte, error) {
+	text, err := internal.ReadImage(img)
+	if err != nil {
+		return nil, err
+	}
+	return internal.DecodeText(text)
+}
+
+// StartDecodeWorker starts a DecodeWorker child process. The executable must
+// call RunDecodeWorker at the start of main.
+//
+// The child gets an empty environment apart from the worker marker, the root
+// directory as working directory and no descriptors but its standard input
+// and output.
+//
+// Returns:
+// - *DecodeWorker: the started DecodeWorker.
+// - error: an error if the child process cannot be started.
+func StartDecodeWorker() (*DecodeWorker, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(exe)
+	cmd.Env = []string{decodeWorkerEnv + "=1"}
+	cmd.Dir = "/"
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	w := &DecodeWorker{cmd: cmd, stdin: stdin}
+	if w.w, err = NewFeedWriter(stdin); err == nil {
+		w.r, err = readFeedHeader(stdout)
+	}
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// DecodeImage decodes an image in the worker and adds the frame to the
+// QRSequence, like QRSequence.DecodeImage.
+//
+// Parameters:
+// - seq: the receiving QRSequence.
+// - img: an image.Image to be decoded.
+//
+// Returns:
+//   - error: a *DecodeError if the frame cannot be decoded, the terminal error
+//     of the QRSequence, or an error if the worker failed. A failed worker
+//     must be closed and cannot be used anymore.
+func (w *DecodeWorker) DecodeImage(seq *QRSequence, img image.Image) error {
+	if seq.err != nil {
+		return seq.err
+	}
+	if !seq.acceptsFrames() {
+		return nil
+	}
+
+	if err := w.w.write(feedImage, encodeFeedImage(img)); err != nil {
+		return err
+	}
+	kind, body, err := readFeedMessage(w.r, maxFeedMessage)
+	if errors.Is(err, io.EOF) {
+		return ErrWorkerExited
+	}
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case feedFrame:
+		return seq.receive(body)
+	case feedFailure:
+		if len(body) < 1 {
+			return ErrInvalidFeedMessage
+		}
+		decodeErr, err := workerFailure(body)
+		if err != nil {
+			return err
+		}
+		seq.countFailure(decodeErr)
+		return decodeErr
+	}
+	return ErrUnknownFeedMessage
+}
+
+// workerFailure returns the DecodeError of a failure message of the worker.
+// It wraps the sentinel of the failure, with the message of the worker if it
+// has more to say.
+func workerFailure(body []byte) (*DecodeError, error) {
+	failure := DecodeFailure(body[0])
+	sentinel, ok := failureErrors[failure]
+	if !ok {
+		return nil, ErrInvalidFeedMessage
+	}
+	err := sentinel
+	if msg := string(body[1:]); msg != sentinel.Error() {
+		err = fmt.Errorf("%w: %s", sentinel, msg)
+	}
+	return &DecodeError{Failure: failure, Err: err}, nil
+}
+
+// Close stops the worker and waits for it to exit.
+func (w *DecodeWorker) Close() error {
+	w.stdin.Close()
+	return w.cmd.Wait()
+}
+
+// encodeFeedImage encodes img as the body of an image message.
+func encodeFeedImage(img image.Image) []byte {
+	b := img.Bounds()
+	gray, ok := img.(*image.Gray)
+	if !ok || gray.Stride != b.Dx() {
+		gray = image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(gray, gray.Bounds(), img, b.Min, draw.Src)
+	}
+
+	body := make([]byte, 8, 8+len(gray.Pix))
+	binary.LittleEndian.PutUint32(body[0:4], uint32(b.Dx()))
+	binary.LittleEndian.PutUint32(body[4:8], uint32(b.Dy()))
+	return append(body, gray.Pix[:b.Dx()*b.Dy()]...)
+}
+
+// decodeFeedImage decodes the body of an image message.
+func decodeFeedImage(body []byte) (*image.Gray, error) {
+	if len(body) < 8 {
+		return nil, ErrInvalidFeedImage
+	}
+	width := int(binary.LittleEndian.Uint32(body[0:4]))
+	height := int(binary.LittleEndian.Uint32(body[4:8]))
+	if width <= 0 || height <= 0 || len(body)-8 != width*height {
+		return nil, ErrInvalidFeedImage
+	}
+
+	return &image.Gray{
+		Pix:    body[8:],
+		Stride: width,
+		Rect:   image.Rect(0, 0, width, height),
+	}, nil
+}
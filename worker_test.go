@@ -0,0 +1,33 @@
+//go:build !core
+
+package qrseq
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWorkerFailure(t *testing.T) {
+	for _, tc := range []struct {
+		body []byte
+		want error
+		msg  string
+	}{
+		{body: append([]byte{byte(FailureNotFound)}, ErrNoQRFound.Error()...), want: ErrNoQRFound, msg: "no QR code found"},
+		{body: append([]byte{byte(FailureInvalid)}, "invalid chunk size"...), want: ErrInvalidChunk, msg: "invalid chunk: invalid chunk size"},
+	} {
+		decodeErr, err := workerFailure(tc.body)
+		if err != nil {
+			t.Fatalf("workerFailure: %v", err)
+		}
+		if !errors.Is(decodeErr, tc.want) || !errors.Is(decodeErr.Err, tc.want) {
+			t.Errorf("got %v, want %v", decodeErr, tc.want)
+		}
+		if decodeErr.Err.Error() != tc.msg {
+			t.Errorf("got message %q, want %q", decodeErr.Err.Error(), tc.msg)
+		}
+	}
+	if _, err := workerFailure([]byte{0}); !errors.Is(err, ErrInvalidFeedMessage) {
+		t.Errorf("unknown failure: got %v, want %v", err, ErrInvalidFeedMessage)
+	}
+}